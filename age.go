@@ -0,0 +1,67 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// OlderThan returns true if the given file was last modified more than d
+// ago; otherwise returns false. See also [NewerThan] and [IsNewerThan].
+func OlderThan(path string, d time.Duration) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return time.Since(info.ModTime()) > d, nil
+}
+
+// NewerThan returns true if the given file was last modified less than d
+// ago; otherwise returns false. See also [OlderThan].
+func NewerThan(path string, d time.Duration) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return time.Since(info.ModTime()) < d, nil
+}
+
+// IsNewerThan returns true if file a was last modified more recently than
+// file b; otherwise returns false. See also [OlderThan].
+func IsNewerThan(a, b string) (bool, error) {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return aInfo.ModTime().After(bInfo.ModTime()), nil
+}
+
+// NeedsRebuild returns true if the target file doesn't exist or was last
+// modified before any of the source files, as make(1) does; otherwise
+// returns false. A missing source is an error (wrapping
+// [fs.ErrNotExist]) since the target can't be built from it.
+// See also [IsNewerThan].
+func NeedsRebuild(target string, sources ...string) (bool, error) {
+	targetInfo, err := os.Stat(target)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	rebuild := err != nil // target is missing
+	for _, source := range sources {
+		info, err := os.Stat(source)
+		if err != nil {
+			return false, err
+		}
+		if !rebuild && info.ModTime().After(targetInfo.ModTime()) {
+			rebuild = true // still check that the other sources exist
+		}
+	}
+	return rebuild, nil
+}
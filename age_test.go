@@ -0,0 +1,73 @@
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_FileAge(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"old": "1", "new": "2"})
+	old := filepath.Join(dir, "old")
+	new := filepath.Join(dir, "new")
+	then := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(old, then, then); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := OlderThan(old, time.Hour); err != nil || !ok {
+		t.Errorf("expected old to be older than an hour %v", err)
+	}
+	if ok, err := OlderThan(new, time.Hour); err != nil || ok {
+		t.Errorf("expected new not to be older than an hour %v", err)
+	}
+	if ok, err := NewerThan(new, time.Hour); err != nil || !ok {
+		t.Errorf("expected new to be newer than an hour %v", err)
+	}
+	if ok, err := IsNewerThan(new, old); err != nil || !ok {
+		t.Errorf("expected new to be newer than old %v", err)
+	}
+	if ok, err := IsNewerThan(old, new); err != nil || ok {
+		t.Errorf("expected old not to be newer than new %v", err)
+	}
+	if _, err := IsNewerThan(old, filepath.Join(dir, "missing")); err ==
+		nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func Test_NeedsRebuild(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"a.c": "1", "b.h": "2",
+		"a.o": "3"})
+	source := filepath.Join(dir, "a.c")
+	header := filepath.Join(dir, "b.h")
+	target := filepath.Join(dir, "a.o")
+	then := time.Now().Add(-time.Hour)
+	for _, name := range []string{source, header} {
+		if err := os.Chtimes(name, then, then); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ok, err := NeedsRebuild(target, source, header); err != nil || ok {
+		t.Errorf("expected up to date target %v", err)
+	}
+	if err := os.Chtimes(header, time.Now().Add(time.Minute),
+		time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := NeedsRebuild(target, source, header); err != nil || !ok {
+		t.Errorf("expected stale target %v", err)
+	}
+	missing := filepath.Join(dir, "missing")
+	if ok, err := NeedsRebuild(missing, source); err != nil || !ok {
+		t.Errorf("expected missing target to need rebuilding %v", err)
+	}
+	if _, err := NeedsRebuild(target, header, missing); !errors.Is(err,
+		fs.ErrNotExist) {
+		t.Errorf("expected %v got %v", fs.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// AnonymousFile is a file that has no name until it is given one by
+// [AnonymousFile.LinkInto]; if it is closed without being linked it
+// disappears. On Linux it is created with O_TMPFILE so that it is
+// invisible to other processes and never left behind by a crash; on other
+// platforms (or where O_TMPFILE or /proc isn't available) it is a hidden
+// temporary file that is removed on Close if it hasn't been linked. The
+// package's atomic writes use the same mechanism.
+// Create with [CreateAnonymous].
+type AnonymousFile struct {
+	*os.File
+	tempname string // "" for an O_TMPFILE file
+	linked   bool
+}
+
+// CreateAnonymous returns a new AnonymousFile in folder dir opened for
+// reading and writing. To replace a file atomically create the
+// AnonymousFile in the same folder, write to it, call LinkInto, and then
+// Close.
+func CreateAnonymous(dir string) (*AnonymousFile, error) {
+	if file, err := openTmpFile(dir); err == nil {
+		return &AnonymousFile{File: file}, nil
+	}
+	file, err := os.CreateTemp(dir, ".anon-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err = file.Chmod(modeDefault); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return &AnonymousFile{File: file, tempname: file.Name()}, nil
+}
+
+// LinkInto syncs the AnonymousFile's data to stable storage and gives it
+// the name path, atomically replacing path if it exists, and then syncs
+// path's folder (see [SyncDir]). Path must be on the same filesystem as
+// the folder the AnonymousFile was created in. The file remains open and
+// must still be closed.
+func (me *AnonymousFile) LinkInto(path string) error {
+	if me.linked {
+		return errors.New("anonymous file already linked")
+	}
+	if err := me.Sync(); err != nil {
+		return err
+	}
+	tempname := me.tempname
+	if tempname == "" {
+		tempname = filepath.Join(filepath.Dir(path),
+			"."+filepath.Base(path)+"."+strconv.Itoa(os.Getpid())+".lnk")
+		os.Remove(tempname) // left by a crash
+		if err := linkTmpFile(me.File, tempname); err != nil {
+			return err
+		}
+	}
+	if err := rename(tempname, path); err != nil {
+		if me.tempname == "" {
+			os.Remove(tempname)
+		}
+		return err
+	}
+	me.linked = true
+	return SyncDir(filepath.Dir(path))
+}
+
+// Close closes the AnonymousFile, removing it if it was never linked.
+func (me *AnonymousFile) Close() error {
+	err := me.File.Close()
+	if me.tempname != "" && !me.linked {
+		os.Remove(me.tempname)
+	}
+	return err
+}
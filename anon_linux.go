@@ -0,0 +1,38 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// procFdAvailable reports whether /proc/self/fd, which linkTmpFile needs,
+// is available (it isn't in some containers and chroots).
+var procFdAvailable = sync.OnceValue(func() bool {
+	_, err := os.Stat("/proc/self/fd")
+	return err == nil
+})
+
+// openTmpFile returns a new unnamed file in dir created with O_TMPFILE,
+// or an error if that isn't supported (including if the file couldn't be
+// linked later because /proc isn't available).
+func openTmpFile(dir string) (*os.File, error) {
+	if !procFdAvailable() {
+		return nil, errors.ErrUnsupported
+	}
+	return os.OpenFile(dir, os.O_RDWR|unix.O_TMPFILE, modeDefault)
+}
+
+// linkTmpFile gives the O_TMPFILE file the name path (which mustn't
+// exist) via its /proc/self/fd entry, which unlike AT_EMPTY_PATH needs no
+// special privileges.
+func linkTmpFile(file *os.File, path string) error {
+	return unix.Linkat(unix.AT_FDCWD,
+		"/proc/self/fd/"+strconv.Itoa(int(file.Fd())), unix.AT_FDCWD, path,
+		unix.AT_SYMLINK_FOLLOW)
+}
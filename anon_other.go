@@ -0,0 +1,20 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build !linux
+
+package ufile
+
+import (
+	"errors"
+	"os"
+)
+
+// openTmpFile always fails since O_TMPFILE is Linux-only.
+func openTmpFile(dir string) (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// linkTmpFile always fails since O_TMPFILE is Linux-only.
+func linkTmpFile(file *os.File, path string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,73 @@
+package ufile
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_CreateAnonymous(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"out.txt": "old"})
+	file, err := CreateAnonymous(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.WriteString("new\n"); err != nil {
+		t.Fatal(err)
+	}
+	if tree, _ := TreeString(dir); tree != ".\n└── out.txt\n" {
+		t.Errorf("expected anonymous file to be hidden got\n%s", tree)
+	}
+	filename := filepath.Join(dir, "out.txt")
+	if err = file.LinkInto(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.LinkInto(filename); err == nil {
+		t.Error("expected error linking twice")
+	}
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if lines, err := ReadTextFile(filename); err != nil ||
+		lines[0] != "new" {
+		t.Errorf("expected %q got %q %v", "new", lines, err)
+	}
+	file, err = CreateAnonymous(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if tree, _ := TreeString(dir); tree != ".\n└── out.txt\n" {
+		t.Errorf("expected unlinked file to vanish got\n%s", tree)
+	}
+}
+
+func Test_writeAtomic_anonymous(t *testing.T) {
+	dir := t.TempDir()
+	if file, err := openTmpFile(dir); err != nil {
+		t.Skip("O_TMPFILE not supported")
+	} else {
+		file.Close()
+	}
+	filename := filepath.Join(dir, "out.txt")
+	err := writeAtomic(filename, func(out *bufio.Writer) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) != 0 {
+			t.Errorf("expected no named temporary file got %v", entries)
+		}
+		_, err = out.WriteString("data")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || entries[0].Name() != "out.txt" {
+		t.Errorf("expected only out.txt got %v %v", entries, err)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+)
+
+// RuntimeDir returns a folder for the given application's runtime files,
+// e.g., Unix sockets, pid files, and lock files, creating it if necessary
+// so that it is readable and writable only by the current user. The
+// folder is in $XDG_RUNTIME_DIR if that is set; otherwise it is in
+// [os.TempDir] (which on macOS is per-user) with the user ID appended to
+// its name on other Unix systems, or in %LOCALAPPDATA% on Windows. An
+// existing folder that is a symlink, or (on Unix) that is owned by
+// another user, is refused with an error wrapping [fs.ErrPermission], and
+// an existing folder's permissions are tightened if necessary.
+func RuntimeDir(appname string) (string, error) {
+	rx := regexp.MustCompile(`\W+`)
+	name := rx.ReplaceAllString(appname, "_")
+	var dir string
+	if base := os.Getenv("XDG_RUNTIME_DIR"); base != "" {
+		dir = filepath.Join(base, name)
+	} else {
+		switch runtime.GOOS {
+		case "windows":
+			base, err := os.UserCacheDir()
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(base, name, "run")
+		case "darwin":
+			dir = filepath.Join(os.TempDir(), name)
+		default:
+			dir = filepath.Join(os.TempDir(),
+				name+"-"+strconv.Itoa(os.Getuid()))
+		}
+	}
+	return dir, ensurePrivateDir(dir)
+}
+
+// ensurePrivateDir creates dir if necessary with 0700 permissions, or
+// checks that the existing dir is a folder (not a symlink) owned by the
+// current user and makes its permissions 0700 if they are looser.
+func ensurePrivateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(dir); err != nil {
+		return err
+	} else if !info.IsDir() { // e.g., a symlink
+		return &fs.PathError{Op: "runtimedir", Path: dir,
+			Err: fs.ErrPermission}
+	}
+	info, err := Stat(dir)
+	if err != nil {
+		return err
+	}
+	if info.UID != -1 && info.UID != os.Getuid() {
+		return &fs.PathError{Op: "runtimedir", Path: dir,
+			Err: fs.ErrPermission}
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o700 {
+		return os.Chmod(dir, 0o700)
+	}
+	return nil
+}
+
+// GetLogFile given a domain name, say, "domain.com", and an application
+// name, say, "myapp", returns the conventional log filename for the
+// current platform, creating its folder if necessary. The log file is
+// named appname plus ".log" (with any non-word characters replaced by
+// "_") and is in the domain subfolder (if domain isn't "") of
+// $XDG_STATE_HOME (or ~/.local/state) on Unix, ~/Library/Logs on macOS,
+// or %LOCALAPPDATA% on Windows (where the folder also has appname and
+// Logs subfolders).
+func GetLogFile(domain, appname string) (string, error) {
+	dir, err := logDir(domain, appname)
+	if err != nil {
+		return "", err
+	}
+	if err = EnsureDir(dir, fs.ModePerm); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFilename(appname, ".log")), nil
+}
+
+func logDir(domain, appname string) (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		rx := regexp.MustCompile(`\W+`)
+		return filepath.Join(base, domain, rx.ReplaceAllString(appname,
+			"_"), "Logs"), nil
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "Logs", domain), nil
+	}
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" || !filepath.IsAbs(base) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, domain), nil
+}
@@ -0,0 +1,54 @@
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_RuntimeDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relies on Unix permissions")
+	}
+	base := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", base)
+	dir, err := RuntimeDir("my app")
+	expected := filepath.Join(base, "my_app")
+	if err != nil || dir != expected {
+		t.Fatalf("expected %q got %q %v", expected, dir, err)
+	}
+	if err = os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = RuntimeDir("my app"); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(dir); info.Mode().Perm() != 0o700 {
+		t.Errorf("expected %o got %o", 0o700, info.Mode().Perm())
+	}
+	if err = os.Symlink(dir, filepath.Join(base, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = RuntimeDir("link"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected %v got %v", fs.ErrPermission, err)
+	}
+}
+
+func Test_GetLogFile(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("relies on XDG_STATE_HOME")
+	}
+	base := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", base)
+	filename, err := GetLogFile("qtrac.eu", "my app")
+	expected := filepath.Join(base, "qtrac.eu", "my_app.log")
+	if err != nil || filename != expected {
+		t.Errorf("expected %q got %q %v", expected, filename, err)
+	}
+	if !IsDir(filepath.Dir(filename)) {
+		t.Errorf("expected %q to be created", filepath.Dir(filename))
+	}
+}
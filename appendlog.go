@@ -0,0 +1,247 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+	"os"
+	"sync"
+	"time"
+)
+
+// SyncPolicy determines when an [AppendLog] fsyncs its file.
+type SyncPolicy uint8
+
+const (
+	SyncAlways   SyncPolicy = iota // fsync after every Append
+	SyncInterval                   // fsync at most Interval after Append
+	SyncNever                      // leave it to the OS (and Close)
+)
+
+// AppendLogOptions are used by [OpenAppendLog].
+type AppendLogOptions struct {
+	Sync     SyncPolicy
+	Interval time.Duration // used if Sync is SyncInterval
+}
+
+// ErrCorruptRecord is yielded by [AppendLog.ReadAll] when a record's
+// checksum doesn't match its data.
+var ErrCorruptRecord = errors.New("corrupt append log record")
+
+const appendLogHeaderSize = 8 // 4-byte length + 4-byte CRC-32
+
+// AppendLog is an append-only file of records. Each record is stored with
+// its length and a checksum of its length and data so that a record torn
+// or zeroed by a crash is detected (and discarded, along with any records
+// following it, when the log is next opened). It is safe for concurrent
+// use. Create with [OpenAppendLog].
+type AppendLog struct {
+	mutex    sync.Mutex
+	path     string
+	file     *os.File
+	opts     AppendLogOptions
+	timer    *time.Timer
+	unsynced bool
+}
+
+// OpenAppendLog opens (creating with [ModeURW] permissions if necessary)
+// the append log at path. The file is truncated at the first incomplete or
+// corrupt record (if any), e.g., due to a crash mid-append, so that
+// records appended afterwards can be read.
+func OpenAppendLog(path string, opts AppendLogOptions) (*AppendLog, error) {
+	if opts.Sync == SyncInterval && opts.Interval <= 0 {
+		return nil, fmt.Errorf("invalid sync interval %v", opts.Interval)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, ModeURW)
+	if err != nil {
+		return nil, err
+	}
+	end, err := validAppendLogEnd(file)
+	if err == nil {
+		err = file.Truncate(end)
+	}
+	if err == nil {
+		_, err = file.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &AppendLog{path: path, file: file, opts: opts}, nil
+}
+
+// validAppendLogEnd returns the offset just past the last complete record
+// whose checksum is correct.
+func validAppendLogEnd(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	reader := bufio.NewReader(file)
+	header := make([]byte, appendLogHeaderSize)
+	var end int64
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return end, nil // EOF or torn header
+		}
+		n := int64(binary.LittleEndian.Uint32(header))
+		if end+appendLogHeaderSize+n > size {
+			return end, nil // torn data
+		}
+		hasher := crc32.NewIEEE()
+		hasher.Write(header[:4])
+		if _, err := io.CopyN(hasher, reader, n); err != nil {
+			return end, err
+		}
+		if hasher.Sum32() != binary.LittleEndian.Uint32(header[4:]) {
+			return end, nil // corrupt, e.g., a zero-filled tail
+		}
+		end += appendLogHeaderSize + n
+	}
+}
+
+// appendLogChecksum returns the CRC-32 of a record's length (the first 4
+// bytes of its header) and data, so that a zero-filled header isn't a
+// valid empty record.
+func appendLogChecksum(header, data []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE(header[:4]), crc32.IEEETable,
+		data)
+}
+
+// Append appends the data as a single record and fsyncs according to the
+// log's [SyncPolicy].
+func (me *AppendLog) Append(data []byte) error {
+	if int64(len(data)) > 0xFFFFFFFF {
+		return fmt.Errorf("append log record too large (%d bytes)",
+			len(data))
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.file == nil {
+		return os.ErrClosed
+	}
+	record := make([]byte, appendLogHeaderSize+len(data))
+	binary.LittleEndian.PutUint32(record, uint32(len(data)))
+	copy(record[appendLogHeaderSize:], data)
+	binary.LittleEndian.PutUint32(record[4:], appendLogChecksum(record,
+		data))
+	if _, err := me.file.Write(record); err != nil {
+		return err
+	}
+	me.unsynced = true
+	switch me.opts.Sync {
+	case SyncAlways:
+		return me.sync()
+	case SyncInterval:
+		if me.timer == nil {
+			me.timer = time.AfterFunc(me.opts.Interval, func() {
+				me.mutex.Lock()
+				defer me.mutex.Unlock()
+				me.timer = nil
+				if me.file != nil {
+					_ = me.sync()
+				}
+			})
+		}
+	}
+	return nil
+}
+
+// Sync fsyncs any appended records that haven't been synced yet.
+func (me *AppendLog) Sync() error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.file == nil {
+		return os.ErrClosed
+	}
+	return me.sync()
+}
+
+func (me *AppendLog) sync() error {
+	if !me.unsynced {
+		return nil
+	}
+	if err := me.file.Sync(); err != nil {
+		return err
+	}
+	me.unsynced = false
+	return nil
+}
+
+// Close syncs and closes the log.
+func (me *AppendLog) Close() error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.file == nil {
+		return os.ErrClosed
+	}
+	if me.timer != nil {
+		me.timer.Stop()
+		me.timer = nil
+	}
+	err := me.sync()
+	if closeErr := me.file.Close(); err == nil {
+		err = closeErr
+	}
+	me.file = nil
+	return err
+}
+
+// ReadAll returns an iterator of (record, error) for every complete record
+// in the log. A record whose checksum is wrong is yielded with
+// [ErrCorruptRecord] and reading stops; an incomplete final record is
+// silently ignored.
+func (me *AppendLog) ReadAll() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		file, err := os.Open(me.path)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		remaining := info.Size()
+		reader := bufio.NewReader(file)
+		header := make([]byte, appendLogHeaderSize)
+		for {
+			if _, err := io.ReadFull(reader, header); err != nil {
+				if err != io.EOF && err != io.ErrUnexpectedEOF {
+					yield(nil, err)
+				}
+				return
+			}
+			remaining -= appendLogHeaderSize
+			n := int64(binary.LittleEndian.Uint32(header))
+			if n > remaining {
+				return // incomplete (and too large to allocate)
+			}
+			remaining -= n
+			data := make([]byte, n)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				if err != io.EOF && err != io.ErrUnexpectedEOF {
+					yield(nil, err)
+				}
+				return
+			}
+			if appendLogChecksum(header, data) !=
+				binary.LittleEndian.Uint32(header[4:]) {
+				yield(nil, ErrCorruptRecord)
+				return
+			}
+			if !yield(data, nil) {
+				return
+			}
+		}
+	}
+}
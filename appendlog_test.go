@@ -0,0 +1,92 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_AppendLog(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "journal.log")
+	log, err := OpenAppendLog(filename, AppendLogOptions{
+		Sync: SyncInterval, Interval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"first", "", "third record"}
+	for _, record := range expected {
+		if err := log.Append([]byte(record)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// simulate a torn write
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, ModeURW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.Write([]byte{99, 0, 0, 0, 1, 2})
+	file.Close()
+	log, err = OpenAppendLog(filename, AppendLogOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	if err := log.Append([]byte("fourth")); err != nil {
+		t.Fatal(err)
+	}
+	expected = append(expected, "fourth")
+	records := []string{}
+	for record, err := range log.ReadAll() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, string(record))
+	}
+	if slices.Compare(records, expected) != 0 {
+		t.Errorf("expected %q got %q", expected, records)
+	}
+}
+
+func Test_AppendLog_corruptTail(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "journal.log")
+	for _, tail := range [][]byte{make([]byte, 16),
+		{0xFF, 0xFF, 0xFF, 0x7F, 0, 0, 0, 0}} {
+		log, err := OpenAppendLog(filename, AppendLogOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = log.Append([]byte("good")); err != nil {
+			t.Fatal(err)
+		}
+		log.Close()
+		file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND,
+			ModeURW)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = file.Write(tail)
+		file.Close()
+		records := []string{}
+		log, err = OpenAppendLog(filename, AppendLogOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for record, err := range log.ReadAll() {
+			if err != nil {
+				t.Fatal(err)
+			}
+			records = append(records, string(record))
+		}
+		log.Close()
+		if expected := []string{"good"}; !slices.Equal(records,
+			expected) {
+			t.Errorf("expected %q got %q", expected, records)
+		}
+		os.Remove(filename)
+	}
+}
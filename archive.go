@@ -0,0 +1,773 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrUnsafePath is returned when extracting an archive entry whose path
+// (or symlink target) would escape the destination folder.
+var ErrUnsafePath = errors.New("archive entry escapes destination")
+
+// deterministicTime is the default timestamp used for reproducible output
+// (the earliest time zip's MS-DOS timestamps can represent).
+var deterministicTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// reproducibleTime returns the timestamp to use for reproducible output:
+// the time given by the SOURCE_DATE_EPOCH environment variable (see
+// reproducible-builds.org) if it is set and valid and not before 1980, or
+// deterministicTime otherwise.
+func reproducibleTime() time.Time {
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10,
+		64); err == nil {
+		if when := time.Unix(epoch, 0).UTC(); !when.Before(
+			deterministicTime) {
+			return when
+		}
+	}
+	return deterministicTime
+}
+
+// reproducibleMode returns mode with its permissions normalized so they
+// don't depend on the umask: 0755 for folders and for files with any
+// executable bit set, 0777 for symlinks, and 0644 otherwise.
+func reproducibleMode(mode fs.FileMode) fs.FileMode {
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		return mode.Type() | 0o777
+	case mode.IsDir() || mode.Perm()&0o111 != 0:
+		return mode.Type() | 0o755
+	default:
+		return mode.Type() | 0o644
+	}
+}
+
+// linkTarget returns symlink path's slash-separated target. If
+// deterministic is true an absolute target inside srcDir is made relative
+// to the symlink's folder so that it doesn't depend on where srcDir is.
+func linkTarget(srcDir, path string, deterministic bool) (string, error) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return "", err
+	}
+	if deterministic && filepath.IsAbs(target) {
+		if rel, err := filepath.Rel(AbsPath(srcDir),
+			target); err == nil && filepath.IsLocal(rel) {
+			if rel, err = filepath.Rel(filepath.Dir(AbsPath(path)),
+				target); err == nil {
+				target = rel
+			}
+		}
+	}
+	return filepath.ToSlash(target), nil
+}
+
+// ZipOptions are used by [ZipDir] and [ZipDirContext].
+//
+// Entries whose names or slash-separated paths relative to the source
+// folder match any of the Exclude globs (see [filepath.Match]) or that
+// Ignore ignores (see [IgnoreMatcher]) are skipped (and excluded folders
+// aren't descended into). Level is the deflate compression level from 1
+// (fastest) to 9 (best); 0 means the default level and a negative level
+// means store without compression. Entries are always added in lexical
+// order. If Deterministic is true every entry gets the same fixed
+// timestamp (1980-01-01, or the time given by the SOURCE_DATE_EPOCH
+// environment variable), permissions are normalized to 0644 or 0755 (so
+// they don't depend on the umask), and absolute symlink targets inside the
+// source folder are made relative, so that zipping identical trees
+// produces identical zip files on any machine.
+type ZipOptions struct {
+	Exclude       []string
+	Ignore        *IgnoreMatcher
+	Level         int
+	Deterministic bool
+}
+
+// ZipDir writes the tree rooted at srcDir into a zip file at zipPath with
+// entry names relative to srcDir. The zip file is written atomically and
+// is never included in itself. Only folders, regular files, and symlinks
+// (stored as links) are included. See also [ZipDirContext] and [Unzip].
+func ZipDir(srcDir, zipPath string, opts ZipOptions) error {
+	return zipDir(srcDir, zipPath, opts, nil)
+}
+
+// ZipDirContext works like [ZipDir] but stops with the context's error
+// (leaving any existing zipPath untouched) if ctx is cancelled, and if
+// progress is not nil calls it as files are read with the total being the
+// size of the files to be zipped.
+func ZipDirContext(ctx context.Context, srcDir, zipPath string,
+	opts ZipOptions, progress ProgressFunc,
+) error {
+	var total int64 = -1
+	if progress != nil {
+		total = 0
+		err := walkZipEntries(filepath.Clean(srcDir), AbsPath(zipPath),
+			opts, func(_, _ string, info fs.FileInfo) error {
+				if info.Mode().IsRegular() {
+					total += info.Size()
+				}
+				return ctx.Err()
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return zipDir(srcDir, zipPath, opts, newTracker(ctx, progress, total))
+}
+
+func zipDir(srcDir, zipPath string, opts ZipOptions, track *tracker) error {
+	srcDir = filepath.Clean(srcDir)
+	absZipPath := AbsPath(zipPath)
+	return writeAtomic(zipPath, func(out *bufio.Writer) error {
+		writer := zip.NewWriter(out)
+		if opts.Level > 0 {
+			level := min(opts.Level, flate.BestCompression)
+			writer.RegisterCompressor(zip.Deflate,
+				func(out io.Writer) (io.WriteCloser, error) {
+					return flate.NewWriter(out, level)
+				})
+		}
+		err := walkZipEntries(srcDir, absZipPath, opts, func(path,
+			rel string, info fs.FileInfo,
+		) error {
+			if err := track.err(); err != nil {
+				return err
+			}
+			return addZipEntry(writer, srcDir, path, rel, info, opts,
+				track)
+		})
+		if err != nil {
+			return err
+		}
+		return writer.Close()
+	})
+}
+
+// walkZipEntries calls visit for every entry in the tree rooted at srcDir
+// that isn't excluded by opts and isn't the zip file itself.
+func walkZipEntries(srcDir, absZipPath string, opts ZipOptions,
+	visit func(path, rel string, info fs.FileInfo) error,
+) error {
+	return filepath.WalkDir(srcDir, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if path == srcDir {
+			return nil
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchesAnyGlob(opts.Exclude, dirEntry.Name(), rel) ||
+			opts.Ignore.Match(rel, dirEntry.IsDir()) ||
+			AbsPath(path) == absZipPath {
+			if dirEntry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		return visit(path, rel, info)
+	})
+}
+
+// matchesAnyGlob returns true if name or the slash-separated path rel
+// matches any of the globs (see [filepath.Match]); otherwise returns
+// false.
+func matchesAnyGlob(globs []string, name, rel string) bool {
+	for _, glob := range globs {
+		if matched, _ := filepath.Match(glob, name); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(glob, rel); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func addZipEntry(writer *zip.Writer, srcDir, path, rel string,
+	info fs.FileInfo, opts ZipOptions, track *tracker,
+) error {
+	mode := info.Mode()
+	if !mode.IsRegular() && !mode.IsDir() && mode&fs.ModeSymlink == 0 {
+		return nil // skip devices, sockets, etc.
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = rel
+	if opts.Deterministic {
+		header.Modified = reproducibleTime()
+		header.SetMode(reproducibleMode(mode))
+	}
+	switch {
+	case mode.IsDir():
+		header.Name += "/"
+		header.Method = zip.Store
+	case mode&fs.ModeSymlink != 0:
+		header.Method = zip.Store
+	case opts.Level < 0:
+		header.Method = zip.Store
+	default:
+		header.Method = zip.Deflate
+	}
+	entry, err := writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	switch {
+	case mode.IsDir():
+		return nil
+	case mode&fs.ModeSymlink != 0:
+		target, err := linkTarget(srcDir, path, opts.Deterministic)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(entry, target)
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(entry, track.reader(file, path))
+	return err
+}
+
+// Unzip extracts the zip file at zipPath into dstDir (which is created if
+// necessary), preserving permissions and modification times. An entry
+// whose path is absolute or contains enough ".." components to escape
+// dstDir causes [ErrUnsafePath] to be returned before anything is
+// written. An entry that would be written through a symlink (e.g., one
+// extracted earlier), or a symlink whose target would escape dstDir, also
+// causes ErrUnsafePath to be returned, but the entries preceding it will
+// already have been extracted. See also [ZipDir].
+func Unzip(zipPath, dstDir string) error {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for _, entry := range reader.File {
+		if _, err := safeJoin(dstDir, entry.Name); err != nil {
+			return err
+		}
+	}
+	if err = os.MkdirAll(dstDir, fs.ModePerm); err != nil {
+		return err
+	}
+	var dirs []*zip.File
+	for _, entry := range reader.File {
+		if entry.FileInfo().IsDir() {
+			dirs = append(dirs, entry)
+		}
+		if err = extractZipEntry(entry, dstDir); err != nil {
+			return err
+		}
+	}
+	for _, entry := range dirs { // set folder times after filling them
+		path, _ := safeJoin(dstDir, entry.Name)
+		_ = os.Chtimes(path, entry.Modified, entry.Modified)
+	}
+	return nil
+}
+
+// safeJoin returns dstDir joined with the slash-separated entry name or
+// ErrUnsafePath if the result would be outside dstDir.
+func safeJoin(dstDir, name string) (string, error) {
+	local := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if local == "" || !filepath.IsLocal(local) ||
+		strings.Contains(name, `\`) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+	return filepath.Join(dstDir, local), nil
+}
+
+// checkParents returns ErrUnsafePath if any existing folder between
+// dstDir and path (which is inside dstDir) is a symlink, since writing
+// through it could write outside dstDir.
+func checkParents(dstDir, path string) error {
+	rel, err := filepath.Rel(dstDir, filepath.Dir(path))
+	if err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return fmt.Errorf("%w: %q", ErrUnsafePath, path)
+	}
+	if rel == "." {
+		return nil
+	}
+	current := dstDir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // the rest will be created as real folders
+			}
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %q is under symlink %q", ErrUnsafePath,
+				path, current)
+		}
+	}
+	return nil
+}
+
+// prepareEntryPath returns ErrUnsafePath if path (which is inside dstDir)
+// could only be written through a symlink (see [checkParents]), and
+// otherwise removes path if it is a symlink so that writing to it can't
+// follow the link.
+func prepareEntryPath(dstDir, path string) error {
+	if err := checkParents(dstDir, path); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(path); err == nil &&
+		info.Mode()&fs.ModeSymlink != 0 {
+		return os.Remove(path)
+	}
+	return nil
+}
+
+// checkLinkTarget returns ErrUnsafePath if a symlink at path (which is
+// inside dstDir) pointing to target would point outside dstDir. The
+// target is resolved component by component against what is on disk, and
+// one that goes through an existing symlink is rejected since the
+// symlink's own target would change where any following ".." leads.
+func checkLinkTarget(dstDir, path, target string) error {
+	if filepath.IsAbs(target) || filepath.VolumeName(target) != "" {
+		return fmt.Errorf("%w: symlink to %q", ErrUnsafePath, target)
+	}
+	current := filepath.Dir(path)
+	parts := strings.FieldsFunc(target, func(c rune) bool {
+		return c < utf8.RuneSelf && os.IsPathSeparator(uint8(c))
+	})
+	for i, part := range parts {
+		switch part {
+		case ".":
+			continue
+		case "..":
+			current = filepath.Dir(current)
+		default:
+			current = filepath.Join(current, part)
+		}
+		rel, err := filepath.Rel(dstDir, current)
+		if err != nil || !filepath.IsLocal(rel) && rel != "." {
+			return fmt.Errorf("%w: symlink to %q", ErrUnsafePath, target)
+		}
+		if i == len(parts)-1 {
+			break // the target itself may be a symlink
+		}
+		if info, err := os.Lstat(current); err == nil &&
+			info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: symlink to %q goes through symlink %q",
+				ErrUnsafePath, target, current)
+		}
+	}
+	return nil
+}
+
+func extractZipEntry(entry *zip.File, dstDir string) error {
+	path, err := safeJoin(dstDir, entry.Name)
+	if err != nil {
+		return err
+	}
+	if err = prepareEntryPath(dstDir, path); err != nil {
+		return err
+	}
+	info := entry.FileInfo()
+	mode := info.Mode()
+	if mode.IsDir() {
+		return os.MkdirAll(path, mode.Perm()|0o700)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), fs.ModePerm); err != nil {
+		return err
+	}
+	in, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if mode&fs.ModeSymlink != 0 {
+		raw, err := io.ReadAll(io.LimitReader(in, 4096))
+		if err != nil {
+			return err
+		}
+		target := filepath.FromSlash(string(raw))
+		if err = checkLinkTarget(dstDir, path, target); err != nil {
+			return err
+		}
+		_ = os.Remove(path)
+		return os.Symlink(target, path)
+	}
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = modeDefault
+	}
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(path, entry.Modified, entry.Modified)
+}
+
+// TarOptions are used by [TarDir].
+//
+// Exclude, Ignore, and Deterministic work as for [ZipOptions] (and
+// Deterministic also omits owner IDs and names). If Gzip is true the
+// output is gzip-compressed at GzipLevel (1 to 9; 0 means the default).
+// (Zstandard isn't supported since the standard library has no encoder.)
+type TarOptions struct {
+	Exclude       []string
+	Ignore        *IgnoreMatcher
+	Gzip          bool
+	GzipLevel     int
+	Deterministic bool
+}
+
+// TarDir writes the tree rooted at srcDir as a tar (or if opts.Gzip is
+// true, tar.gz) stream to writer, with entry names relative to srcDir.
+// Permissions, modification times, and symlinks are preserved (and, if
+// opts.Deterministic is false, owner IDs). Devices, FIFOs, and sockets are
+// skipped. See also [Untar].
+func TarDir(srcDir string, writer io.Writer, opts TarOptions) error {
+	srcDir = filepath.Clean(srcDir)
+	var gzwriter *gzip.Writer
+	if opts.Gzip {
+		level := opts.GzipLevel
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		var err error
+		if gzwriter, err = gzip.NewWriterLevel(writer, level); err != nil {
+			return err
+		}
+		writer = gzwriter
+	}
+	tarWriter := tar.NewWriter(writer)
+	err := filepath.WalkDir(srcDir, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if path == srcDir {
+			return nil
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchesAnyGlob(opts.Exclude, dirEntry.Name(), rel) ||
+			opts.Ignore.Match(rel, dirEntry.IsDir()) {
+			if dirEntry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		return addTarEntry(tarWriter, srcDir, path, rel, info, opts)
+	})
+	if err != nil {
+		return err
+	}
+	if err = tarWriter.Close(); err != nil {
+		return err
+	}
+	if gzwriter != nil {
+		return gzwriter.Close()
+	}
+	return nil
+}
+
+func addTarEntry(writer *tar.Writer, srcDir, path, rel string,
+	info fs.FileInfo, opts TarOptions,
+) error {
+	mode := info.Mode()
+	if !mode.IsRegular() && !mode.IsDir() && mode&fs.ModeSymlink == 0 {
+		return nil // skip devices, sockets, etc.
+	}
+	var target string
+	if mode&fs.ModeSymlink != 0 {
+		var err error
+		if target, err = linkTarget(srcDir, path,
+			opts.Deterministic); err != nil {
+			return err
+		}
+	}
+	header, err := tar.FileInfoHeader(info, target)
+	if err != nil {
+		return err
+	}
+	header.Name = rel
+	if mode.IsDir() {
+		header.Name += "/"
+	}
+	if opts.Deterministic {
+		header.ModTime = reproducibleTime()
+		header.Mode = int64(reproducibleMode(mode).Perm())
+		header.AccessTime = time.Time{}
+		header.ChangeTime = time.Time{}
+		header.Uid, header.Gid = 0, 0
+		header.Uname, header.Gname = "", ""
+		header.Format = tar.FormatPAX
+	}
+	if err = writer.WriteHeader(header); err != nil {
+		return err
+	}
+	if !mode.IsRegular() {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(writer, file)
+	return err
+}
+
+// Untar extracts the tar or tar.gz (detected automatically) stream from
+// reader into dstDir (which is created if necessary), preserving
+// permissions, modification times, symlinks, and hard links. An entry
+// whose path, link, or symlink target would escape dstDir, including by
+// going through a symlink (e.g., one extracted earlier), causes
+// [ErrUnsafePath] to be returned; since the input is streamed, entries
+// preceding the unsafe one will already have been extracted.
+// See also [TarDir].
+func Untar(reader io.Reader, dstDir string) error {
+	buffered := bufio.NewReader(reader)
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1F &&
+		magic[1] == 0x8B {
+		gzreader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return err
+		}
+		defer gzreader.Close()
+		reader = gzreader
+	} else {
+		reader = buffered
+	}
+	if err := os.MkdirAll(dstDir, fs.ModePerm); err != nil {
+		return err
+	}
+	tarReader := tar.NewReader(reader)
+	type dirTime struct {
+		path    string
+		modTime time.Time
+	}
+	var dirs []dirTime
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		path, err := safeJoin(dstDir, header.Name)
+		if err != nil {
+			if strings.Trim(header.Name, "./") == "" {
+				continue // the root itself, e.g., "./"
+			}
+			return err
+		}
+		if err = prepareEntryPath(dstDir, path); err != nil {
+			return err
+		}
+		if err = extractTarEntry(tarReader, header, dstDir,
+			path); err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeDir {
+			dirs = append(dirs, dirTime{path, header.ModTime})
+		}
+	}
+	for _, dir := range dirs { // set folder times after filling them
+		_ = os.Chtimes(dir.path, dir.modTime, dir.modTime)
+	}
+	return nil
+}
+
+func extractTarEntry(reader *tar.Reader, header *tar.Header, dstDir,
+	path string,
+) error {
+	perm := fs.FileMode(header.Mode).Perm()
+	if header.Typeflag != tar.TypeDir {
+		if err := os.MkdirAll(filepath.Dir(path), fs.ModePerm); err != nil {
+			return err
+		}
+	}
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(path, perm|0o700); err != nil {
+			return err
+		}
+		return os.Chmod(path, perm|0o700)
+	case tar.TypeSymlink:
+		target := filepath.FromSlash(header.Linkname)
+		if err := checkLinkTarget(dstDir, path, target); err != nil {
+			return err
+		}
+		_ = os.Remove(path)
+		return os.Symlink(target, path)
+	case tar.TypeLink:
+		target, err := safeJoin(dstDir, header.Linkname)
+		if err != nil {
+			return err
+		}
+		if err = checkParents(dstDir, target); err != nil {
+			return err
+		}
+		if info, err := os.Lstat(target); err == nil &&
+			info.Mode()&fs.ModeSymlink != 0 {
+			// some platforms' link() follows symlinks
+			return fmt.Errorf("%w: hard link to symlink %q", ErrUnsafePath,
+				header.Linkname)
+		}
+		_ = os.Remove(path)
+		return os.Link(target, path)
+	case tar.TypeReg:
+		out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
+			perm)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(out, reader); err != nil {
+			out.Close()
+			return err
+		}
+		if err = out.Close(); err != nil {
+			return err
+		}
+		if err = os.Chmod(path, perm); err != nil { // ignore umask
+			return err
+		}
+		return os.Chtimes(path, header.ModTime, header.ModTime)
+	}
+	return nil // skip devices, FIFOs, etc.
+}
+
+// ArchiveEntry describes an entry in an archive as returned by
+// [ArchiveEntries].
+type ArchiveEntry struct {
+	Name    string // slash-separated; folders end with "/"
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+}
+
+// ArchiveEntries returns an iterator of (entry, error) for every entry in
+// the given zip, tar, or tar.gz archive (detected by content, not name)
+// without extracting anything.
+func ArchiveEntries(path string) iter.Seq2[ArchiveEntry, error] {
+	return func(yield func(ArchiveEntry, error) bool) {
+		file, err := os.Open(path)
+		if err != nil {
+			yield(ArchiveEntry{}, err)
+			return
+		}
+		defer file.Close()
+		magic := make([]byte, 4)
+		n, _ := io.ReadFull(file, magic)
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			yield(ArchiveEntry{}, err)
+			return
+		}
+		if n == 4 && bytes.HasPrefix(magic, []byte("PK")) {
+			zipEntries(file, yield)
+		} else {
+			tarEntries(file, n >= 2 && magic[0] == 0x1F && magic[1] == 0x8B,
+				yield)
+		}
+	}
+}
+
+func zipEntries(file *os.File, yield func(ArchiveEntry, error) bool) {
+	info, err := file.Stat()
+	if err != nil {
+		yield(ArchiveEntry{}, err)
+		return
+	}
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		yield(ArchiveEntry{}, err)
+		return
+	}
+	for _, entry := range reader.File {
+		if !yield(ArchiveEntry{Name: entry.Name,
+			Size:    int64(entry.UncompressedSize64),
+			Mode:    entry.Mode(),
+			ModTime: entry.Modified}, nil) {
+			return
+		}
+	}
+}
+
+func tarEntries(file *os.File, gzipped bool,
+	yield func(ArchiveEntry, error) bool,
+) {
+	var reader io.Reader = file
+	if gzipped {
+		gzreader, err := gzip.NewReader(file)
+		if err != nil {
+			yield(ArchiveEntry{}, err)
+			return
+		}
+		defer gzreader.Close()
+		reader = gzreader
+	}
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			yield(ArchiveEntry{}, err)
+			return
+		}
+		if !yield(ArchiveEntry{Name: header.Name, Size: header.Size,
+			Mode: header.FileInfo().Mode(), ModTime: header.ModTime},
+			nil) {
+			return
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package ufile
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func makeTestTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	if err := MakeTree(root, files); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_ZipDir_Unzip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	makeTestTree(t, src, map[string]string{"a.txt": "alpha",
+		"sub/b.txt": "beta", "sub/c.o": "object", "build/d.txt": "delta"})
+	zipPath := filepath.Join(dir, "out.zip")
+	opts := ZipOptions{Exclude: []string{"*.o", "build"}, Level: 9,
+		Deterministic: true}
+	if err := ZipDir(src, zipPath, opts); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := os.ReadFile(zipPath)
+	if err := ZipDir(src, zipPath, opts); err != nil {
+		t.Fatal(err)
+	}
+	if second, _ := os.ReadFile(zipPath); !bytes.Equal(first, second) {
+		t.Error("expected deterministic zip files to be identical")
+	}
+	dst := filepath.Join(dir, "dst")
+	if err := Unzip(zipPath, dst); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"a.txt": "alpha",
+		"sub/b.txt": "beta"} {
+		raw, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+		if err != nil || string(raw) != content {
+			t.Errorf("expected %q got %q %v", content, raw, err)
+		}
+	}
+	for _, name := range []string{"sub/c.o", "build"} {
+		if PathExists(filepath.Join(dst, filepath.FromSlash(name))) {
+			t.Errorf("expected %s to be excluded", name)
+		}
+	}
+}
+
+func Test_Unzip_slip(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "evil.zip")
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for _, name := range []string{"ok.txt", "../evil.txt"} {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = entry.Write([]byte("x"))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(zipPath, buffer.Bytes(), ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "dst")
+	if err := Unzip(zipPath, dst); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("expected ErrUnsafePath got %v", err)
+	}
+	if PathExists(filepath.Join(dir, "evil.txt")) ||
+		PathExists(filepath.Join(dst, "ok.txt")) {
+		t.Error("expected nothing to be extracted")
+	}
+}
+
+func Test_Unzip_symlinkChain(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	for i, entries := range [][][2]string{
+		{{"a", "."}, {"a/b", ".."}, {"a/b/evil.txt", ""}},
+		{{"a", "."}, {"b", "a/.."}},
+		{{"a", "/tmp"}},
+	} {
+		dir := t.TempDir()
+		zipPath := filepath.Join(dir, "evil.zip")
+		var buffer bytes.Buffer
+		writer := zip.NewWriter(&buffer)
+		for _, entry := range entries {
+			header := &zip.FileHeader{Name: entry[0]}
+			header.SetMode(0o644)
+			if entry[1] != "" {
+				header.SetMode(fs.ModeSymlink | 0o777)
+			}
+			out, err := writer.CreateHeader(header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = out.Write([]byte(entry[1]))
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		err := os.WriteFile(zipPath, buffer.Bytes(), ModeURW)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := filepath.Join(dir, "dst")
+		if err = Unzip(zipPath, dst); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("#%d: expected ErrUnsafePath got %v", i, err)
+		}
+		if PathExists(filepath.Join(dir, "evil.txt")) {
+			t.Errorf("#%d: expected nothing outside dst", i)
+		}
+	}
+}
+
+func Test_TarDir_Untar(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	makeTestTree(t, src, map[string]string{"a.txt": "alpha",
+		"sub/b.sh": "#!/bin/sh", "skip/c.txt": "gamma"})
+	if err := os.Chmod(filepath.Join(src, "sub/b.sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	hasSymlinks := os.Symlink("sub/b.sh", filepath.Join(src, "link")) == nil
+	var buffer bytes.Buffer
+	if err := TarDir(src, &buffer, TarOptions{Gzip: true,
+		Exclude: []string{"skip"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buffer.Bytes(), []byte{0x1F, 0x8B}) {
+		t.Error("expected gzip output")
+	}
+	dst := filepath.Join(dir, "dst")
+	if err := Untar(&buffer, dst); err != nil {
+		t.Fatal(err)
+	}
+	if raw, err := os.ReadFile(filepath.Join(dst, "a.txt")); err != nil ||
+		string(raw) != "alpha" {
+		t.Errorf("expected alpha got %q %v", raw, err)
+	}
+	info, err := os.Stat(filepath.Join(dst, "sub", "b.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o755 {
+		t.Errorf("expected 0755 got %o", info.Mode().Perm())
+	}
+	if hasSymlinks {
+		if target, err := os.Readlink(filepath.Join(dst,
+			"link")); err != nil || target != filepath.FromSlash("sub/b.sh") {
+			t.Errorf("expected sub/b.sh got %q %v", target, err)
+		}
+	}
+	if PathExists(filepath.Join(dst, "skip")) {
+		t.Error("expected skip to be excluded")
+	}
+}
+
+func Test_Untar_symlinkChain(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	symlink := func(name, target string) *tar.Header {
+		return &tar.Header{Name: name, Typeflag: tar.TypeSymlink,
+			Linkname: target}
+	}
+	for i, headers := range [][]*tar.Header{
+		{symlink("a", "."), symlink("a/b", ".."),
+			{Name: "a/b/evil.txt", Typeflag: tar.TypeReg, Mode: 0o644}},
+		{{Name: "h", Typeflag: tar.TypeLink, Linkname: "ext/secret"}},
+	} {
+		dir := t.TempDir()
+		makeTestTree(t, dir, map[string]string{"outside/secret": "x",
+			"dst/": ""})
+		dst := filepath.Join(dir, "dst")
+		if err := os.Symlink(filepath.Join(dir, "outside"),
+			filepath.Join(dst, "ext")); err != nil {
+			t.Fatal(err)
+		}
+		var buffer bytes.Buffer
+		writer := tar.NewWriter(&buffer)
+		for _, header := range headers {
+			if err := writer.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := Untar(&buffer, dst); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("#%d: expected ErrUnsafePath got %v", i, err)
+		}
+		if PathExists(filepath.Join(dir, "evil.txt")) ||
+			PathExists(filepath.Join(dst, "h")) {
+			t.Errorf("#%d: expected nothing written", i)
+		}
+	}
+}
+
+func Test_DeterministicArchives(t *testing.T) {
+	dir := t.TempDir()
+	archive := func(name string, perm os.FileMode) ([]byte, []byte) {
+		src := filepath.Join(dir, name)
+		makeTestTree(t, src, map[string]string{"a.txt": "alpha",
+			"sub/b.txt": "beta"})
+		if err := os.Chmod(filepath.Join(src, "a.txt"), perm); err != nil {
+			t.Fatal(err)
+		}
+		os.Symlink(filepath.Join(AbsPath(src), "sub", "b.txt"),
+			filepath.Join(src, "link"))
+		zipPath := filepath.Join(dir, name+".zip")
+		if err := ZipDir(src, zipPath,
+			ZipOptions{Deterministic: true}); err != nil {
+			t.Fatal(err)
+		}
+		zipped, _ := os.ReadFile(zipPath)
+		var buffer bytes.Buffer
+		if err := TarDir(src, &buffer, TarOptions{Gzip: true,
+			Deterministic: true}); err != nil {
+			t.Fatal(err)
+		}
+		return zipped, buffer.Bytes()
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	zip1, tar1 := archive("one", 0o644)
+	zip2, tar2 := archive("two", 0o664)
+	if !bytes.Equal(zip1, zip2) {
+		t.Error("expected identical zip files")
+	}
+	if !bytes.Equal(tar1, tar2) {
+		t.Error("expected identical tar files")
+	}
+	if when := reproducibleTime(); when.Unix() != 1700000000 {
+		t.Errorf("expected SOURCE_DATE_EPOCH time got %v", when)
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "invalid")
+	if when := reproducibleTime(); !when.Equal(deterministicTime) {
+		t.Errorf("expected %v got %v", deterministicTime, when)
+	}
+}
+
+func Test_ArchiveEntries(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	makeTestTree(t, src, map[string]string{"a.txt": "alpha",
+		"sub/b.txt": "beta!"})
+	zipPath := filepath.Join(dir, "out.zip")
+	if err := ZipDir(src, zipPath, ZipOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	tgzPath := filepath.Join(dir, "out.tgz")
+	file, err := os.Create(tgzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = TarDir(src, file, TarOptions{Gzip: true}); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	for _, path := range []string{zipPath, tgzPath} {
+		sizes := map[string]int64{}
+		for entry, err := range ArchiveEntries(path) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			sizes[entry.Name] = entry.Size
+			if entry.Name == "sub/" && !entry.Mode.IsDir() {
+				t.Errorf("%s: expected sub/ to be a folder", path)
+			}
+		}
+		if len(sizes) != 3 || sizes["a.txt"] != 5 ||
+			sizes["sub/b.txt"] != 5 {
+			t.Errorf("%s: unexpected entries %v", path, sizes)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const modeDefault = 0o644
+
+// errUnchanged may be returned by a writeAtomic write function to abandon
+// the write leaving the original file untouched.
+var errUnchanged = errors.New("unchanged")
+
+// writeAtomic calls write with a buffered writer for a temporary file in
+// the same folder as filename and then renames the temporary file to
+// filename, so readers see either the old or the new file but never a
+// partial one. The folder is then synced (see [SyncDir]) so the new file
+// survives a crash. If filename exists its permissions are preserved.
+func writeAtomic(filename string, write func(*bufio.Writer) error) error {
+	return writeAtomicOpt(filename, false, write)
+}
+
+// writeAtomicOpt works like writeAtomic but if verify is true the
+// temporary file is read back and checked before the rename (see
+// verifyWritten).
+func writeAtomicOpt(filename string, verify bool,
+	write func(*bufio.Writer) error,
+) error {
+	tempname, err := writeTemp(filename, verify, write)
+	if err != nil {
+		return err
+	}
+	if err = rename(tempname, filename); err != nil {
+		os.Remove(tempname)
+		return err
+	}
+	return SyncDir(filepath.Dir(filename))
+}
+
+// writeTemp calls write with a buffered writer for a temporary file in the
+// same folder as filename and returns the synced and closed temporary
+// file's name. The temporary file has filename's permissions if filename
+// exists. Where possible (see [CreateAnonymous]) the file is created with
+// O_TMPFILE and only given its temporary name once fully written and
+// synced, so a crash mid-write leaves nothing behind. If verify is true
+// the temporary file is read back and checked (see verifyWritten). On
+// error the temporary file is removed.
+func writeTemp(filename string, verify bool,
+	write func(*bufio.Writer) error,
+) (string, error) {
+	perm := fs.FileMode(modeDefault)
+	if info, err := os.Stat(filename); err == nil {
+		perm = info.Mode().Perm()
+	}
+	dir := filepath.Dir(filename)
+	tempname := ""
+	file, err := openTmpFile(dir)
+	if err != nil {
+		file, err = os.CreateTemp(dir, "."+filepath.Base(filename)+
+			".*.tmp")
+		if err != nil {
+			return "", err
+		}
+		tempname = file.Name()
+	}
+	ok := false
+	defer func() {
+		if !ok {
+			file.Close()
+			if tempname != "" {
+				os.Remove(tempname)
+			}
+		}
+	}()
+	var writer io.Writer = file
+	hash := sha256.New()
+	if verify {
+		writer = io.MultiWriter(file, hash)
+	}
+	out := bufio.NewWriter(writer)
+	if err = write(out); err != nil {
+		return "", err
+	}
+	if err = out.Flush(); err != nil {
+		return "", err
+	}
+	if err = file.Chmod(perm); err != nil {
+		return "", err
+	}
+	if err = file.Sync(); err != nil {
+		return "", err
+	}
+	if tempname == "" {
+		if tempname, err = linkTemp(file, filename); err != nil {
+			return "", err
+		}
+	}
+	if err = file.Close(); err != nil {
+		return "", err
+	}
+	if verify {
+		if err = verifyWritten(tempname, hash.Sum(nil)); err != nil {
+			return "", err
+		}
+	}
+	ok = true
+	return tempname, nil
+}
+
+// linkTemp gives the O_TMPFILE file a new unique temporary name in the
+// same folder as filename and returns the name.
+func linkTemp(file *os.File, filename string) (string, error) {
+	prefix := filepath.Join(filepath.Dir(filename),
+		"."+filepath.Base(filename)+".")
+	for {
+		tempname := prefix + strconv.FormatUint(rand.Uint64(), 36) + ".tmp"
+		err := linkTmpFile(file, tempname)
+		if !errors.Is(err, fs.ErrExist) {
+			return tempname, err
+		}
+	}
+}
+
+// verifyWritten returns an error wrapping [ErrVerifyFailed] if the given
+// file's SHA256 checksum isn't sum.
+func verifyWritten(filename string, sum []byte) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return err
+	}
+	if !bytes.Equal(hash.Sum(nil), sum) {
+		return &fs.PathError{Op: "verify", Path: filename,
+			Err: ErrVerifyFailed}
+	}
+	return nil
+}
+
+// copyFileData copies the contents of src to a new or truncated dst
+// (which gets src's permissions if it is created).
+func copyFileData(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
+		info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
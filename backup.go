@@ -0,0 +1,215 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBackupLayout is the time layout used by [BackupFile] if none is
+// given.
+const DefaultBackupLayout = "20060102T150405"
+
+// BackupFile copies the given file (see [CopyFile]) into folder dir
+// (which is created if necessary, and which is the file's own folder if
+// dir is "") with the current time inserted before its suffix, e.g.,
+// "config.json" is backed up as "config-20240131T120000.json", and
+// returns the backup's filename. The time is formatted using layout (see
+// [time.Time.Format]), or [DefaultBackupLayout] if layout is "". For
+// [PruneBackups] to work the layout must be the default, or for
+// [PruneBackupsLayout] it must contain no "." and the same layout must be
+// passed to it. If a backup of the same name already
+// exists "-2", "-3", etc., is appended to the time.
+func BackupFile(path, dir, layout string) (string, error) {
+	if layout == "" {
+		layout = DefaultBackupLayout
+	}
+	if dir == "" {
+		dir = filepath.Dir(path)
+	}
+	if err := EnsureDir(dir, 0o755); err != nil {
+		return "", err
+	}
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext) + "-" + time.Now().Format(layout)
+	backup := filepath.Join(dir, stem+ext)
+	for i := 2; PathExists(backup); i++ {
+		backup = filepath.Join(dir, fmt.Sprintf("%s-%d%s", stem, i, ext))
+	}
+	return backup, CopyFile(path, backup)
+}
+
+// PruneBackups removes all but the newest keep backups of each file in
+// folder dir made by [BackupFile] with [DefaultBackupLayout] (e.g.,
+// "config-20240131T120000.json") and returns how many were removed.
+// Backups are grouped by their original filename and ordered by the time
+// in their names (and then by any "-2", "-3", etc., suffix). Only files
+// whose names contain a time that parses with the layout (see
+// [time.Parse]) are considered backups, so other files in dir (e.g.,
+// "chapter-1.txt") are never removed. See also [PruneBackupsLayout].
+func PruneBackups(dir string, keep int) (removed int, err error) {
+	return PruneBackupsLayout(dir, DefaultBackupLayout, keep)
+}
+
+// PruneBackupsLayout works like [PruneBackups] but for backups made by
+// [BackupFile] with the given layout (or [DefaultBackupLayout] if layout
+// is "").
+func PruneBackupsLayout(dir, layout string, keep int) (removed int,
+	err error,
+) {
+	if keep < 0 {
+		return 0, fmt.Errorf("invalid number of backups to keep %d", keep)
+	}
+	if layout == "" {
+		layout = DefaultBackupLayout
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	groups := make(map[string][]backupName)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if backup, ok := parseBackupName(entry.Name(), layout); ok {
+			groups[backup.key] = append(groups[backup.key], backup)
+		}
+	}
+	for _, backups := range groups {
+		slices.SortFunc(backups, func(a, b backupName) int { // newest first
+			if c := b.when.Compare(a.when); c != 0 {
+				return c
+			}
+			return cmp.Compare(b.n, a.n)
+		})
+		for _, backup := range backups[min(keep, len(backups)):] {
+			err = os.Remove(filepath.Join(dir, backup.name))
+			if err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// backupName holds a backup's filename, the original filename it is a
+// backup of (key), its time, and its "-N" suffix number (1 if none).
+type backupName struct {
+	name string
+	key  string
+	when time.Time
+	n    int
+}
+
+// parseBackupName returns the backupName for the given filename and true
+// if it is named as [BackupFile] would name a backup using layout;
+// otherwise returns false.
+func parseBackupName(name, layout string) (backupName, bool) {
+	ext := filepath.Ext(name)
+	rest := strings.TrimSuffix(name, ext)
+	for i := 1; i < len(rest); i++ {
+		if rest[i] != '-' {
+			continue
+		}
+		stamp, n := rest[i+1:], 1
+		if j := strings.LastIndexByte(stamp, '-'); j > 0 {
+			if suffix, err := strconv.Atoi(stamp[j+1:]); err == nil &&
+				suffix > 1 && stamp[j+1] != '+' {
+				if when, ok := parseStamp(stamp[:j], layout); ok {
+					return backupName{name, rest[:i] + ext, when, suffix},
+						true
+				}
+			}
+		}
+		if when, ok := parseStamp(stamp, layout); ok {
+			return backupName{name, rest[:i] + ext, when, n}, true
+		}
+	}
+	return backupName{}, false
+}
+
+// parseStamp returns the time in stamp and true if stamp is exactly a time
+// formatted with layout; otherwise returns false.
+func parseStamp(stamp, layout string) (time.Time, bool) {
+	when, err := time.Parse(layout, stamp)
+	if err != nil || when.Format(layout) != stamp {
+		return time.Time{}, false
+	}
+	return when, true
+}
+
+// SnapshotDir makes a snapshot of the tree rooted at folder src in a new
+// folder in snapshotsRoot (which is created if necessary) and returns the
+// snapshot folder's name. The snapshot is named after src with the
+// current time appended (formatted using [DefaultBackupLayout]), e.g.,
+// "photos-20240131T120000". Regular files are hard-linked into the
+// snapshot which is fast and uses no extra space, with a fallback to
+// copying (see [CopyFile]) if linking fails, e.g., across filesystems.
+// Since hard-linked files share their data the snapshot is only
+// preserved for files that are removed or replaced by renaming (as
+// atomic writers such as [SaveConfig] and [Ini.Save] do), not for files
+// that are modified or rewritten in place.
+func SnapshotDir(src, snapshotsRoot string) (string, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", &fs.PathError{Op: "snapshot", Path: src,
+			Err: fs.ErrInvalid}
+	}
+	if err = EnsureDir(snapshotsRoot, 0o755); err != nil {
+		return "", err
+	}
+	name := filepath.Base(AbsPath(src)) + "-" +
+		time.Now().Format(DefaultBackupLayout)
+	snapshot := filepath.Join(snapshotsRoot, name)
+	for i := 2; PathExists(snapshot); i++ {
+		snapshot = filepath.Join(snapshotsRoot, fmt.Sprintf("%s-%d", name,
+			i))
+	}
+	err = filepath.WalkDir(src, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(snapshot, rel)
+		switch mode := dirEntry.Type(); {
+		case mode.IsDir():
+			info, err := dirEntry.Info()
+			if err != nil {
+				return err
+			}
+			return os.Mkdir(target, info.Mode().Perm()|0o700)
+		case mode&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case mode.IsRegular():
+			if os.Link(path, target) != nil {
+				_, err = copyFile(path, target, CloneAuto, nil)
+			}
+			return err
+		}
+		return nil // skip sockets, devices, etc.
+	})
+	return snapshot, err
+}
@@ -0,0 +1,103 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func Test_BackupFile(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"config.json": "{}"})
+	filename := filepath.Join(dir, "config.json")
+	backups := filepath.Join(dir, "backups")
+	rx := regexp.MustCompile(`^config-\d{8}T\d{6}(-\d)?\.json$`)
+	for range 3 {
+		backup, err := BackupFile(filename, backups, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rx.MatchString(filepath.Base(backup)) {
+			t.Errorf("unexpected backup name %q", backup)
+		}
+		if same, err := FilesEqual(filename, backup); err != nil || !same {
+			t.Errorf("expected %q to equal %q %v", backup, filename, err)
+		}
+	}
+	makeTestTree(t, backups, map[string]string{
+		"notes-20240101T000000.txt": "1", "notes-20240102T000000.txt": "2",
+		"notes-20240103T000000.txt": "3", "notes-20240103T000000-9.txt": "4",
+		"notes-20240103T000000-10.txt": "5", "README": "keep",
+		"chapter-1.txt": "keep", "chapter-2.txt": "keep",
+		"report-2023.pdf": "keep"})
+	removed, err := PruneBackups(backups, 1)
+	if err != nil || removed != 6 {
+		t.Errorf("expected 6 removed got %d %v", removed, err)
+	}
+	entries, _ := ListDir(backups, ListOptions{})
+	var names []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "config-") {
+			names = append(names, entry.Name())
+		}
+	}
+	expected := []string{"chapter-1.txt", "chapter-2.txt",
+		"notes-20240103T000000-10.txt", "README", "report-2023.pdf"}
+	if len(entries) != 6 || !slices.Equal(names, expected) {
+		t.Errorf("expected config backup and %v got %v", expected, names)
+	}
+	dated := filepath.Join(dir, "dated")
+	makeTestTree(t, dated, map[string]string{"log-2024-01-01.txt": "1",
+		"log-2024-01-02.txt": "2", "log-20240103T000000.txt": "3"})
+	removed, err = PruneBackupsLayout(dated, "2006-01-02", 1)
+	if err != nil || removed != 1 || PathExists(filepath.Join(dated,
+		"log-2024-01-01.txt")) {
+		t.Errorf("expected log-2024-01-01.txt removed got %d %v", removed,
+			err)
+	}
+}
+
+func Test_SnapshotDir(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	makeTestTree(t, src, map[string]string{"a.txt": "alpha",
+		"sub/b.txt": "beta", "empty/": ""})
+	snapshots := filepath.Join(dir, "snapshots")
+	snapshot, err := SnapshotDir(src, snapshots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^src-\d{8}T\d{6}$`).MatchString(
+		filepath.Base(snapshot)) {
+		t.Errorf("unexpected snapshot name %q", snapshot)
+	}
+	expected, _ := TreeString(src)
+	if tree, _ := TreeString(snapshot); tree != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, tree)
+	}
+	if err = os.Remove(filepath.Join(src, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err = WriteTextFile(filepath.Join(src, "a.txt"),
+		[]string{"changed"}); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := ReadTextFile(filepath.Join(snapshot, "a.txt"))
+	if err != nil || lines[0] != "alpha" {
+		t.Errorf("expected %q got %q %v", "alpha", lines, err)
+	}
+	again, err := SnapshotDir(src, snapshots)
+	if err != nil || again == snapshot {
+		t.Errorf("expected a new snapshot got %q %v", again, err)
+	}
+	if _, err = SnapshotDir(filepath.Join(src, "a.txt"),
+		snapshots); err == nil {
+		t.Error("expected error for non-folder")
+	}
+	if _, err = os.Stat(filepath.Join(again, "empty")); err != nil {
+		t.Error(err)
+	}
+}
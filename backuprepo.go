@@ -0,0 +1,333 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackupOptions are used by [Backup].
+//
+// Entries whose names or slash-separated paths relative to the source
+// folder match any of the Exclude globs (see [filepath.Match]) are
+// skipped (and excluded folders aren't descended into). Chunking controls
+// how files are split into chunks (see [CDCOptions]; its Algo is ignored
+// since chunks are always identified by their SHA-256 hashes).
+type BackupOptions struct {
+	Exclude  []string
+	Chunking CDCOptions
+}
+
+// backupManifest is a snapshot as saved in a backup repository.
+type backupManifest struct {
+	ID      string
+	Time    time.Time
+	Source  string
+	Entries []backupEntry
+}
+
+// backupEntry is a folder, file, or symlink in a backupManifest.
+type backupEntry struct {
+	Path    string // slash-separated and relative to the source
+	Mode    fs.FileMode
+	ModTime time.Time
+	Size    int64    `json:",omitempty"`
+	Target  string   `json:",omitempty"` // for symlinks
+	Chunks  []string `json:",omitempty"` // for regular files
+}
+
+// Backup makes a snapshot of the tree rooted at folder srcRoot in the
+// backup repository repoDir (which is created if necessary) and returns
+// the snapshot's ID (the current time formatted using
+// [DefaultBackupLayout], with "-2", "-3", etc., appended if needed).
+// Files are split into content-defined chunks (see [ChunkFileCDC]) which
+// are stored in a [BlobStore] in repoDir's "chunks" subfolder, so each
+// distinct chunk is only stored once however many files or snapshots
+// contain it. Files whose size and modification time are unchanged since
+// the previous snapshot aren't read again. Each snapshot's manifest (a
+// JSON file listing its folders, files with their chunks, and symlinks)
+// is stored in repoDir's "snapshots" subfolder. Sockets, devices, etc.,
+// are skipped. See also [Restore] and [Snapshots].
+func Backup(srcRoot, repoDir string, opts BackupOptions) (string, error) {
+	srcRoot = filepath.Clean(srcRoot)
+	store, err := OpenBlobStore(filepath.Join(repoDir, "chunks"))
+	if err != nil {
+		return "", err
+	}
+	snapshotsDir := filepath.Join(repoDir, "snapshots")
+	if err = EnsureDir(snapshotsDir, 0o755); err != nil {
+		return "", err
+	}
+	previous, err := previousBackupEntries(repoDir)
+	if err != nil {
+		return "", err
+	}
+	opts.Chunking.Algo = SHA256
+	manifest := backupManifest{Time: time.Now(), Source: AbsPath(srcRoot)}
+	err = filepath.WalkDir(srcRoot, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if path == srcRoot {
+			return nil
+		}
+		rel, err := filepath.Rel(srcRoot, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchesAnyGlob(opts.Exclude, dirEntry.Name(), rel) {
+			if dirEntry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		entry := backupEntry{Path: rel, Mode: info.Mode(),
+			ModTime: info.ModTime()}
+		switch mode := info.Mode(); {
+		case mode.IsDir():
+		case mode&fs.ModeSymlink != 0:
+			if entry.Target, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			entry.Size = info.Size()
+			if entry.Chunks, err = backupChunks(store, path, entry,
+				previous[rel], opts.Chunking); err != nil {
+				return err
+			}
+		default:
+			return nil // skip sockets, devices, etc.
+		}
+		manifest.Entries = append(manifest.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	manifest.ID = manifest.Time.Format(DefaultBackupLayout)
+	filename := filepath.Join(snapshotsDir, manifest.ID+".json")
+	for i := 2; PathExists(filename); i++ {
+		manifest.ID = fmt.Sprintf("%s-%d",
+			manifest.Time.Format(DefaultBackupLayout), i)
+		filename = filepath.Join(snapshotsDir, manifest.ID+".json")
+	}
+	return manifest.ID, writeAtomic(filename, func(out *bufio.Writer) error {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(manifest)
+	})
+}
+
+// backupChunks returns the hashes of the chunks of file path (whose entry
+// is given), storing any new chunks, or previous's chunks if the file is
+// unchanged since the previous snapshot.
+func backupChunks(store *BlobStore, path string, entry,
+	previous backupEntry, opts CDCOptions,
+) ([]string, error) {
+	if previous.Mode.IsRegular() && previous.Size == entry.Size &&
+		previous.ModTime.Equal(entry.ModTime) &&
+		!slices.ContainsFunc(previous.Chunks, func(hash string) bool {
+			return !store.Has(hash)
+		}) {
+		return previous.Chunks, nil
+	}
+	var chunks []string
+	for chunk, err := range ChunkFileCDC(path, opts) {
+		if err != nil {
+			return nil, err
+		}
+		if !store.Has(chunk.Hash) {
+			if _, err = store.Put(bytes.NewReader(chunk.Data)); err != nil {
+				return nil, err
+			}
+		}
+		chunks = append(chunks, chunk.Hash)
+	}
+	return chunks, nil
+}
+
+// previousBackupEntries returns the entries of the repository's newest
+// snapshot keyed by path, or an empty map if there are no snapshots.
+func previousBackupEntries(repoDir string) (map[string]backupEntry,
+	error,
+) {
+	entries := make(map[string]backupEntry)
+	ids, err := Snapshots(repoDir)
+	if err != nil || len(ids) == 0 {
+		return entries, err
+	}
+	manifest, err := readBackupManifest(repoDir, ids[len(ids)-1])
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range manifest.Entries {
+		entries[entry.Path] = entry
+	}
+	return entries, nil
+}
+
+// Snapshots returns the IDs of the snapshots in the backup repository
+// repoDir, oldest first. See also [Backup] and [Restore].
+func Snapshots(repoDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(repoDir, "snapshots"))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		if id, ok := strings.CutSuffix(entry.Name(), ".json"); ok &&
+			entry.Type().IsRegular() {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		aTime, aNum := splitSnapshotID(a)
+		bTime, bNum := splitSnapshotID(b)
+		return cmp.Or(strings.Compare(aTime, bTime), cmp.Compare(aNum,
+			bNum))
+	})
+	return ids, nil
+}
+
+// splitSnapshotID returns a snapshot ID's time and its number (1 if it
+// has no "-N" suffix).
+func splitSnapshotID(id string) (string, int) {
+	if i := strings.LastIndexByte(id, '-'); i > -1 {
+		if n, err := strconv.Atoi(id[i+1:]); err == nil {
+			return id[:i], n
+		}
+	}
+	return id, 1
+}
+
+func readBackupManifest(repoDir, snapshotID string) (backupManifest,
+	error,
+) {
+	var manifest backupManifest
+	if !filepath.IsLocal(snapshotID) || strings.ContainsAny(snapshotID,
+		`/\`) {
+		return manifest, &fs.PathError{Op: "snapshot", Path: snapshotID,
+			Err: fs.ErrInvalid}
+	}
+	raw, err := os.ReadFile(filepath.Join(repoDir, "snapshots",
+		snapshotID+".json"))
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, json.Unmarshal(raw, &manifest)
+}
+
+// Restore recreates the snapshot with the given ID (see [Snapshots]) from
+// the backup repository repoDir in folder dstRoot (which is created if
+// necessary), restoring permissions and modification times. Existing
+// files and symlinks in dstRoot are replaced but other existing files are
+// left alone. A snapshot entry whose path would escape dstRoot, including
+// by going through a symlink (whether restored earlier or already in
+// dstRoot), causes [ErrUnsafePath] to be returned. See also [Backup].
+func Restore(repoDir, snapshotID, dstRoot string) error {
+	manifest, err := readBackupManifest(repoDir, snapshotID)
+	if err != nil {
+		return err
+	}
+	store := &BlobStore{dir: filepath.Join(repoDir, "chunks")}
+	if err = EnsureDir(dstRoot, 0o755); err != nil {
+		return err
+	}
+	var dirs []backupEntry
+	for _, entry := range manifest.Entries {
+		if !filepath.IsLocal(filepath.FromSlash(entry.Path)) {
+			return fmt.Errorf("%w: %q", ErrUnsafePath, entry.Path)
+		}
+		path := filepath.Join(dstRoot, filepath.FromSlash(entry.Path))
+		if err = prepareEntryPath(dstRoot, path); err != nil {
+			return err
+		}
+		switch {
+		case entry.Mode.IsDir():
+			if err = os.MkdirAll(path, 0o700); err != nil {
+				return err
+			}
+			dirs = append(dirs, entry)
+		case entry.Mode&fs.ModeSymlink != 0:
+			if err = os.Remove(path); err != nil &&
+				!errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			if err = os.Symlink(entry.Target, path); err != nil {
+				return err
+			}
+		default:
+			if err = restoreBackupFile(store, path, entry); err != nil {
+				return err
+			}
+		}
+	}
+	for _, entry := range slices.Backward(dirs) {
+		path := filepath.Join(dstRoot, filepath.FromSlash(entry.Path))
+		if info, err := os.Lstat(path); err != nil || !info.IsDir() ||
+			checkParents(dstRoot, path) != nil {
+			continue // replaced by a later entry
+		}
+		if err = os.Chmod(path, entry.Mode.Perm()); err != nil {
+			return err
+		}
+		if err = os.Chtimes(path, entry.ModTime, entry.ModTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreBackupFile atomically writes the file at path from its entry's
+// chunks and sets its permissions and modification time.
+func restoreBackupFile(store *BlobStore, path string,
+	entry backupEntry,
+) error {
+	err := writeAtomic(path, func(out *bufio.Writer) error {
+		var size int64
+		for _, hash := range entry.Chunks {
+			chunk, err := store.Get(hash)
+			if err != nil {
+				return err
+			}
+			n, err := io.Copy(out, chunk)
+			chunk.Close()
+			if err != nil {
+				return err
+			}
+			size += n
+		}
+		if size != entry.Size {
+			return fmt.Errorf("restore %q: expected %d bytes got %d",
+				entry.Path, entry.Size, size)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(path, entry.Mode.Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(path, entry.ModTime, entry.ModTime)
+}
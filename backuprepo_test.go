@@ -0,0 +1,124 @@
+package ufile
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_BackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	repo := filepath.Join(dir, "repo")
+	big := bytes.Repeat([]byte("all work and no play "), 10_000)
+	makeTestTree(t, src, map[string]string{"a.txt": "alpha",
+		"sub/b.txt": "beta", "empty/": "", "cache/x.tmp": "skip"})
+	if err := os.WriteFile(filepath.Join(src, "big.dat"), big,
+		ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	os.Symlink("sub/b.txt", filepath.Join(src, "link"))
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filepath.Join(src, "a.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	opts := BackupOptions{Exclude: []string{"cache"}}
+	first, err := Backup(src, repo, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = WriteTextFile(filepath.Join(src, "sub", "b.txt"),
+		[]string{"beta v2"}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := Backup(src, repo, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids, err := Snapshots(repo); err != nil ||
+		!slices.Equal(ids, []string{first, second}) {
+		t.Errorf("expected %q got %q %v", []string{first, second}, ids,
+			err)
+	}
+	dst := filepath.Join(dir, "dst")
+	if err = Restore(repo, first, dst); err != nil {
+		t.Fatal(err)
+	}
+	if lines, _ := ReadTextFile(filepath.Join(dst, "sub",
+		"b.txt")); !slices.Equal(lines, []string{"beta"}) {
+		t.Errorf("expected first version got %q", lines)
+	}
+	if err = Restore(repo, second, dst); err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(filepath.Join(src, "cache"))
+	report, err := DiffDirs(src, dst, DiffOptions{ByHash: true})
+	if err != nil || !report.Same() {
+		t.Errorf("expected restored tree to match got %+v %v", report, err)
+	}
+	if info, _ := os.Stat(filepath.Join(dst, "a.txt")); info == nil ||
+		!info.ModTime().Equal(old) {
+		t.Errorf("expected modification time %v", old)
+	}
+	if err = Restore(repo, "../x", dst); err == nil {
+		t.Error("expected error for invalid snapshot ID")
+	}
+	if _, err = readBackupManifest(repo, "missing"); !errors.Is(err,
+		os.ErrNotExist) {
+		t.Errorf("expected ErrNotExist got %v", err)
+	}
+}
+
+func Test_SnapshotsOrder(t *testing.T) {
+	repo := t.TempDir()
+	makeTestTree(t, repo, map[string]string{
+		"snapshots/20240101T120000-10.json": "{}",
+		"snapshots/20240101T120000-2.json":  "{}",
+		"snapshots/20240101T120000.json":    "{}",
+		"snapshots/20231231T235959.json":    "{}",
+		"snapshots/notes.txt":               ""})
+	ids, err := Snapshots(repo)
+	expected := []string{"20231231T235959", "20240101T120000",
+		"20240101T120000-2", "20240101T120000-10"}
+	if err != nil || !slices.Equal(ids, expected) {
+		t.Errorf("expected %q got %q %v", expected, ids, err)
+	}
+}
+
+func Test_Restore_symlinkEscape(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	dir := t.TempDir()
+	elsewhere := filepath.Join(dir, "elsewhere")
+	manifest := `{"ID": "evil", "Entries": [
+	{"Path": "l", "Mode": ` + strconv.Itoa(int(fs.ModeSymlink|0o777)) +
+		`, "Target": ` + strconv.Quote(elsewhere) + `},
+	{"Path": "l/x", "Mode": 420}]}`
+	makeTestTree(t, dir, map[string]string{"elsewhere/": "",
+		"repo/snapshots/evil.json": manifest,
+		"repo/snapshots/existing.json": `{"ID": "existing", "Entries": [
+	{"Path": "e/x", "Mode": 420}]}`})
+	dst := filepath.Join(dir, "dst")
+	if err := Restore(filepath.Join(dir, "repo"), "evil",
+		dst); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("expected ErrUnsafePath got %v", err)
+	}
+	if err := os.Symlink(elsewhere, filepath.Join(dst, "e")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(filepath.Join(dir, "repo"), "existing",
+		dst); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("expected ErrUnsafePath got %v", err)
+	}
+	if PathExists(filepath.Join(elsewhere, "x")) {
+		t.Error("expected nothing written outside dst")
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// BlobStore is a content-addressed store of blobs (i.e., arbitrary
+// data), each stored once in a file named after its SHA-256 checksum. The
+// files are in a fan-out folder layout to keep folders small, e.g., a blob
+// whose hash is "ab12..." is stored as dir/ab/ab12.... BlobStore methods
+// are safe for concurrent use (including by separate processes) since
+// blobs are written atomically and never modified. Create with
+// [OpenBlobStore].
+type BlobStore struct {
+	dir string
+}
+
+// OpenBlobStore returns a BlobStore that keeps its blobs in folder dir,
+// creating it if necessary.
+func OpenBlobStore(dir string) (*BlobStore, error) {
+	if err := EnsureDir(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &BlobStore{dir: dir}, nil
+}
+
+// Dir returns the BlobStore's folder.
+func (me *BlobStore) Dir() string { return me.dir }
+
+// Put stores all the data read from r as a blob (unless an identical blob
+// is already stored) and returns its hash (SHA-256 lowercase hex).
+func (me *BlobStore) Put(r io.Reader) (hash string, err error) {
+	file, err := os.CreateTemp(me.dir, ".blob-*")
+	if err != nil {
+		return "", err
+	}
+	tempname := file.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tempname)
+		}
+	}()
+	hasher := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(file, hasher), r); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err = file.Close(); err != nil {
+		return "", err
+	}
+	hash = hex.EncodeToString(hasher.Sum(nil))
+	filename := me.path(hash)
+	if FileExists(filename) {
+		os.Remove(tempname)
+		return hash, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return "", err
+	}
+	return hash, rename(tempname, filename)
+}
+
+// Get returns a reader for the blob with the given hash; the caller must
+// close it. If there is no such blob the error wraps [fs.ErrNotExist].
+func (me *BlobStore) Get(hash string) (io.ReadCloser, error) {
+	if !isBlobHash(hash) {
+		return nil, &fs.PathError{Op: "get", Path: hash, Err: fs.ErrInvalid}
+	}
+	return os.Open(me.path(hash))
+}
+
+// Has returns true if the BlobStore has a blob with the given hash;
+// otherwise returns false.
+func (me *BlobStore) Has(hash string) bool {
+	return isBlobHash(hash) && FileExists(me.path(hash))
+}
+
+// blobTempMaxAge is how old a temporary file left by [BlobStore.Put]
+// must be before [BlobStore.GC] assumes it was abandoned (e.g., by a
+// crash) rather than still being written.
+const blobTempMaxAge = time.Hour
+
+// GC removes every blob whose hash isn't in live and returns how many
+// blobs were removed. Temporary files abandoned by interrupted Puts are
+// removed too. (Empty fan-out folders are kept since removing them could
+// make a concurrent Put fail.)
+func (me *BlobStore) GC(live []string) (removed int, err error) {
+	keep := make(map[string]bool, len(live))
+	for _, hash := range live {
+		keep[strings.ToLower(hash)] = true
+	}
+	subdirs, err := os.ReadDir(me.dir)
+	if err != nil {
+		return 0, err
+	}
+	for _, subdir := range subdirs {
+		if strings.HasPrefix(subdir.Name(), ".blob-") {
+			me.removeStaleTemp(subdir)
+			continue
+		}
+		if !subdir.IsDir() || len(subdir.Name()) != 2 {
+			continue
+		}
+		dir := filepath.Join(me.dir, subdir.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return removed, err
+		}
+		for _, entry := range entries {
+			if !isBlobHash(entry.Name()) || keep[entry.Name()] {
+				continue
+			}
+			if err = os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// removeStaleTemp removes the given temporary file if it is older than
+// blobTempMaxAge.
+func (me *BlobStore) removeStaleTemp(entry fs.DirEntry) {
+	info, err := entry.Info()
+	if err == nil && info.Mode().IsRegular() &&
+		time.Since(info.ModTime()) > blobTempMaxAge {
+		_ = os.Remove(filepath.Join(me.dir, entry.Name()))
+	}
+}
+
+// path returns the filename for the blob with the given hash.
+func (me *BlobStore) path(hash string) string {
+	hash = strings.ToLower(hash)
+	return filepath.Join(me.dir, hash[:2], hash)
+}
+
+// isBlobHash returns true if hash is a valid SHA-256 hex hash; otherwise
+// returns false.
+func isBlobHash(hash string) bool {
+	return regexp.MustCompile(`^[0-9a-fA-F]{64}$`).MatchString(hash)
+}
@@ -0,0 +1,73 @@
+package ufile
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_BlobStore(t *testing.T) {
+	store, err := OpenBlobStore(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello, err := store.Put(strings.NewReader("hello"))
+	expected := "2cf24dba5fb0a30e26e83b2ac5b9e29e" +
+		"1b161e5c1fa7425e73043362938b9824"
+	if err != nil || hello != expected {
+		t.Errorf("expected %q got %q %v", expected, hello, err)
+	}
+	again, err := store.Put(strings.NewReader("hello"))
+	if err != nil || again != hello {
+		t.Errorf("expected %q got %q %v", hello, again, err)
+	}
+	world, err := store.Put(strings.NewReader("world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := store.Get(hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(data) != "hello" {
+		t.Errorf("expected %q got %q %v", "hello", data, err)
+	}
+	if _, err = store.Get("../../etc/passwd"); !errors.Is(err,
+		fs.ErrInvalid) {
+		t.Errorf("expected %v got %v", fs.ErrInvalid, err)
+	}
+	oldTemp := filepath.Join(store.Dir(), ".blob-123")
+	newTemp := filepath.Join(store.Dir(), ".blob-456")
+	makeTestTree(t, store.Dir(), map[string]string{".blob-123": "",
+		".blob-456": ""})
+	old := time.Now().Add(-2 * blobTempMaxAge)
+	if err = os.Chtimes(oldTemp, old, old); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := store.GC([]string{world})
+	if err != nil || removed != 1 {
+		t.Errorf("expected 1 removed got %d %v", removed, err)
+	}
+	if store.Has(hello) || !store.Has(world) {
+		t.Error("expected only the live blob to remain")
+	}
+	if _, err = store.Get(hello); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected %v got %v", fs.ErrNotExist, err)
+	}
+	// the emptied fan-out folder is kept
+	expectedTree := ".\n├── .blob-456\n├── 2c/\n└── 48/\n    └── " + world +
+		"\n"
+	if tree, _ := TreeString(store.Dir()); tree != expectedTree {
+		t.Errorf("expected\n%s\ngot\n%s", expectedTree, tree)
+	}
+	if PathExists(oldTemp) || !PathExists(newTemp) {
+		t.Error("expected only the stale temporary file to be removed")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CopyRange copies length bytes starting at offset from file src to file
+// dst, which is created (or atomically replaced) and contains only the
+// copied bytes. On Linux the copy is done in the kernel using
+// copy_file_range(2) where possible (which on some filesystems shares
+// rather than copies the data). If src has fewer than offset+length bytes
+// nothing is written and [io.ErrUnexpectedEOF] is returned.
+// See also [ReadRange].
+func CopyRange(src string, offset, length int64, dst string) error {
+	if offset < 0 || length < 0 {
+		return fmt.Errorf("invalid byte range offset %d length %d", offset,
+			length)
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if _, err = in.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(dst),
+		"."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempname := temp.Name()
+	// *os.File.ReadFrom uses copy_file_range for an io.LimitedReader
+	n, err := io.Copy(temp, io.LimitReader(in, length))
+	if err == nil && n < length {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil {
+		err = temp.Chmod(modeDefault)
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = rename(tempname, dst)
+	}
+	if err != nil {
+		os.Remove(tempname)
+	}
+	return err
+}
+
+// ReadRange returns length bytes starting at offset from the given file.
+// If the file has fewer than offset+length bytes the bytes that could be
+// read are returned with [io.ErrUnexpectedEOF]. See also [CopyRange] and
+// [ReadChunks].
+func ReadRange(path string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid byte range offset %d length %d",
+			offset, length)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := length
+	if info.Mode().IsRegular() { // don't allocate more than can be read
+		size = max(0, min(length, info.Size()-offset))
+	}
+	data := make([]byte, size)
+	n, err := file.ReadAt(data, offset)
+	if err == nil && size < length {
+		err = io.EOF
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return data[:n], err
+}
@@ -0,0 +1,60 @@
+package ufile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_CopyRange(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"src.bin": "0123456789"})
+	src := filepath.Join(dir, "src.bin")
+	dst := filepath.Join(dir, "dst.bin")
+	if err := CopyRange(src, 3, 4, dst); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "3456" {
+		t.Errorf("expected %q got %q %v", "3456", data, err)
+	}
+	if err := CopyRange(src, 8, 4, dst); !errors.Is(err,
+		io.ErrUnexpectedEOF) {
+		t.Errorf("expected %v got %v", io.ErrUnexpectedEOF, err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "3456" {
+		t.Errorf("expected %q unchanged got %q", "3456", data)
+	}
+	if err := CopyRange(src, -1, 4, dst); err == nil {
+		t.Error("expected invalid range error")
+	}
+}
+
+func Test_ReadRange(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"src.bin": "0123456789"})
+	src := filepath.Join(dir, "src.bin")
+	if data, err := ReadRange(src, 0, 3); err != nil ||
+		string(data) != "012" {
+		t.Errorf("expected %q got %q %v", "012", data, err)
+	}
+	data, err := ReadRange(src, 8, 4)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || string(data) != "89" {
+		t.Errorf("expected %q %v got %q %v", "89", io.ErrUnexpectedEOF,
+			data, err)
+	}
+	if data, err = ReadRange(src, 10, 0); err != nil || len(data) != 0 {
+		t.Errorf("expected no data got %q %v", data, err)
+	}
+	data, err = ReadRange(src, 5, 1<<62) // mustn't try to allocate it all
+	if !errors.Is(err, io.ErrUnexpectedEOF) || string(data) != "56789" {
+		t.Errorf("expected %q %v got %q %v", "56789",
+			io.ErrUnexpectedEOF, data, err)
+	}
+	data, err = ReadRange(src, 20, 1)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || len(data) != 0 {
+		t.Errorf("expected no data %v got %q %v", io.ErrUnexpectedEOF,
+			data, err)
+	}
+}
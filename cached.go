@@ -0,0 +1,79 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"sync"
+	"time"
+)
+
+// CachedFile holds a file's contents and only rereads the file when its
+// size or modification time has changed. This is useful for config or
+// dictionary files that are polled often but rarely change. It is safe
+// for concurrent use. Create with [NewCachedFile] or [NewCachedFileOpt].
+//
+// Note that a change that keeps the same size and happens within the
+// filesystem's timestamp granularity of the previous change can't be
+// detected.
+type CachedFile struct {
+	mutex   sync.Mutex
+	path    string
+	byHash  bool
+	valid   bool
+	size    int64
+	modTime time.Time
+	sum     [sha256.Size]byte
+	data    []byte
+}
+
+// NewCachedFile returns a CachedFile for the file at path; nothing is
+// read until [CachedFile.Read] is called.
+func NewCachedFile(path string) *CachedFile {
+	return NewCachedFileOpt(path, false)
+}
+
+// NewCachedFileOpt works like [NewCachedFile] and if byHash is true
+// a file whose size or modification time has changed but whose contents
+// (compared by SHA-256) haven't is reported as unchanged, e.g., after
+// being touched or rewritten with the same contents.
+func NewCachedFileOpt(path string, byHash bool) *CachedFile {
+	return &CachedFile{path: path, byHash: byHash}
+}
+
+// Path returns the cached file's path.
+func (me *CachedFile) Path() string { return me.path }
+
+// Read returns the file's contents and true if they've changed since the
+// last Read (or this is the first Read); otherwise returns the cached
+// contents and false without rereading the file. The returned data must
+// not be modified.
+func (me *CachedFile) Read() ([]byte, bool, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	info, err := os.Stat(me.path)
+	if err != nil {
+		return nil, false, err
+	}
+	if me.valid && info.Size() == me.size &&
+		info.ModTime().Equal(me.modTime) {
+		return me.data, false, nil
+	}
+	data, err := os.ReadFile(me.path)
+	if err != nil {
+		return nil, false, err
+	}
+	changed := true
+	if me.byHash {
+		sum := sha256.Sum256(data)
+		changed = !me.valid || !bytes.Equal(sum[:], me.sum[:])
+		me.sum = sum
+	}
+	me.valid = true
+	me.size = info.Size()
+	me.modTime = info.ModTime()
+	me.data = data
+	return data, changed, nil
+}
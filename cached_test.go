@@ -0,0 +1,46 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_CachedFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.ini")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(filename, []byte(content),
+			ModeURW); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filename, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("a=1", start)
+	for _, byHash := range []bool{false, true} {
+		cached := NewCachedFileOpt(filename, byHash)
+		check := func(expected string, expectChanged bool) {
+			t.Helper()
+			data, changed, err := cached.Read()
+			if err != nil || string(data) != expected ||
+				changed != expectChanged {
+				t.Errorf("byHash=%t: expected %q %t got %q %t %v", byHash,
+					expected, expectChanged, data, changed, err)
+			}
+		}
+		check("a=1", true)
+		check("a=1", false)
+		write("a=1", start.Add(time.Minute)) // touched
+		check("a=1", !byHash)
+		write("a=2", start.Add(2*time.Minute))
+		check("a=2", true)
+		check("a=2", false)
+		write("a=1", start) // reset for the next pass
+	}
+	if _, _, err := NewCachedFile(filename + ".x").Read(); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,9 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build !windows
+
+package ufile
+
+// longPathName returns path unchanged since only Windows has 8.3 short
+// names.
+func longPathName(path string) string { return path }
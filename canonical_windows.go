@@ -0,0 +1,28 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// longPathName returns the existing path with any 8.3 short names (e.g.,
+// `PROGRA~1`) expanded to their long forms, or path unchanged on error.
+func longPathName(path string) string {
+	from, err := windows.UTF16PtrFromString(LongPath(path))
+	if err != nil {
+		return path
+	}
+	buffer := make([]uint16, windows.MAX_LONG_PATH)
+	n, err := windows.GetLongPathName(from, &buffer[0], uint32(len(buffer)))
+	if err != nil || n == 0 || int(n) > len(buffer) {
+		return path
+	}
+	long := windows.UTF16ToString(buffer[:n])
+	if rest, ok := strings.CutPrefix(long, `\\?\UNC\`); ok {
+		return `\\` + rest
+	}
+	return strings.TrimPrefix(long, `\\?\`)
+}
@@ -0,0 +1,166 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"iter"
+	"math/bits"
+	"os"
+)
+
+// CDCOptions are used by [ChunkFileCDC].
+//
+// AvgSize is the target average chunk size (default 8KiB; rounded down to
+// a power of two). MinSize (default AvgSize/4) and MaxSize (default
+// AvgSize*8) bound the chunk sizes (except that the last chunk may be
+// smaller than MinSize). Algo is used for the chunks' hashes.
+type CDCOptions struct {
+	MinSize int
+	AvgSize int
+	MaxSize int
+	Algo    HashAlgo
+}
+
+// Chunk is a content-defined chunk of a file as returned by
+// [ChunkFileCDC]. Hash is the chunk's checksum (as lowercase hex). Data's
+// underlying buffer is reused so it must be copied if it is to be kept
+// beyond the current iteration.
+type Chunk struct {
+	Offset int64
+	Size   int
+	Hash   string
+	Data   []byte
+}
+
+// cdcGear is the table of random values used by the gear hash; it is
+// generated from a fixed seed so that chunk boundaries never change.
+var cdcGear = func() [256]uint64 {
+	var gear [256]uint64
+	state := uint64(0x5DEECE66D)
+	for i := range gear {
+		state += 0x9E3779B97F4A7C15 // splitmix64
+		value := state
+		value = (value ^ (value >> 30)) * 0xBF58476D1CE4E5B9
+		value = (value ^ (value >> 27)) * 0x94D049BB133111EB
+		gear[i] = value ^ (value >> 31)
+	}
+	return gear
+}()
+
+// ChunkFileCDC reads the given file and returns an iterator of (chunk,
+// error) for every content-defined chunk of the file. Chunk boundaries
+// are found using a rolling (FastCDC-style gear) hash of the content, so
+// inserting or deleting bytes only changes the chunks near the edit,
+// which makes the chunks suitable for deduplication and delta syncing.
+// See also [CDCOptions] and [ReadChunks].
+func ChunkFileCDC(path string, opts CDCOptions) iter.Seq2[Chunk, error] {
+	return func(yield func(Chunk, error) bool) {
+		if err := opts.normalize(); err != nil {
+			yield(Chunk{}, err)
+			return
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			yield(Chunk{}, err) // failed to open file
+			return              // we cannot progress from here
+		}
+		defer file.Close()
+		chunkCDC(file, opts, yield)
+	}
+}
+
+// chunkCDC yields the content-defined chunks read from reader.
+func chunkCDC(reader io.Reader, opts CDCOptions,
+	yield func(Chunk, error) bool,
+) {
+	shift := bits.Len(uint(opts.AvgSize)) - 1
+	maskS := cdcMask(shift + 1) // harder to match before AvgSize
+	maskL := cdcMask(shift - 1) // easier to match after AvgSize
+	buffer := make([]byte, 2*opts.MaxSize)
+	start, end := 0, 0
+	var offset int64
+	eof := false
+	for {
+		if !eof && end-start < opts.MaxSize {
+			copy(buffer, buffer[start:end])
+			end -= start
+			start = 0
+			n, err := io.ReadFull(reader, buffer[end:])
+			end += n
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				eof = true
+			} else if err != nil {
+				yield(Chunk{}, err) // read error
+				return
+			}
+		}
+		if start == end {
+			return
+		}
+		data := buffer[start:end]
+		size := cdcCut(data, opts, maskS, maskL)
+		hash := opts.Algo.New()
+		hash.Write(data[:size])
+		chunk := Chunk{Offset: offset, Size: size,
+			Hash: hex.EncodeToString(hash.Sum(nil)), Data: data[:size]}
+		if !yield(chunk, nil) {
+			return
+		}
+		start += size
+		offset += int64(size)
+	}
+}
+
+// cdcCut returns the length of the chunk at the start of data.
+func cdcCut(data []byte, opts CDCOptions, maskS, maskL uint64) int {
+	size := len(data)
+	if size <= opts.MinSize {
+		return size
+	}
+	size = min(size, opts.MaxSize)
+	normal := min(size, opts.AvgSize)
+	var hash uint64
+	for i := opts.MinSize; i < normal; i++ {
+		hash = hash<<1 + cdcGear[data[i]]
+		if hash&maskS == 0 {
+			return i + 1
+		}
+	}
+	for i := normal; i < size; i++ {
+		hash = hash<<1 + cdcGear[data[i]]
+		if hash&maskL == 0 {
+			return i + 1
+		}
+	}
+	return size
+}
+
+// cdcMask returns a mask with its top n bits set (the gear hash's high
+// bits depend on the most bytes).
+func cdcMask(n int) uint64 {
+	return ^uint64(0) << (64 - n)
+}
+
+// normalize sets the defaults for any unset sizes and rounds AvgSize down
+// to a power of two, or returns an error if the sizes are invalid.
+func (me *CDCOptions) normalize() error {
+	if me.AvgSize <= 0 {
+		me.AvgSize = 8192
+	}
+	me.AvgSize = 1 << (bits.Len(uint(me.AvgSize)) - 1)
+	if me.MinSize <= 0 {
+		me.MinSize = me.AvgSize / 4
+	}
+	if me.MaxSize <= 0 {
+		me.MaxSize = me.AvgSize * 8
+	}
+	if me.AvgSize < 64 || me.MinSize > me.AvgSize ||
+		me.AvgSize > me.MaxSize {
+		return fmt.Errorf("invalid chunk sizes %d, %d, %d", me.MinSize,
+			me.AvgSize, me.MaxSize)
+	}
+	return nil
+}
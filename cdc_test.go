@@ -0,0 +1,70 @@
+package ufile
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ChunkFileCDC(t *testing.T) {
+	random := rand.New(rand.NewPCG(3, 4))
+	data := make([]byte, 1_000_000)
+	for i := range data {
+		data[i] = byte(random.IntN(256))
+	}
+	dir := t.TempDir()
+	oldname := filepath.Join(dir, "old.bin")
+	newname := filepath.Join(dir, "new.bin")
+	if err := os.WriteFile(oldname, data, ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	edited := append(append(bytes.Clone(data[:500_000]),
+		[]byte("inserted")...), data[500_000:]...)
+	if err := os.WriteFile(newname, edited, ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	opts := CDCOptions{AvgSize: 4096}
+	chunks := func(filename string, original []byte) map[string]bool {
+		hashes := make(map[string]bool)
+		var joined []byte
+		var offset int64
+		for chunk, err := range ChunkFileCDC(filename, opts) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if chunk.Offset != offset || chunk.Size != len(chunk.Data) ||
+				(chunk.Size < 1024 && chunk.Offset+int64(chunk.Size) !=
+					int64(len(original))) || chunk.Size > 32768 {
+				t.Errorf("unexpected chunk at %d of size %d", chunk.Offset,
+					chunk.Size)
+			}
+			offset += int64(chunk.Size)
+			joined = append(joined, chunk.Data...)
+			hashes[chunk.Hash] = true
+		}
+		if !bytes.Equal(joined, original) {
+			t.Error("expected chunks to make up the file")
+		}
+		return hashes
+	}
+	oldHashes := chunks(oldname, data)
+	newHashes := chunks(newname, edited)
+	changed := 0
+	for hash := range newHashes {
+		if !oldHashes[hash] {
+			changed++
+		}
+	}
+	if len(oldHashes) < 100 || changed > 3 {
+		t.Errorf("expected many chunks with few changed got %d, %d",
+			len(oldHashes), changed)
+	}
+	for _, err := range ChunkFileCDC(oldname, CDCOptions{MinSize: 9000,
+		AvgSize: 4096}) {
+		if err == nil {
+			t.Error("expected error for invalid sizes")
+		}
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// HashAlgo identifies a checksum algorithm.
+type HashAlgo uint8
+
+const (
+	SHA256 HashAlgo = iota
+	SHA512
+	SHA1
+	MD5
+)
+
+// String returns the HashAlgo's name, e.g., "SHA256".
+func (me HashAlgo) String() string {
+	switch me {
+	case SHA512:
+		return "SHA512"
+	case SHA1:
+		return "SHA1"
+	case MD5:
+		return "MD5"
+	default:
+		return "SHA256"
+	}
+}
+
+// New returns a new hash.Hash for the algorithm.
+func (me HashAlgo) New() hash.Hash {
+	switch me {
+	case SHA512:
+		return sha512.New()
+	case SHA1:
+		return sha1.New()
+	case MD5:
+		return md5.New()
+	default:
+		return sha256.New()
+	}
+}
+
+// hashAlgoForHexLength returns the algorithm whose hex checksums have the
+// given length.
+func hashAlgoForHexLength(size int) (HashAlgo, bool) {
+	switch size {
+	case 64:
+		return SHA256, true
+	case 128:
+		return SHA512, true
+	case 40:
+		return SHA1, true
+	case 32:
+		return MD5, true
+	}
+	return SHA256, false
+}
+
+// Checksum returns the given file's checksum as lowercase hex.
+// See also [ChecksumContext].
+func Checksum(filename string, algo HashAlgo) (string, error) {
+	return checksum(filename, algo, nil)
+}
+
+// ChecksumContext works like [Checksum] but stops with the context's
+// error if ctx is cancelled, and if progress is not nil calls it as the
+// file is read with the total being the file's size.
+func ChecksumContext(ctx context.Context, filename string, algo HashAlgo,
+	progress ProgressFunc,
+) (string, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return "", err
+	}
+	return checksum(filename, algo, newTracker(ctx, progress, info.Size()))
+}
+
+func checksum(filename string, algo HashAlgo, track *tracker) (string,
+	error,
+) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := algo.New()
+	if _, err = io.Copy(hash, track.reader(file, filename)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CopyAndHash copies src to dst and returns the number of bytes written
+// and the checksum (as lowercase hex) of the data copied, so the copy can
+// be verified without reading it back. See also [CopyFileAndHash].
+func CopyAndHash(dst io.Writer, src io.Reader, algo HashAlgo) (
+	written int64, sum string, err error,
+) {
+	hash := algo.New()
+	if written, err = io.Copy(io.MultiWriter(dst, hash), src); err != nil {
+		return written, "", err
+	}
+	return written, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CopyFileAndHash atomically copies regular file src to dst (replacing dst
+// if it exists) preserving src's permissions and modification time, and
+// returns the checksum (as lowercase hex) of the data copied. Unlike
+// [CopyFile] the data is always copied (never cloned) since it must be
+// read. See also [CopyAndHash] and [Checksum].
+func CopyFileAndHash(src, dst string, algo HashAlgo) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", &fs.PathError{Op: "copy", Path: src, Err: fs.ErrInvalid}
+	}
+	out, err := os.CreateTemp(filepath.Dir(dst),
+		"."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	tempname := out.Name()
+	_, sum, err := CopyAndHash(out, bufio.NewReader(in), algo)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempname)
+		return "", err
+	}
+	if err = finishCopy(tempname, dst, info); err != nil {
+		return "", err
+	}
+	return sum, nil
+}
+
+// WriteChecksumManifest writes a manifest of the checksums of every
+// regular file in the tree rooted at root (excluding the manifest itself)
+// to manifestPath in the coreutils format (e.g., as used by `sha256sum
+// -c`). Names are slash-separated and relative to the manifest's folder.
+// Files are hashed in parallel using GOMAXPROCS goroutines. See also
+// [WriteChecksumManifestOpt] and [VerifyChecksumManifest].
+func WriteChecksumManifest(root, manifestPath string, algo HashAlgo) error {
+	return WriteChecksumManifestOpt(root, manifestPath, algo, 0)
+}
+
+// WriteChecksumManifestOpt works like [WriteChecksumManifest] but uses up
+// to workers goroutines (or GOMAXPROCS if workers < 1).
+func WriteChecksumManifestOpt(root, manifestPath string, algo HashAlgo,
+	workers int,
+) error {
+	manifestDir := filepath.Dir(AbsPath(manifestPath))
+	absManifest := AbsPath(manifestPath)
+	var mutex sync.Mutex
+	var lines []string
+	err := WalkParallel(root, workers, func(entry Entry) error {
+		if !entry.Mode().IsRegular() || AbsPath(entry.Path) == absManifest {
+			return nil
+		}
+		sum, err := Checksum(entry.Path, algo)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(manifestDir, AbsPath(entry.Path))
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		lines = append(lines, checksumLine(sum, name))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(lines, func(a, b string) int { // walk order
+		_, a, _ = parseChecksumLine(a)
+		_, b, _ = parseChecksumLine(b)
+		return slices.Compare(strings.Split(a, "/"), strings.Split(b, "/"))
+	})
+	return writeChecksumLines(manifestPath, lines)
+}
+
+// VerifyResult is the outcome of verifying one file listed in a checksum
+// manifest by [VerifyChecksumManifest].
+type VerifyResult struct {
+	Name string // as given in the manifest
+	Path string // the name resolved relative to the manifest's folder
+	OK   bool
+}
+
+// VerifyChecksumManifest reads the given coreutils-format checksum
+// manifest (SHA-256, SHA-512, SHA-1, or MD5, deduced from the checksums'
+// length) and returns an iterator of (result, error) for every file it
+// lists, in manifest order. A file that can't be read is yielded with a
+// (not OK) result and the error; a malformed line is yielded with an
+// empty result and an error. Files are hashed in parallel using
+// GOMAXPROCS goroutines. See also [VerifyChecksumManifestOpt] and
+// [WriteChecksumManifest].
+func VerifyChecksumManifest(manifestPath string,
+) iter.Seq2[VerifyResult, error] {
+	return VerifyChecksumManifestOpt(manifestPath, 0)
+}
+
+// VerifyChecksumManifestOpt works like [VerifyChecksumManifest] but uses
+// up to workers goroutines (or GOMAXPROCS if workers < 1).
+func VerifyChecksumManifestOpt(manifestPath string, workers int,
+) iter.Seq2[VerifyResult, error] {
+	type item struct {
+		result VerifyResult
+		sum    string
+		algo   HashAlgo
+		err    error
+	}
+	return func(yield func(VerifyResult, error) bool) {
+		dir := filepath.Dir(manifestPath)
+		var items []item
+		lino := 0
+		for line, err := range ReadUtf8Lines(manifestPath) {
+			if err != nil {
+				items = append(items, item{err: err})
+				break
+			}
+			lino++
+			if strings.TrimSpace(line) == "" ||
+				strings.HasPrefix(line, "#") {
+				continue
+			}
+			sum, name, ok := parseChecksumLine(line)
+			algo, known := hashAlgoForHexLength(len(sum))
+			if !ok || !known {
+				items = append(items, item{err: fmt.Errorf(
+					"%s:%d: invalid checksum line", manifestPath, lino)})
+				continue
+			}
+			items = append(items, item{result: VerifyResult{Name: name,
+				Path: filepath.Join(dir, filepath.FromSlash(name))},
+				sum: sum, algo: algo})
+		}
+		parallelOrdered(len(items), workers, func(i int) item {
+			item := items[i]
+			if item.err == nil {
+				actual, err := Checksum(item.result.Path, item.algo)
+				if err == nil {
+					item.result.OK = strings.EqualFold(actual, item.sum)
+				}
+				item.err = err
+			}
+			return item
+		}, func(item item) bool {
+			return yield(item.result, item.err)
+		})
+	}
+}
+
+// checksumLine returns a line in coreutils checksum (text mode) format.
+func checksumLine(sum, name string) string {
+	return sum + "  " + filepath.ToSlash(name)
+}
+
+func writeChecksumLines(filename string, lines []string) error {
+	return writeAtomic(filename, func(out *bufio.Writer) error {
+		for _, line := range lines {
+			if _, err := out.WriteString(line + "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// readChecksumMap reads a coreutils-format checksum file and returns a map
+// of names to hex checksums.
+func readChecksumMap(filename string) (map[string]string, error) {
+	sums := map[string]string{}
+	for line, err := range ReadUtf8Lines(filename) {
+		if err != nil {
+			return nil, err
+		}
+		sum, name, ok := parseChecksumLine(line)
+		if ok {
+			sums[name] = sum
+		}
+	}
+	return sums, nil
+}
+
+// parseChecksumLine parses a coreutils-format checksum line, i.e., hex
+// checksum, space, then space (text mode) or * (binary mode), then name.
+func parseChecksumLine(line string) (sum, name string, ok bool) {
+	sum, rest, ok := strings.Cut(line, " ")
+	if !ok || sum == "" || len(rest) < 2 || (rest[0] != ' ' &&
+		rest[0] != '*') {
+		return "", "", false
+	}
+	return sum, rest[1:], true
+}
@@ -0,0 +1,116 @@
+package ufile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Checksum(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "abc.txt")
+	if err := os.WriteFile(filename, []byte("abc"), ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	sums := map[HashAlgo]string{
+		SHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61" +
+			"f20015ad",
+		MD5: "900150983cd24fb0d6963f7d28e17f72",
+	}
+	for algo, expected := range sums {
+		if sum, err := Checksum(filename, algo); err != nil ||
+			sum != expected {
+			t.Errorf("%s: expected %s got %s %v", algo, expected, sum, err)
+		}
+	}
+}
+
+func Test_CopyAndHash(t *testing.T) {
+	var out bytes.Buffer
+	n, sum, err := CopyAndHash(&out, strings.NewReader("abc"), MD5)
+	if err != nil || n != 3 || out.String() != "abc" ||
+		sum != "900150983cd24fb0d6963f7d28e17f72" {
+		t.Errorf("expected 3 abc MD5 got %d %q %q %v", n, out.String(),
+			sum, err)
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	dst := filepath.Join(dir, "dst.txt")
+	if err = os.WriteFile(src, []byte("abc"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if sum, err = CopyFileAndHash(src, dst, SHA256); err != nil {
+		t.Fatal(err)
+	}
+	if expected, _ := Checksum(dst, SHA256); sum != expected {
+		t.Errorf("expected %q got %q", expected, sum)
+	}
+	if tree, _ := TreeString(dir); tree != ".\n├── dst.txt\n└── src.txt\n" {
+		t.Errorf("expected no temporary files got\n%s", tree)
+	}
+	if _, err = CopyFileAndHash(dir, dst, SHA256); err == nil {
+		t.Error("expected error copying a folder")
+	}
+}
+
+func Test_ChecksumManifest(t *testing.T) {
+	root := t.TempDir()
+	makeTestTree(t, root, map[string]string{"a.txt": "alpha",
+		"sub/b.txt": "beta", "sub/c.txt": "gamma"})
+	manifest := filepath.Join(root, "SHA256SUMS")
+	if err := WriteChecksumManifest(root, manifest, SHA256); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := ReadTextFile(manifest)
+	if err != nil || len(lines) != 3 ||
+		lines[0] != "8ed3f6ad685b959ead7022518e1af76cd816f8e8ec7ccdda1ed4"+
+			"018e8f2223f8  a.txt" {
+		t.Errorf("unexpected manifest %q %v", lines, err)
+	}
+	if err = os.WriteFile(filepath.Join(root, "sub", "b.txt"),
+		[]byte("BETA"), ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Remove(filepath.Join(root, "sub", "c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	results := map[string]bool{}
+	errors := 0
+	for result, err := range VerifyChecksumManifest(manifest) {
+		if err != nil {
+			errors++
+		}
+		results[result.Name] = result.OK
+	}
+	if errors != 1 || !results["a.txt"] || results["sub/b.txt"] ||
+		results["sub/c.txt"] {
+		t.Errorf("unexpected results %v with %d errors", results, errors)
+	}
+}
+
+func Test_ChecksumManifestOpt(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{"sub-a.txt": "1", "sub/b.txt": "2",
+		"sub/z/c.txt": "3", "x.txt": "4", "a.txt": "5"}
+	makeTestTree(t, root, files)
+	manifest := filepath.Join(root, "SHA256SUMS")
+	for _, workers := range []int{1, 4} {
+		err := WriteChecksumManifestOpt(root, manifest, SHA256, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for result, err := range VerifyChecksumManifestOpt(manifest,
+			workers) {
+			if err != nil || !result.OK {
+				t.Errorf("unexpected result %v %v", result, err)
+			}
+			names = append(names, result.Name)
+		}
+		expected := "a.txt sub/b.txt sub/z/c.txt sub-a.txt x.txt"
+		if got := strings.Join(names, " "); got != expected {
+			t.Errorf("expected %q got %q", expected, got)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile replaces the existing empty file tempname with an APFS clone
+// of src using clonefile(2).
+func cloneFile(src *os.File, tempname string) error {
+	if err := os.Remove(tempname); err != nil {
+		return err
+	}
+	if err := unix.Clonefile(src.Name(), tempname,
+		unix.CLONE_NOFOLLOW); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile makes the existing empty file tempname a reflink clone of src
+// using the FICLONE ioctl (supported by Btrfs, XFS, bcachefs, etc.).
+func cloneFile(src *os.File, tempname string) error {
+	out, err := os.OpenFile(tempname, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err = unix.IoctlFileClone(int(out.Fd()), int(src.Fd())); err != nil {
+		return err
+	}
+	return out.Close()
+}
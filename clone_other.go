@@ -0,0 +1,15 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build !linux && !darwin
+
+package ufile
+
+import (
+	"errors"
+	"os"
+)
+
+// cloneFile always fails since cloning isn't supported on this platform.
+func cloneFile(src *os.File, tempname string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,161 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// ConfigCodec is the file suffix and encoding used by [LoadConfigOpt] and
+// [SaveConfigOpt]. Use [JSONCodec] or provide your own, e.g., for TOML or
+// YAML using a third-party package.
+type ConfigCodec struct {
+	Ext       string // e.g., ".json"
+	Marshal   func(v any) ([]byte, error)
+	Unmarshal func(data []byte, v any) error
+}
+
+// JSONCodec is the [ConfigCodec] used by [LoadConfig] and [SaveConfig];
+// it writes indented JSON.
+var JSONCodec = ConfigCodec{
+	Ext: ".json",
+	Marshal: func(v any) ([]byte, error) {
+		return json.MarshalIndent(v, "", "\t")
+	},
+	Unmarshal: json.Unmarshal,
+}
+
+// LoadConfig returns the config for the given domain and application
+// name (see [GetConfigFile]) read from a JSON file, and the config
+// filename. If there is no config file the zero T is returned with the
+// filename where it should be saved and a nil error. See also
+// [SaveConfig] and [LoadConfigOpt].
+func LoadConfig[T any](domain, appname string) (T, string, error) {
+	return LoadConfigOpt[T](domain, appname, JSONCodec)
+}
+
+// LoadConfigOpt works like [LoadConfig] but using the given codec.
+func LoadConfigOpt[T any](domain, appname string, codec ConfigCodec) (T,
+	string, error,
+) {
+	var cfg T
+	filename, _ := GetConfigFile(domain, appname, codec.Ext)
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return cfg, filename, err
+	}
+	err = codec.Unmarshal(raw, &cfg)
+	return cfg, filename, err
+}
+
+// SaveConfig saves the given config for the given domain and application
+// name (see [GetConfigFile]) as a JSON file, creating its folder if
+// necessary. The file is written atomically so that a crash can't leave a
+// partial config file. See also [LoadConfig] and [SaveConfigOpt].
+func SaveConfig[T any](domain, appname string, cfg T) error {
+	return SaveConfigOpt(domain, appname, cfg, JSONCodec)
+}
+
+// SaveConfigOpt works like [SaveConfig] but using the given codec.
+func SaveConfigOpt[T any](domain, appname string, cfg T,
+	codec ConfigCodec,
+) error {
+	raw, err := codec.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	filename, _ := GetConfigFile(domain, appname, codec.Ext)
+	if err = EnsureParentDir(filename); err != nil {
+		return err
+	}
+	return writeAtomic(filename, func(out *bufio.Writer) error {
+		if _, err := out.Write(raw); err != nil {
+			return err
+		}
+		return out.WriteByte('\n')
+	})
+}
+
+// GetSystemConfigFile given a domain name, say, "domain.com", an
+// application name, say, "myapp", and a suffix, say, ".json", returns
+// where the corresponding system-wide config file is located and true, or
+// where it would be expected (i.e., if it doesn't exist) and false. The
+// folders searched are /etc and /usr/local/etc on Unix, and also
+// /Library/Application Support on macOS, and %ProgramData% on Windows;
+// in each the file is looked for in the domain subfolder first. See also
+// [GetConfigFile] and [MergedConfigPaths].
+func GetSystemConfigFile(domain, appname, ext string) (string, bool) {
+	return systemConfigFile(systemConfigDirs(), domain, appname, ext)
+}
+
+func systemConfigFile(dirs []string, domain, appname, ext string) (string,
+	bool,
+) {
+	filename := configFilename(appname, ext)
+	var filenames []string
+	for _, dir := range dirs {
+		if domain != "" {
+			filenames = append(filenames, filepath.Join(dir, domain,
+				filename))
+		}
+		filenames = append(filenames, filepath.Join(dir, filename))
+	}
+	for _, name := range filenames {
+		if FileExists(name) {
+			return name, true // found
+		}
+	}
+	if len(filenames) == 0 {
+		return filename, false
+	}
+	return filenames[0], false
+}
+
+// systemConfigDirs returns the platform's system-wide config folders in
+// search order.
+func systemConfigDirs() []string {
+	switch runtime.GOOS {
+	case "windows":
+		dir := os.Getenv("ProgramData")
+		if dir == "" {
+			dir = `C:\ProgramData`
+		}
+		return []string{dir}
+	case "darwin":
+		return []string{"/Library/Application Support", "/etc",
+			"/usr/local/etc"}
+	}
+	return []string{"/etc", "/usr/local/etc"}
+}
+
+// MergedConfigPaths returns the existing system-wide and user config files
+// for the given domain, application name, and suffix in that order (see
+// [GetSystemConfigFile] and [GetConfigFile]). This is for layered
+// loading, where each file is loaded in turn with later values
+// overriding earlier ones. The result is empty if neither exists.
+func MergedConfigPaths(domain, appname, ext string) []string {
+	return mergedConfigPaths(systemConfigDirs(), domain, appname, ext)
+}
+
+func mergedConfigPaths(dirs []string, domain, appname, ext string,
+) []string {
+	var paths []string
+	if name, found := systemConfigFile(dirs, domain, appname,
+		ext); found {
+		paths = append(paths, name)
+	}
+	if name, found := GetConfigFile(domain, appname, ext); found &&
+		(len(paths) == 0 || AbsPath(name) != AbsPath(paths[0])) {
+		paths = append(paths, name)
+	}
+	return paths
+}
@@ -0,0 +1,67 @@
+package ufile
+
+import (
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	Width  int
+	Recent []string
+}
+
+func Test_LoadSaveConfig(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("relies on XDG_CONFIG_HOME")
+	}
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	cfg, filename, err := LoadConfig[testConfig]("qtrac.eu", "my app")
+	expected := filepath.Join(dir, "qtrac.eu", "my_app.json")
+	if err != nil || cfg.Width != 0 || filename != expected {
+		t.Errorf("expected %q got %q %v %v", expected, filename, cfg, err)
+	}
+	cfg = testConfig{Width: 80, Recent: []string{"a.txt", "b.txt"}}
+	if err = SaveConfig("qtrac.eu", "my app", cfg); err != nil {
+		t.Fatal(err)
+	}
+	loaded, filename, err := LoadConfig[testConfig]("qtrac.eu", "my app")
+	if err != nil || filename != expected || loaded.Width != 80 ||
+		!slices.Equal(loaded.Recent, cfg.Recent) {
+		t.Errorf("expected %v got %v %v", cfg, loaded, err)
+	}
+	lines, _ := ReadTextFile(expected)
+	if !strings.HasPrefix(lines[1], "\t\"Width\"") {
+		t.Errorf("expected indented JSON got %q", lines)
+	}
+}
+
+func Test_MergedConfigPaths(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("relies on XDG_CONFIG_HOME")
+	}
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "user"))
+	dirs := []string{filepath.Join(dir, "etc"),
+		filepath.Join(dir, "local")}
+	name, found := systemConfigFile(dirs, "qtrac.eu", "app", "ini")
+	expected := filepath.Join(dir, "etc", "qtrac.eu", "app.ini")
+	if found || name != expected {
+		t.Errorf("expected %q got %q %t", expected, name, found)
+	}
+	if paths := mergedConfigPaths(dirs, "qtrac.eu", "app",
+		"ini"); len(paths) != 0 {
+		t.Errorf("expected no paths got %q", paths)
+	}
+	makeTestTree(t, dir, map[string]string{"local/app.ini": "[a]",
+		"user/qtrac.eu/app.ini": "[b]"})
+	paths := mergedConfigPaths(dirs, "qtrac.eu", "app", ".ini")
+	expectedPaths := []string{filepath.Join(dir, "local", "app.ini"),
+		filepath.Join(dir, "user", "qtrac.eu", "app.ini")}
+	if !slices.Equal(paths, expectedPaths) {
+		t.Errorf("expected %q got %q", expectedPaths, paths)
+	}
+}
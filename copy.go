@@ -0,0 +1,395 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// CloneMode determines whether [CopyFileOpt] uses a reflink clone (which
+// shares the source's data blocks until either file is changed) rather
+// than copying the data.
+type CloneMode uint8
+
+const (
+	CloneAuto    CloneMode = iota // clone if possible, otherwise copy
+	CloneRequire                  // clone or fail
+	CloneNever                    // always copy
+)
+
+// CopyOptions are used by [CopyFileOpt].
+type CopyOptions struct {
+	Clone CloneMode
+}
+
+// ErrCloneUnsupported is returned (wrapped) by [CopyFileOpt] when
+// CloneRequire is used and the file can't be cloned, e.g., because the
+// platform or filesystem doesn't support it, or src and dst are on
+// different filesystems.
+var ErrCloneUnsupported = errors.New("cloning not supported")
+
+// CopyFile atomically copies regular file src to dst (replacing dst if it
+// exists) preserving src's permissions and modification time. On Linux
+// (Btrfs, XFS, etc.) and macOS (APFS) the copy is an instant reflink clone
+// when possible; otherwise sparse files are copied without filling in
+// their holes. See also [CopyFileOpt] and [CopyFileFS].
+func CopyFile(src, dst string) error {
+	return CopyFileOpt(src, dst, CopyOptions{})
+}
+
+// CopyFileOpt works like [CopyFile] but opts.Clone can be used to require
+// or forbid cloning.
+func CopyFileOpt(src, dst string, opts CopyOptions) error {
+	_, err := copyFile(src, dst, opts.Clone, nil)
+	return err
+}
+
+// copyFile atomically copies regular file src to dst (replacing dst if it
+// exists) preserving src's permissions and modification time, and returns
+// the number of bytes copied. The copy is tracked by track if not nil.
+func copyFile(src, dst string, clone CloneMode, track *tracker) (int64,
+	error,
+) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if !info.Mode().IsRegular() {
+		return 0, &fs.PathError{Op: "copy", Path: src, Err: fs.ErrInvalid}
+	}
+	temp, err := os.CreateTemp(filepath.Dir(dst),
+		"."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	tempname := temp.Name()
+	temp.Close()
+	if clone != CloneNever {
+		err = cloneFile(in, tempname)
+		if err == nil {
+			if err = finishCopy(tempname, dst, info); err != nil {
+				return 0, err
+			}
+			return info.Size(), track.add(info.Size(), src)
+		}
+		if clone == CloneRequire {
+			os.Remove(tempname)
+			return 0, fmt.Errorf("%w: %w", ErrCloneUnsupported, err)
+		}
+	}
+	n, err := copyData(in, info, tempname, track)
+	if err == nil {
+		err = finishCopy(tempname, dst, info)
+	} else {
+		os.Remove(tempname)
+	}
+	return n, err
+}
+
+// copyData copies in (whose info is given) to file tempname, tracked by
+// track if not nil. Sparse files
+// are copied range by range so that holes aren't filled in; otherwise
+// io.Copy is used between the files, which on Linux uses
+// copy_file_range(2) (itself a server-side or reflink copy where the
+// filesystem supports it) if the copy isn't tracked.
+func copyData(in *os.File, info fs.FileInfo, tempname string,
+	track *tracker,
+) (int64, error) {
+	out, err := os.OpenFile(tempname, os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
+		ModeURW)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	var n int64
+	if allocatedSize(info) < info.Size() {
+		n, err = copySparse(in, out, info.Size(), track)
+	} else {
+		n, err = io.Copy(out, track.reader(in, in.Name()))
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	return n, err
+}
+
+// copySparse copies only in's data ranges to out, leaving holes between
+// them, and returns the number of bytes copied (i.e., excluding holes).
+func copySparse(in, out *os.File, size int64, track *tracker) (int64,
+	error,
+) {
+	var copied, offset int64
+	for offset < size {
+		start, end, err := nextDataRange(in, offset)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return copied, err
+		}
+		if err = track.add(start-offset, in.Name()); err != nil { // hole
+			return copied, err
+		}
+		if _, err = out.Seek(start, io.SeekStart); err != nil {
+			return copied, err
+		}
+		n, err := io.Copy(out, track.reader(io.NewSectionReader(in,
+			start, end-start), in.Name()))
+		copied += n
+		if err != nil {
+			return copied, err
+		}
+		offset = end
+	}
+	if err := track.add(size-offset, in.Name()); err != nil { // tail hole
+		return copied, err
+	}
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		return copied, err
+	}
+	return copied, out.Truncate(size)
+}
+
+// IsSparse returns true if the given file has fewer bytes allocated on
+// disk than its apparent size (i.e., it has holes); otherwise returns
+// false. Filesystem compression can also make a file appear sparse, and
+// on platforms other than Linux and macOS this always returns false.
+// See also [ApparentAndAllocatedSize].
+func IsSparse(path string) (bool, error) {
+	apparent, allocated, err := ApparentAndAllocatedSize(path)
+	return allocated < apparent, err
+}
+
+// ApparentAndAllocatedSize returns the given file's size (as reported by
+// [os.Stat]) and the number of bytes allocated for it on disk, which may
+// be less (for sparse files) or more (since whole blocks are allocated).
+// On platforms other than Linux and macOS the allocated size is the
+// apparent size.
+func ApparentAndAllocatedSize(path string) (int64, int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	return info.Size(), allocatedSize(info), nil
+}
+
+// finishCopy gives tempname info's permissions and modification time and
+// renames it to dst.
+func finishCopy(tempname, dst string, info fs.FileInfo) error {
+	err := os.Chmod(tempname, info.Mode().Perm())
+	if err == nil {
+		err = os.Chtimes(tempname, info.ModTime(), info.ModTime())
+	}
+	if err == nil {
+		err = rename(tempname, dst)
+	}
+	if err != nil {
+		os.Remove(tempname)
+	}
+	return err
+}
+
+// copyEntry copies src (a folder, regular file, or symlink, which isn't
+// followed) to dst, recursively for folders, preserving permissions and
+// modification times, and calls copied (if not nil) with each file's
+// relative path and size. Other kinds of file, and entries for which skip
+// (if not nil) returns true given their slash-separated relative paths,
+// are skipped. The copy is tracked by track if not nil.
+func copyEntry(src, dst string, track *tracker,
+	copied func(rel string, size int64),
+	skip func(rel string, isDir bool) bool,
+) error {
+	return filepath.WalkDir(src, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if err = track.err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if skip != nil && rel != "." && skip(filepath.ToSlash(rel),
+			dirEntry.IsDir()) {
+			if dirEntry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		mode := info.Mode()
+		switch {
+		case mode.IsDir():
+			if err = os.MkdirAll(target, mode.Perm()|0o700); err != nil {
+				return err
+			}
+		case mode&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			_ = os.Remove(target)
+			if err = os.Symlink(link, target); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			n, err := copyFile(path, target, CloneAuto, track)
+			if err != nil {
+				return err
+			}
+			if copied != nil {
+				copied(rel, n)
+			}
+		}
+		return nil
+	})
+}
+
+// OverwritePolicy determines what [Move] does if the destination exists.
+type OverwritePolicy uint8
+
+const (
+	OverwriteNever   OverwritePolicy = iota // fail with fs.ErrExist
+	OverwriteAlways                         // replace the destination
+	OverwriteIfNewer                        // replace it if src is newer
+)
+
+// MoveOptions are used by [Move].
+type MoveOptions struct {
+	Overwrite OverwritePolicy
+}
+
+// Move moves (renames) src (a file, folder, or symlink) to dst. If the
+// rename fails because src and dst are on different filesystems, src is
+// copied to dst (preserving permissions and modification times), the copy
+// is verified, and only then is src removed; if the copy fails the
+// partial dst is removed. Any other rename error is returned as is. If dst
+// exists and opts.Overwrite doesn't allow it to be replaced (or, for
+// OverwriteIfNewer, src isn't newer), an error wrapping [fs.ErrExist] is
+// returned and nothing is changed. A replaced dst folder is replaced
+// rather than merged: it is moved aside first and only removed once the
+// move has succeeded (and restored if it fails). Moving a folder inside
+// itself returns an error wrapping [fs.ErrInvalid].
+func Move(src, dst string, opts MoveOptions) error {
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if srcInfo.IsDir() && isWithin(AbsPath(dst), AbsPath(src), false) {
+		return &fs.PathError{Op: "move", Path: dst, Err: fs.ErrInvalid}
+	}
+	dstInfo, err := os.Lstat(dst)
+	exists := err == nil
+	var aside string
+	if exists {
+		if os.SameFile(srcInfo, dstInfo) {
+			return nil
+		}
+		if opts.Overwrite == OverwriteNever ||
+			(opts.Overwrite == OverwriteIfNewer &&
+				!srcInfo.ModTime().After(dstInfo.ModTime())) {
+			return &fs.PathError{Op: "move", Path: dst, Err: fs.ErrExist}
+		}
+		if dstInfo.IsDir() || srcInfo.IsDir() {
+			if aside, err = moveAside(dst); err != nil {
+				return err
+			}
+		}
+	}
+	if err = rename(src, dst); err != nil && isCrossDevice(err) {
+		if exists && aside == "" {
+			if aside, err = moveAside(dst); err != nil {
+				return err
+			}
+		}
+		err = moveByCopy(src, dst, srcInfo)
+	}
+	if err != nil {
+		if aside != "" {
+			err = errors.Join(err, restoreAside(aside, dst))
+		}
+		return err
+	}
+	if aside != "" {
+		return removeAll(filepath.Dir(aside))
+	}
+	return nil
+}
+
+// moveAside renames path into a new temporary folder beside it and
+// returns its new name. See also [restoreAside].
+func moveAside(path string) (string, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path),
+		"."+filepath.Base(path)+".*.old")
+	if err != nil {
+		return "", err
+	}
+	aside := filepath.Join(dir, filepath.Base(path))
+	if err = rename(path, aside); err != nil {
+		os.Remove(dir)
+		return "", err
+	}
+	return aside, nil
+}
+
+// restoreAside renames aside (as returned by [moveAside]) back to path,
+// replacing anything at path, and removes aside's temporary folder.
+func restoreAside(aside, path string) error {
+	if err := removeAll(path); err != nil {
+		return err
+	}
+	if err := rename(aside, path); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Dir(aside))
+}
+
+// moveByCopy copies src to dst, verifies the copy, and removes src. If the
+// copy or its verification fails dst is removed.
+func moveByCopy(src, dst string, srcInfo fs.FileInfo) error {
+	if err := copyEntry(src, dst, nil, nil, nil); err != nil {
+		return errors.Join(err, removeAll(dst))
+	}
+	var same bool
+	switch {
+	case srcInfo.Mode().IsRegular():
+		var err error
+		if same, err = FilesEqual(src, dst); err != nil {
+			return errors.Join(err, removeAll(dst))
+		}
+	case srcInfo.IsDir():
+		report, err := DiffDirs(src, dst, DiffOptions{ByHash: true})
+		if err != nil {
+			return errors.Join(err, removeAll(dst))
+		}
+		same = report.Same()
+	default: // symlink
+		same = true
+	}
+	if !same {
+		return errors.Join(fmt.Errorf(
+			"move %q to %q: copy verification failed", src, dst),
+			removeAll(dst))
+	}
+	return removeAll(src)
+}
@@ -0,0 +1,166 @@
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_CopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	if err := os.WriteFile(src, []byte("some data\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2020, 5, 17, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(src, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []CloneMode{CloneAuto, CloneNever, CloneRequire} {
+		dst := filepath.Join(dir, "dst.txt")
+		err := CopyFileOpt(src, dst, CopyOptions{Clone: mode})
+		if err != nil {
+			if mode == CloneRequire && errors.Is(err, ErrCloneUnsupported) {
+				if PathExists(dst) {
+					t.Errorf("expected no dst after failed clone")
+				}
+				continue // e.g., tmpfs or ext4
+			}
+			t.Fatal(err)
+		}
+		equal, err := FilesEqual(src, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equal {
+			t.Errorf("mode %d: expected equal files", mode)
+		}
+		info, err := os.Stat(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(modTime) {
+			t.Errorf("mode %d: expected %v got %v", mode, modTime,
+				info.ModTime())
+		}
+		if perm := info.Mode().Perm(); perm != 0o640 {
+			t.Errorf("mode %d: expected %o got %o", mode, 0o640, perm)
+		}
+		os.Remove(dst)
+	}
+	if err := CopyFile(dir, filepath.Join(dir, "x")); err == nil {
+		t.Error("expected error copying a folder")
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, ".*.tmp")); len(
+		matches) != 0 {
+		t.Errorf("expected no temporary files got %v", matches)
+	}
+}
+
+func Test_CopyFileSparse(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "sparse.img")
+	const size = 16 * 1024 * 1024
+	file, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = file.WriteAt([]byte("start"), 0)
+	if err == nil {
+		_, err = file.WriteAt([]byte("end"), size-3)
+	}
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	sparse, err := IsSparse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sparse {
+		t.Skip("filesystem doesn't support sparse files")
+	}
+	dst := filepath.Join(dir, "copy.img")
+	err = CopyFileOpt(src, dst, CopyOptions{Clone: CloneNever})
+	if err != nil {
+		t.Fatal(err)
+	}
+	apparent, allocated, err := ApparentAndAllocatedSize(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apparent != size {
+		t.Errorf("expected %d got %d", size, apparent)
+	}
+	if allocated >= size {
+		t.Errorf("expected sparse copy got %d allocated", allocated)
+	}
+	equal, err := FilesEqual(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal {
+		t.Error("expected equal files")
+	}
+}
+
+func Test_Move(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"a.txt": "alpha",
+		"b.txt": "beta", "tree/sub/c.txt": "gamma"})
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	if err := Move(a, b, MoveOptions{}); !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected %v got %v", os.ErrExist, err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(a, old, old); err != nil {
+		t.Fatal(err)
+	}
+	err := Move(a, b, MoveOptions{Overwrite: OverwriteIfNewer})
+	if !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected %v got %v", os.ErrExist, err)
+	}
+	if err = Move(a, b, MoveOptions{Overwrite: OverwriteAlways}); err != nil {
+		t.Fatal(err)
+	}
+	if lines, err := ReadTextFile(b); err != nil || lines[0] != "alpha" ||
+		PathExists(a) {
+		t.Errorf("expected a.txt moved to b.txt got %q %v", lines, err)
+	}
+	tree := filepath.Join(dir, "tree")
+	info, err := os.Lstat(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := filepath.Join(dir, "moved")
+	if err = moveByCopy(tree, moved, info); err != nil { // cross-device
+		t.Fatal(err)
+	}
+	if PathExists(tree) || !FileExists(filepath.Join(moved, "sub",
+		"c.txt")) {
+		t.Error("expected tree to have been moved")
+	}
+	err = Move(moved, filepath.Join(moved, "sub", "inner"),
+		MoveOptions{Overwrite: OverwriteAlways})
+	if !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("expected %v got %v", fs.ErrInvalid, err)
+	}
+	err = Move(moved, b, MoveOptions{Overwrite: OverwriteAlways})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `.
+└── b.txt/
+    └── sub/
+        └── c.txt
+`
+	if tree, _ := TreeString(dir); tree != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, tree)
+	}
+}
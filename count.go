@@ -0,0 +1,92 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"unicode"
+)
+
+// FileCounts holds the counts returned by [Counts].
+type FileCounts struct {
+	Lines int
+	Words int
+	Bytes int64
+}
+
+// CountLines returns the number of lines in the given file without
+// reading the whole file into memory, i.e., the number of \n bytes plus
+// one if the file doesn't end with \n, so an empty file has 0 lines and
+// "a\n\n\n" has 3. (This can differ from len(lines) for the lines
+// returned by [ReadTextFile], which drops trailing blank lines.)
+// See also [Counts].
+func CountLines(filename string) (int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	buffer := make([]byte, 64*1024)
+	lines := 0
+	last := byte('\n')
+	for {
+		n, err := file.Read(buffer)
+		if n > 0 {
+			lines += bytes.Count(buffer[:n], []byte{'\n'})
+			last = buffer[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		lines++ // last line did not end with \n
+	}
+	return lines, nil
+}
+
+// Counts returns the number of lines, words, and bytes in the given file
+// (much like wc(1)) without reading the whole file into memory. Lines are
+// counted as for [CountLines] and words are sequences of non-whitespace
+// characters (with the file's text treated as UTF-8).
+func Counts(filename string) (FileCounts, error) {
+	var counts FileCounts
+	file, err := os.Open(filename)
+	if err != nil {
+		return counts, err
+	}
+	defer file.Close()
+	reader := bufio.NewReaderSize(file, 64*1024)
+	inWord := false
+	last := '\n'
+	for {
+		c, size, err := reader.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return counts, err
+		}
+		counts.Bytes += int64(size)
+		if c == '\n' {
+			counts.Lines++
+		}
+		if unicode.IsSpace(c) {
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			counts.Words++
+		}
+		last = c
+	}
+	if last != '\n' {
+		counts.Lines++ // last line did not end with \n
+	}
+	return counts, nil
+}
@@ -0,0 +1,41 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_CountLines(t *testing.T) {
+	dir := t.TempDir()
+	for text, expected := range map[string]int{"": 0, "a": 1, "a\n": 1,
+		"a\nb": 2, "a\r\nb\r\n": 2, "\n\n\n": 3} {
+		filename := filepath.Join(dir, "test.txt")
+		if err := os.WriteFile(filename, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		lines, err := CountLines(filename)
+		if err != nil || lines != expected {
+			t.Errorf("%q: expected %d got %d %v", text, expected, lines, err)
+		}
+		counts, err := Counts(filename)
+		if err != nil || counts.Lines != expected ||
+			counts.Bytes != int64(len(text)) {
+			t.Errorf("%q: expected %d got %v %v", text, expected, counts,
+				err)
+		}
+	}
+}
+
+func Test_Counts(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.txt")
+	text := "The quick brown\tfox\n\n  jumped über the dog\n"
+	if err := os.WriteFile(filename, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	counts, err := Counts(filename)
+	expected := FileCounts{Lines: 3, Words: 8, Bytes: int64(len(text))}
+	if err != nil || counts != expected {
+		t.Errorf("expected %v got %v %v", expected, counts, err)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"iter"
+	"os"
+)
+
+// CSVOptions are used by [ReadCSV].
+//
+// Delimiter is the field separator (or ',' if 0), e.g., '\t' for TSV.
+// If Comment is not 0 lines that begin with it are skipped. If SkipHeader
+// is true the first record is skipped. If LazyQuotes is true quotes may
+// appear in unquoted fields and non-doubled quotes in quoted fields.
+// Records may have differing numbers of fields.
+type CSVOptions struct {
+	Delimiter  rune
+	Comment    rune
+	SkipHeader bool
+	LazyQuotes bool
+}
+
+// ReadCSV reads the given CSV (or TSV, etc.) file and returns an iterator
+// of (record, error) for every record, where each record is a slice of
+// fields. The file is read as it is iterated rather than all at once.
+// Iteration stops after the first error. See also [ReadUtf8Lines].
+func ReadCSV(filename string, opts CSVOptions) iter.Seq2[[]string, error] {
+	return func(yield func([]string, error) bool) {
+		file, err := os.Open(filename)
+		if err != nil {
+			yield(nil, err) // failed to open file
+			return          // we cannot progress from here
+		}
+		defer file.Close()
+		reader := csv.NewReader(bufio.NewReader(file))
+		if opts.Delimiter != 0 {
+			reader.Comma = opts.Delimiter
+		}
+		reader.Comment = opts.Comment
+		reader.LazyQuotes = opts.LazyQuotes
+		reader.FieldsPerRecord = -1
+		skip := opts.SkipHeader
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err) // read or parse error
+				return          // we cannot progress further
+			}
+			if skip {
+				skip = false
+				continue
+			}
+			if !yield(record, nil) {
+				return // for loop break or return or panic
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_ReadCSV(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "test.csv")
+	text := "name,qty\n# comment\napple,3\n\"pear, williams\",\"1\"\nfig\n"
+	if err := os.WriteFile(filename, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var records [][]string
+	for record, err := range ReadCSV(filename, CSVOptions{Comment: '#',
+		SkipHeader: true}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	expected := [][]string{{"apple", "3"}, {"pear, williams", "1"},
+		{"fig"}}
+	if !slices.EqualFunc(records, expected, slices.Equal) {
+		t.Errorf("expected %q got %q", expected, records)
+	}
+	filename = filepath.Join(dir, "test.tsv")
+	text = "a\tb \"c\"\n"
+	if err := os.WriteFile(filename, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range ReadCSV(filename, CSVOptions{Delimiter: '\t'}) {
+		if err == nil {
+			t.Error("expected bare quote error")
+		}
+	}
+	for record, err := range ReadCSV(filename, CSVOptions{Delimiter: '\t',
+		LazyQuotes: true}) {
+		if err != nil || !slices.Equal(record, []string{"a", `b "c"`}) {
+			t.Errorf("expected %q got %q %v", []string{"a", `b "c"`},
+				record, err)
+		}
+	}
+}
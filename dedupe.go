@@ -0,0 +1,133 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// DedupOptions are used by [DedupeByHardlink].
+//
+// Files smaller than MinSize bytes (or empty files if MinSize is 0) are
+// ignored. If DryRun is true the duplicates are found and the space that
+// would be saved is returned but nothing is changed.
+type DedupOptions struct {
+	MinSize int64
+	DryRun  bool
+}
+
+// DedupeByHardlink finds byte-identical regular files in the tree rooted
+// at folder root and replaces each duplicate with a hard link to the
+// first such file (in walk order), returning the number of bytes saved.
+// Files are compared by size, then checksum, then byte-by-byte, and only
+// files on the same device are linked. Files that are already hard links
+// to one another count as one file. Symlinks aren't followed. Each
+// duplicate is replaced atomically so it is never missing. Note that
+// linked files share their permissions and modification time (those of
+// the first file) and that modifying one in place changes them all.
+func DedupeByHardlink(root string, opts DedupOptions) (saved int64,
+	err error,
+) {
+	minSize := max(opts.MinSize, 1)
+	var sizes []int64 // to process in walk order
+	bySize := make(map[int64][]string)
+	err = filepath.WalkDir(root, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil || !dirEntry.Type().IsRegular() {
+			return err
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		if size := info.Size(); size >= minSize {
+			if _, ok := bySize[size]; !ok {
+				sizes = append(sizes, size)
+			}
+			bySize[size] = append(bySize[size], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, size := range sizes {
+		if paths := bySize[size]; len(paths) > 1 {
+			n, err := dedupeSameSize(paths, opts.DryRun)
+			saved += n * size
+			if err != nil {
+				return saved, err
+			}
+		}
+	}
+	return saved, nil
+}
+
+// dedupeSameSize links duplicates among the given same-sized files and
+// returns how many were (or for a dry run, would be) linked.
+func dedupeSameSize(paths []string, dryRun bool) (int64, error) {
+	type fileID struct{ device, inode uint64 }
+	type key struct {
+		device uint64
+		hash   string
+	}
+	seen := make(map[fileID]bool)
+	firsts := make(map[key][]string) // first file of each distinct content
+	var linked int64
+	for _, path := range paths {
+		info, err := Stat(path)
+		if err != nil {
+			return linked, err
+		}
+		id := fileID{info.Device, info.Inode}
+		if info.Inode != 0 {
+			if seen[id] {
+				continue // already a hard link to a file we've seen
+			}
+			seen[id] = true
+		}
+		hash, err := Checksum(path, SHA256)
+		if err != nil {
+			return linked, err
+		}
+		k := key{info.Device, hash}
+		duplicate := ""
+		for _, first := range firsts[k] {
+			if same, err := FilesEqual(first, path); err != nil {
+				return linked, err
+			} else if same {
+				duplicate = first
+				break
+			}
+		}
+		if duplicate == "" {
+			firsts[k] = append(firsts[k], path)
+			continue
+		}
+		if !dryRun {
+			if err = replaceWithLink(duplicate, path); err != nil {
+				return linked, err
+			}
+		}
+		linked++
+	}
+	return linked, nil
+}
+
+// replaceWithLink atomically replaces path with a hard link to target.
+func replaceWithLink(target, path string) error {
+	temp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+"."+
+		strconv.Itoa(os.Getpid())+".lnk")
+	if err := os.Link(target, temp); err != nil {
+		return err
+	}
+	if err := rename(temp, path); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
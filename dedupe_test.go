@@ -0,0 +1,56 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_DedupeByHardlink(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"a.txt": "same content",
+		"sub/b.txt": "same content", "sub/c.txt": "same content",
+		"d.txt": "diff content", "e.txt": "unique", "f.txt": ""})
+	saved, err := DedupeByHardlink(dir, DedupOptions{DryRun: true})
+	if err != nil || saved != 24 {
+		t.Errorf("expected 24 got %d %v", saved, err)
+	}
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "sub", "b.txt")
+	if isSameFile(t, a, b) {
+		t.Error("expected dry run to change nothing")
+	}
+	saved, err = DedupeByHardlink(dir, DedupOptions{})
+	if err != nil || saved != 24 {
+		t.Errorf("expected 24 got %d %v", saved, err)
+	}
+	c := filepath.Join(dir, "sub", "c.txt")
+	if !isSameFile(t, a, b) || !isSameFile(t, a, c) {
+		t.Error("expected duplicates to be hard links")
+	}
+	if isSameFile(t, a, filepath.Join(dir, "d.txt")) {
+		t.Error("expected different content to be left alone")
+	}
+	saved, err = DedupeByHardlink(dir, DedupOptions{})
+	if err != nil || saved != 0 {
+		t.Errorf("expected 0 got %d %v", saved, err)
+	}
+	expected := ".\n├── a.txt\n├── d.txt\n├── e.txt\n├── f.txt\n" +
+		"└── sub/\n    ├── b.txt\n    └── c.txt\n"
+	if tree, _ := TreeString(dir); tree != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, tree)
+	}
+}
+
+func isSameFile(t *testing.T, a, b string) bool {
+	t.Helper()
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return os.SameFile(aInfo, bInfo)
+}
@@ -0,0 +1,233 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// ErrBadPatch is returned (wrapped) by [PatchFile] if the patch is
+// corrupt or was made from a different old file.
+var ErrBadPatch = errors.New("invalid patch")
+
+const (
+	deltaMagic      = "ufdelta1"
+	deltaOpCopy     = 1 // uvarint offset and length of old data
+	deltaOpAdd      = 2 // uvarint length then the literal data
+	deltaPrime      = 16777619
+	deltaCandidates = 8 // maximum old blocks compared per hash
+)
+
+// DiffFiles returns a binary patch which [PatchFile] can apply to file old
+// to recreate file new. The patch holds the parts of new that aren't in
+// old along with instructions to copy the parts that are (wherever they
+// are in old), so for small changes it is much smaller than new. Both
+// files are read into memory. The patch includes checksums of both
+// files so that applying it to the wrong file, or a corrupt patch, is
+// detected.
+func DiffFiles(old, new string) ([]byte, error) {
+	oldData, err := os.ReadFile(old)
+	if err != nil {
+		return nil, err
+	}
+	newData, err := os.ReadFile(new)
+	if err != nil {
+		return nil, err
+	}
+	return diffBytes(oldData, newData), nil
+}
+
+// PatchFile applies patch (as returned by [DiffFiles]) to file old and
+// atomically writes the result to file out (which may be old itself). An
+// error wrapping [ErrBadPatch] is returned if the patch is corrupt or was
+// made from a different old file.
+func PatchFile(old string, patch []byte, out string) error {
+	oldData, err := os.ReadFile(old)
+	if err != nil {
+		return err
+	}
+	newData, err := patchBytes(oldData, patch)
+	if err != nil {
+		return &fs.PathError{Op: "patch", Path: old, Err: err}
+	}
+	return writeAtomic(out, func(writer *bufio.Writer) error {
+		_, err := writer.Write(newData)
+		return err
+	})
+}
+
+// diffBytes returns a patch that turns old into new. Old's blocks are
+// indexed by a polynomial hash and a hash rolled over new finds where
+// they occur; each match is extended as far as it goes in both
+// directions.
+func diffBytes(old, new []byte) []byte {
+	oldSum := sha256.Sum256(old)
+	newSum := sha256.Sum256(new)
+	patch := append([]byte(deltaMagic), oldSum[:]...)
+	patch = append(patch, newSum[:]...)
+	patch = binary.AppendUvarint(patch, uint64(len(new)))
+	addLiteral := func(data []byte) {
+		if len(data) > 0 {
+			patch = append(patch, deltaOpAdd)
+			patch = binary.AppendUvarint(patch, uint64(len(data)))
+			patch = append(patch, data...)
+		}
+	}
+	size := deltaBlockSize(len(old))
+	if len(old) < size || len(new) < size {
+		addLiteral(new)
+		return patch
+	}
+	index := make(map[uint32][]int, len(old)/size)
+	for offset := 0; offset+size <= len(old); offset += size {
+		hash := deltaHash(old[offset : offset+size])
+		if len(index[hash]) < deltaCandidates {
+			index[hash] = append(index[hash], offset)
+		}
+	}
+	var outFactor uint32 = 1 // deltaPrime**(size-1)
+	for range size - 1 {
+		outFactor *= deltaPrime
+	}
+	start := 0 // of the pending literal data
+	i := 0
+	hash := deltaHash(new[:size])
+	for i+size <= len(new) {
+		if offset, length, ok := deltaMatch(old, new, index[hash], i,
+			size); ok {
+			for i > start && offset > 0 && old[offset-1] == new[i-1] {
+				i--
+				offset--
+				length++
+			}
+			addLiteral(new[start:i])
+			patch = append(patch, deltaOpCopy)
+			patch = binary.AppendUvarint(patch, uint64(offset))
+			patch = binary.AppendUvarint(patch, uint64(length))
+			i += length
+			start = i
+			if i+size <= len(new) {
+				hash = deltaHash(new[i : i+size])
+			}
+			continue
+		}
+		if i+size < len(new) {
+			hash = (hash-uint32(new[i])*outFactor)*deltaPrime +
+				uint32(new[i+size])
+		}
+		i++
+	}
+	addLiteral(new[start:])
+	return patch
+}
+
+// deltaMatch returns the offset in old and the length of the longest
+// match of new at position i among the candidate offsets, and true, or
+// false if none of them match.
+func deltaMatch(old, new []byte, candidates []int, i, size int) (int,
+	int, bool,
+) {
+	bestOffset, bestLength := 0, 0
+	for _, offset := range candidates {
+		if !bytes.Equal(old[offset:offset+size], new[i:i+size]) {
+			continue
+		}
+		length := size
+		for offset+length < len(old) && i+length < len(new) &&
+			old[offset+length] == new[i+length] {
+			length++
+		}
+		if length > bestLength {
+			bestOffset, bestLength = offset, length
+		}
+	}
+	return bestOffset, bestLength, bestLength > 0
+}
+
+// deltaBlockSize returns the size of the blocks used to find matches,
+// which grows with the old file's size to keep the index small.
+func deltaBlockSize(size int) int {
+	block := 16
+	for block < 4096 && block*block < size {
+		block *= 2
+	}
+	return block
+}
+
+// deltaHash returns the polynomial hash of data.
+func deltaHash(data []byte) uint32 {
+	var hash uint32
+	for _, b := range data {
+		hash = hash*deltaPrime + uint32(b)
+	}
+	return hash
+}
+
+// patchBytes returns the result of applying patch to old.
+func patchBytes(old, patch []byte) ([]byte, error) {
+	headerSize := len(deltaMagic) + 2*sha256.Size
+	if len(patch) < headerSize ||
+		string(patch[:len(deltaMagic)]) != deltaMagic {
+		return nil, fmt.Errorf("%w: bad header", ErrBadPatch)
+	}
+	oldSum := patch[len(deltaMagic) : len(deltaMagic)+sha256.Size]
+	newSum := patch[len(deltaMagic)+sha256.Size : headerSize]
+	if sum := sha256.Sum256(old); !bytes.Equal(sum[:], oldSum) {
+		return nil, fmt.Errorf("%w: made from a different file",
+			ErrBadPatch)
+	}
+	patch = patch[headerSize:]
+	size, n := binary.Uvarint(patch)
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: bad size", ErrBadPatch)
+	}
+	patch = patch[n:]
+	// size is only trusted after the checksum is checked, but the result
+	// is never allowed to grow beyond it
+	result := make([]byte, 0, min(size, uint64(4*(len(old)+len(patch)))))
+	for len(patch) > 0 {
+		op := patch[0]
+		patch = patch[1:]
+		switch op {
+		case deltaOpCopy:
+			offset, n := binary.Uvarint(patch)
+			if n <= 0 {
+				return nil, fmt.Errorf("%w: bad copy", ErrBadPatch)
+			}
+			length, m := binary.Uvarint(patch[n:])
+			if m <= 0 || offset > uint64(len(old)) ||
+				length > uint64(len(old))-offset {
+				return nil, fmt.Errorf("%w: bad copy", ErrBadPatch)
+			}
+			if length > size-uint64(len(result)) {
+				return nil, fmt.Errorf("%w: too much data", ErrBadPatch)
+			}
+			result = append(result, old[offset:offset+length]...)
+			patch = patch[n+m:]
+		case deltaOpAdd:
+			length, n := binary.Uvarint(patch)
+			if n <= 0 || length > uint64(len(patch)-n) {
+				return nil, fmt.Errorf("%w: bad data", ErrBadPatch)
+			}
+			if length > size-uint64(len(result)) {
+				return nil, fmt.Errorf("%w: too much data", ErrBadPatch)
+			}
+			result = append(result, patch[n:n+int(length)]...)
+			patch = patch[n+int(length):]
+		default:
+			return nil, fmt.Errorf("%w: bad operation %d", ErrBadPatch, op)
+		}
+	}
+	if sum := sha256.Sum256(result); uint64(len(result)) != size ||
+		!bytes.Equal(sum[:], newSum) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrBadPatch)
+	}
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package ufile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_DiffPatchBytes(t *testing.T) {
+	random := rand.New(rand.NewPCG(1, 2))
+	old := make([]byte, 200_000)
+	for i := range old {
+		old[i] = byte(random.IntN(256))
+	}
+	var new []byte
+	new = append(new, []byte("new header")...)
+	new = append(new, old[50_000:120_000]...) // moved
+	new = append(new, []byte("inserted text")...)
+	new = append(new, old[:40_000]...)
+	new = append(new, old[150_000:]...)
+	new[100] ^= 0xFF // changed byte
+	for _, item := range []struct{ old, new []byte }{{old, new},
+		{nil, []byte("all new")}, {[]byte("short"), nil}, {old, old}} {
+		patch := diffBytes(item.old, item.new)
+		result, err := patchBytes(item.old, patch)
+		if err != nil || !bytes.Equal(result, item.new) {
+			t.Errorf("expected patch to recreate new: %v", err)
+		}
+	}
+	patch := diffBytes(old, new)
+	if len(patch) > 1000 {
+		t.Errorf("expected small patch got %d bytes", len(patch))
+	}
+	if _, err := patchBytes(new, patch); !errors.Is(err, ErrBadPatch) {
+		t.Errorf("expected ErrBadPatch for wrong file got %v", err)
+	}
+	patch[len(patch)-1] ^= 0xFF
+	if _, err := patchBytes(old, patch); !errors.Is(err, ErrBadPatch) {
+		t.Errorf("expected ErrBadPatch for corrupt patch got %v", err)
+	}
+	if _, err := patchBytes(old, patch[:50]); !errors.Is(err,
+		ErrBadPatch) {
+		t.Errorf("expected ErrBadPatch for truncated patch got %v", err)
+	}
+	// a small patch that copies all of old many times mustn't be applied
+	sum := sha256.Sum256(old)
+	bomb := append([]byte(deltaMagic), sum[:]...)
+	bomb = append(bomb, sum[:]...)
+	bomb = binary.AppendUvarint(bomb, uint64(len(old)))
+	for range 1000 {
+		bomb = append(bomb, deltaOpCopy)
+		bomb = binary.AppendUvarint(bomb, 0)
+		bomb = binary.AppendUvarint(bomb, uint64(len(old)))
+	}
+	if _, err := patchBytes(old, bomb); !errors.Is(err, ErrBadPatch) ||
+		!strings.Contains(err.Error(), "too much data") {
+		t.Errorf("expected ErrBadPatch for oversized patch got %v", err)
+	}
+}
+
+func Test_DiffPatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	oldname := filepath.Join(dir, "app-1.0.bin")
+	newname := filepath.Join(dir, "app-1.1.bin")
+	if err := os.WriteFile(oldname, bytes.Repeat([]byte("0123456789"),
+		1000), ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newname, bytes.Repeat([]byte("0123456789"),
+		1100), ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	patch, err := DiffFiles(oldname, newname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = PatchFile(oldname, patch, oldname); err != nil {
+		t.Fatal(err)
+	}
+	if same, err := FilesEqual(oldname, newname); err != nil || !same {
+		t.Errorf("expected patched file to equal new file: %v", err)
+	}
+	if err = PatchFile(oldname, patch, oldname); !errors.Is(err,
+		ErrBadPatch) {
+		t.Errorf("expected ErrBadPatch got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Entry holds a path and the [fs.FileInfo] for it (which for symlinks is
+// the information about the link itself).
+type Entry struct {
+	Path string
+	fs.FileInfo
+}
+
+// ListOptions are used by [ListDir].
+//
+// If DirsFirst is true folders are listed before files. If Hidden is true
+// entries whose names begin with '.' are included. If Glob is nonempty
+// only entries whose names match it (see [filepath.Match]) are included.
+type ListOptions struct {
+	DirsFirst bool
+	Hidden    bool
+	Glob      string
+}
+
+// ListDir returns the entries in the given folder sorted naturally, i.e.,
+// case-insensitively and with digit sequences compared numerically so that
+// file2 comes before file10. See also [NaturalCompare].
+func ListDir(dir string, opts ListOptions) ([]Entry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if !opts.Hidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+		if opts.Glob != "" {
+			matched, err := filepath.Match(opts.Glob, name)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // removed since ReadDir
+			}
+			return nil, err
+		}
+		entries = append(entries, Entry{filepath.Join(dir, name), info})
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		if opts.DirsFirst && a.IsDir() != b.IsDir() {
+			if a.IsDir() {
+				return -1
+			}
+			return 1
+		}
+		return NaturalCompare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
+// NaturalCompare returns -1, 0, or 1 depending on whether a is naturally
+// less than, equal to, or greater than b. Letters are compared
+// case-insensitively and digit sequences numerically, e.g., "file2" <
+// "File10". This function can be used to sort a slice of strings, e.g.,
+// `slices.SortFunc(names, ufile.NaturalCompare)`.
+func NaturalCompare(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			x := digitsEnd(a, i)
+			y := digitsEnd(b, j)
+			m := strings.TrimLeft(a[i:x], "0")
+			n := strings.TrimLeft(b[j:y], "0")
+			if len(m) != len(n) {
+				if len(m) < len(n) {
+					return -1
+				}
+				return 1
+			}
+			if c := strings.Compare(m, n); c != 0 {
+				return c
+			}
+			i, j = x, y
+			continue
+		}
+		r, x := utf8.DecodeRuneInString(a[i:])
+		s, y := utf8.DecodeRuneInString(b[j:])
+		r = unicode.ToLower(r)
+		s = unicode.ToLower(s)
+		if r != s {
+			if r < s {
+				return -1
+			}
+			return 1
+		}
+		i += x
+		j += y
+	}
+	if c := (len(a) - i) - (len(b) - j); c != 0 {
+		if c < 0 {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b) // e.g., "a01" vs "a1" or "A" vs "a"
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func digitsEnd(s string, i int) int {
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// NewestFile returns the file in the given folder whose name matches the
+// pattern (see [filepath.Match]; "" matches every name) and which has the
+// most recent modification time, or "" if there is no matching file.
+// See also [OldestFile].
+func NewestFile(dir, pattern string) (string, error) {
+	return extremeFile(dir, pattern, func(a, b time.Time) bool {
+		return a.After(b)
+	})
+}
+
+// OldestFile returns the file in the given folder whose name matches the
+// pattern (see [filepath.Match]; "" matches every name) and which has the
+// oldest modification time, or "" if there is no matching file.
+// See also [NewestFile].
+func OldestFile(dir, pattern string) (string, error) {
+	return extremeFile(dir, pattern, func(a, b time.Time) bool {
+		return a.Before(b)
+	})
+}
+
+func extremeFile(dir, pattern string, better func(a, b time.Time) bool) (
+	string, error,
+) {
+	entries, err := ListDir(dir, ListOptions{Hidden: true, Glob: pattern})
+	if err != nil {
+		return "", err
+	}
+	var filename string
+	var modTime time.Time
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		if filename == "" || better(entry.ModTime(), modTime) {
+			filename = entry.Path
+			modTime = entry.ModTime()
+		}
+	}
+	return filename, nil
+}
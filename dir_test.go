@@ -0,0 +1,96 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_NaturalCompare(t *testing.T) {
+	names := []string{"file10", "File2", "file1", "a", "file02", "B",
+		"file2b", "file2a"}
+	slices.SortFunc(names, NaturalCompare)
+	expected := []string{"a", "B", "file1", "File2", "file02", "file2a",
+		"file2b", "file10"}
+	if slices.Compare(names, expected) != 0 {
+		t.Errorf("expected %q got %q", expected, names)
+	}
+}
+
+func Test_ListDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"file10.txt", "file2.txt", ".hidden",
+		"notes.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil,
+			ModeURW); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "zdir"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	names := func(entries []Entry) []string {
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+	entries, err := ListDir(dir, ListOptions{DirsFirst: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"zdir", "file2.txt", "file10.txt", "notes.md"}
+	if got := names(entries); slices.Compare(got, expected) != 0 {
+		t.Errorf("expected %q got %q", expected, got)
+	}
+	entries, err = ListDir(dir, ListOptions{Hidden: true, Glob: "*i*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{".hidden", "file2.txt", "file10.txt", "zdir"}
+	if got := names(entries); slices.Compare(got, expected) != 0 {
+		t.Errorf("expected %q got %q", expected, got)
+	}
+	if entries[1].Path != filepath.Join(dir, "file2.txt") {
+		t.Errorf("expected %q got %q", filepath.Join(dir, "file2.txt"),
+			entries[1].Path)
+	}
+}
+
+func Test_NewestOldestFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"s1.session", "s2.session", "s3.session",
+		"other.txt"} {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, nil, ModeURW); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(time.Duration(i) * time.Hour)
+		if i == 1 {
+			modTime = now.Add(-time.Hour)
+		}
+		if err := os.Chtimes(filename, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filename, err := NewestFile(dir, "*.session")
+	if err != nil || filename != filepath.Join(dir, "s3.session") {
+		t.Errorf("expected s3.session got %q %v", filename, err)
+	}
+	filename, err = OldestFile(dir, "*.session")
+	if err != nil || filename != filepath.Join(dir, "s2.session") {
+		t.Errorf("expected s2.session got %q %v", filename, err)
+	}
+	filename, err = NewestFile(dir, "")
+	if err != nil || filename != filepath.Join(dir, "other.txt") {
+		t.Errorf("expected other.txt got %q %v", filename, err)
+	}
+	filename, err = NewestFile(dir, "*.none")
+	if err != nil || filename != "" {
+		t.Errorf("expected \"\" got %q %v", filename, err)
+	}
+}
@@ -0,0 +1,375 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// DiffOptions are used by [DiffDirs].
+//
+// By default files are considered to differ if their sizes or
+// modification times differ (modification times within ModTimeWindow of
+// each other are considered equal, which helps with filesystems like FAT
+// that have coarse timestamps). If ByHash is true, files of the same size
+// are compared by their Algo checksums instead of by modification time.
+// If NormalizeUnicode is true names that differ only in their Unicode
+// normalization (see [NormalizePathUnicode]) are considered the same.
+type DiffOptions struct {
+	ByHash           bool
+	Algo             HashAlgo
+	ModTimeWindow    time.Duration
+	NormalizeUnicode bool
+}
+
+// Report is the result of comparing two trees with [DiffDirs]. Every path
+// is slash-separated and relative to the trees' roots, and each slice is
+// sorted. If a folder is only in one tree only the folder itself is
+// reported, not its contents. Differ uses the paths as they are in tree a.
+type Report struct {
+	OnlyInA []string
+	OnlyInB []string
+	Differ  []string
+}
+
+// Same returns true if the report shows no differences; otherwise returns
+// false.
+func (me Report) Same() bool {
+	return len(me.OnlyInA) == 0 && len(me.OnlyInB) == 0 &&
+		len(me.Differ) == 0
+}
+
+// DiffDirs compares the trees rooted at folders a and b and reports which
+// entries are only in a, only in b, or in both but differ (including where
+// one is a folder and the other isn't). Symlinks are compared by target
+// and not followed.
+func DiffDirs(a, b string, opts DiffOptions) (Report, error) {
+	report, _, err := diffDirs(a, b, opts, nil)
+	return report, err
+}
+
+// diffDirs implements [DiffDirs] and also returns a map of the Differ
+// paths whose names in b aren't the same as in a (because they differ in
+// Unicode normalization) to their (slash-separated) paths in b. Entries
+// that ignore ignores (and the contents of ignored folders) are skipped.
+func diffDirs(a, b string, opts DiffOptions, ignore *IgnoreMatcher,
+) (Report, map[string]string, error) {
+	var report Report
+	bNames := map[string]string{}
+	aInfos, err := treeInfos(a, opts.NormalizeUnicode, ignore)
+	if err != nil {
+		return report, bNames, err
+	}
+	bInfos, err := treeInfos(b, opts.NormalizeUnicode, ignore)
+	if err != nil {
+		return report, bNames, err
+	}
+	report.OnlyInA = onlyIn(aInfos, bInfos)
+	report.OnlyInB = onlyIn(bInfos, aInfos)
+	for _, key := range sortedKeys(aInfos) {
+		aEntry := aInfos[key]
+		bEntry, ok := bInfos[key]
+		if !ok {
+			continue
+		}
+		differ, err := entriesDiffer(filepath.Join(a, aEntry.rel),
+			aEntry.info, filepath.Join(b, bEntry.rel), bEntry.info, opts)
+		if err != nil {
+			return report, bNames, err
+		}
+		if differ {
+			rel := filepath.ToSlash(aEntry.rel)
+			report.Differ = append(report.Differ, rel)
+			if bEntry.rel != aEntry.rel {
+				bNames[rel] = filepath.ToSlash(bEntry.rel)
+			}
+		}
+	}
+	return report, bNames, nil
+}
+
+// treeEntry is a path relative to a tree's root and its lstat
+// information.
+type treeEntry struct {
+	rel  string
+	info fs.FileInfo
+}
+
+// treeInfos returns a map of every path (relative to root) in the tree
+// rooted at root to its entry, skipping entries that ignore ignores
+// (without descending into ignored folders). If normalize is true the
+// map's keys are normalized to NFC.
+func treeInfos(root string, normalize bool, ignore *IgnoreMatcher,
+) (map[string]treeEntry, error) {
+	infos := map[string]treeEntry{}
+	err := filepath.WalkDir(root, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if ignore.Match(filepath.ToSlash(rel), dirEntry.IsDir()) {
+			if dirEntry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		key := rel
+		if normalize {
+			key = NormalizePathUnicode(rel, NFC)
+		}
+		infos[key] = treeEntry{rel, info}
+		return nil
+	})
+	return infos, err
+}
+
+func sortedKeys(infos map[string]treeEntry) []string {
+	keys := make([]string, 0, len(infos))
+	for key := range infos {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// onlyIn returns the slash-separated paths in these but not in those,
+// omitting the contents of folders that are themselves only in these.
+func onlyIn(these, those map[string]treeEntry) []string {
+	var paths []string
+	for _, key := range sortedKeys(these) {
+		if _, ok := those[key]; ok {
+			continue
+		}
+		if parent := filepath.Dir(key); parent != "." {
+			if _, ok := those[parent]; !ok {
+				continue // reported via its folder
+			}
+		}
+		paths = append(paths, filepath.ToSlash(these[key].rel))
+	}
+	return paths
+}
+
+func entriesDiffer(aPath string, aInfo fs.FileInfo, bPath string,
+	bInfo fs.FileInfo, opts DiffOptions,
+) (bool, error) {
+	aType := aInfo.Mode().Type()
+	if aType != bInfo.Mode().Type() {
+		return true, nil
+	}
+	switch {
+	case aInfo.IsDir():
+		return false, nil
+	case aType&fs.ModeSymlink != 0:
+		aTarget, err := os.Readlink(aPath)
+		if err != nil {
+			return false, err
+		}
+		bTarget, err := os.Readlink(bPath)
+		if err != nil {
+			return false, err
+		}
+		return aTarget != bTarget, nil
+	case !aInfo.Mode().IsRegular():
+		return false, nil // devices, FIFOs, etc., aren't compared
+	}
+	if aInfo.Size() != bInfo.Size() {
+		return true, nil
+	}
+	if opts.ByHash {
+		aSum, err := Checksum(aPath, opts.Algo)
+		if err != nil {
+			return false, err
+		}
+		bSum, err := Checksum(bPath, opts.Algo)
+		if err != nil {
+			return false, err
+		}
+		return aSum != bSum, nil
+	}
+	delta := aInfo.ModTime().Sub(bInfo.ModTime()).Abs()
+	return delta > opts.ModTimeWindow, nil
+}
+
+// SyncAction identifies what [SyncDirs] is doing when it calls its
+// progress callback.
+type SyncAction uint8
+
+const (
+	SyncCopy SyncAction = iota
+	SyncDelete
+)
+
+// SyncOptions are used by [SyncDirs].
+//
+// Diff determines how changed files are detected (see [DiffOptions]). If
+// Delete is true entries in the destination that aren't in the source are
+// deleted. If DryRun is true nothing is changed but the progress callback
+// and stats report what would be done. If Progress is not nil it is called
+// before each entry is copied or deleted with a slash-separated path
+// relative to the roots. Entries that Ignore ignores (see
+// [IgnoreMatcher]) are neither copied nor deleted.
+type SyncOptions struct {
+	Diff     DiffOptions
+	Delete   bool
+	DryRun   bool
+	Progress func(action SyncAction, rel string)
+	Ignore   *IgnoreMatcher
+}
+
+// Stats reports what [SyncDirs] did (or for a dry run, would do).
+type Stats struct {
+	Copied  int   // entries copied (a folder and its contents count as 1)
+	Deleted int   // entries deleted (likewise)
+	Bytes   int64 // bytes copied (not counted for dry runs)
+}
+
+// SyncDirs makes the tree rooted at dst a mirror of the tree rooted at
+// src (which must exist) by copying new and changed entries (see
+// [DiffDirs]) and, if opts.Delete is true, deleting entries in dst that
+// aren't in src. Copies preserve permissions and modification times, and
+// files are replaced atomically. If opts.Diff.NormalizeUnicode is true
+// changed entries keep their dst names. See also [SyncDirsContext].
+func SyncDirs(src, dst string, opts SyncOptions) (Stats, error) {
+	return syncDirs(context.Background(), src, dst, opts, nil)
+}
+
+// SyncDirsContext works like [SyncDirs] but stops with the context's error
+// if ctx is cancelled (leaving dst partly synchronized, but with no
+// partial files), and if progress is not nil calls it as bytes are copied
+// with the total being the size of the files to be copied.
+func SyncDirsContext(ctx context.Context, src, dst string,
+	opts SyncOptions, progress ProgressFunc,
+) (Stats, error) {
+	return syncDirs(ctx, src, dst, opts, progress)
+}
+
+func syncDirs(ctx context.Context, src, dst string, opts SyncOptions,
+	progress ProgressFunc,
+) (Stats, error) {
+	var stats Stats
+	if !IsDir(src) {
+		return stats, &fs.PathError{Op: "sync", Path: src,
+			Err: fs.ErrNotExist}
+	}
+	if !opts.DryRun {
+		if err := os.MkdirAll(dst, fs.ModePerm); err != nil {
+			return stats, err
+		}
+	}
+	var diff Report
+	dstNames := map[string]string{}
+	if PathExists(dst) {
+		var err error
+		diff, dstNames, err = diffDirs(src, dst, opts.Diff, opts.Ignore)
+		if err != nil {
+			return stats, err
+		}
+	} else { // dry run so dst hasn't been created
+		infos, err := treeInfos(src, false, opts.Ignore)
+		if err != nil {
+			return stats, err
+		}
+		diff.OnlyInA = onlyIn(infos, nil)
+	}
+	report := func(action SyncAction, rel string) {
+		if opts.Progress != nil {
+			opts.Progress(action, rel)
+		}
+	}
+	if opts.Delete {
+		for _, rel := range diff.OnlyInB {
+			if err := ctx.Err(); err != nil {
+				return stats, err
+			}
+			report(SyncDelete, rel)
+			stats.Deleted++
+			if !opts.DryRun {
+				err := removeAll(filepath.Join(dst,
+					filepath.FromSlash(rel)))
+				if err != nil {
+					return stats, err
+				}
+			}
+		}
+	}
+	toCopy := append(diff.OnlyInA, diff.Differ...)
+	slices.Sort(toCopy) // so parents are copied before children
+	var track *tracker
+	if progress != nil || ctx.Done() != nil {
+		var total int64 = -1
+		if progress != nil && !opts.DryRun {
+			total = 0
+			for _, rel := range toCopy {
+				size, err := DirSizeContext(ctx, filepath.Join(src,
+					filepath.FromSlash(rel)), nil)
+				if err != nil {
+					return stats, err
+				}
+				total += size
+			}
+		}
+		track = newTracker(ctx, progress, total)
+	}
+	for _, rel := range toCopy {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		report(SyncCopy, rel)
+		stats.Copied++
+		if opts.DryRun {
+			continue
+		}
+		from := filepath.Join(src, filepath.FromSlash(rel))
+		to := filepath.Join(dst, filepath.FromSlash(rel))
+		if dstRel, ok := dstNames[rel]; ok {
+			to = filepath.Join(dst, filepath.FromSlash(dstRel))
+		}
+		if err := removeIfTypeDiffers(from, to); err != nil {
+			return stats, err
+		}
+		if err := copyEntry(from, to, track, func(_ string, size int64) {
+			stats.Bytes += size
+		}, func(sub string, isDir bool) bool {
+			return opts.Ignore.Match(path.Join(rel, sub), isDir)
+		}); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// removeIfTypeDiffers removes to if it exists and isn't the same kind of
+// entry (folder, file, symlink) as from.
+func removeIfTypeDiffers(from, to string) error {
+	toInfo, err := os.Lstat(to)
+	if err != nil {
+		return nil // doesn't exist
+	}
+	fromInfo, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	if fromInfo.Mode().Type() != toInfo.Mode().Type() {
+		return removeAll(to)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package ufile
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_DiffDirs(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	makeTestTree(t, a, map[string]string{"same.txt": "same",
+		"changed.txt": "old", "touched.txt": "touch", "onlya/x.txt": "x",
+		"onlya.txt": "a", "sub/s.txt": "s"})
+	makeTestTree(t, b, map[string]string{"same.txt": "same",
+		"changed.txt": "new", "touched.txt": "touch", "onlyb.txt": "b",
+		"sub/s.txt": "s", "sub/t.txt": "t"})
+	now := time.Now()
+	for _, root := range []string{a, b} {
+		for _, name := range []string{"same.txt", "changed.txt",
+			"sub/s.txt"} {
+			filename := filepath.Join(root, filepath.FromSlash(name))
+			if err := os.Chtimes(filename, now, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	later := now.Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(b, "touched.txt"), later,
+		later); err != nil {
+		t.Fatal(err)
+	}
+	report, err := DiffDirs(a, b, DiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(what string, got, expected []string) {
+		t.Helper()
+		if slices.Compare(got, expected) != 0 {
+			t.Errorf("%s: expected %q got %q", what, expected, got)
+		}
+	}
+	check("OnlyInA", report.OnlyInA, []string{"onlya", "onlya.txt"})
+	check("OnlyInB", report.OnlyInB, []string{"onlyb.txt", "sub/t.txt"})
+	check("Differ", report.Differ, []string{"touched.txt"})
+	report, err = DiffDirs(a, b, DiffOptions{ByHash: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Differ", report.Differ, []string{"changed.txt"})
+	if report.Same() {
+		t.Error("expected differences")
+	}
+	if report, err = DiffDirs(a, a, DiffOptions{}); err != nil ||
+		!report.Same() {
+		t.Errorf("expected no differences got %v %v", report, err)
+	}
+}
+
+func Test_SyncDirs(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	makeTestTree(t, src, map[string]string{"a.txt": "alpha",
+		"sub/b.txt": "beta", "new/c.txt": "gamma"})
+	makeTestTree(t, dst, map[string]string{"a.txt": "old alpha",
+		"sub/b.txt": "beta", "extra.txt": "extra"})
+	now := time.Now()
+	for _, root := range []string{src, dst} {
+		filename := filepath.Join(root, "sub", "b.txt")
+		if err := os.Chtimes(filename, now, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var actions []string
+	opts := SyncOptions{Delete: true, DryRun: true,
+		Progress: func(action SyncAction, rel string) {
+			actions = append(actions, rel)
+		}}
+	stats, err := SyncDirs(src, dst, opts)
+	if err != nil || stats.Copied != 2 || stats.Deleted != 1 {
+		t.Errorf("unexpected dry run stats %+v %v", stats, err)
+	}
+	expected := []string{"extra.txt", "a.txt", "new"}
+	if slices.Compare(actions, expected) != 0 {
+		t.Errorf("expected %q got %q", expected, actions)
+	}
+	if !PathExists(filepath.Join(dst, "extra.txt")) {
+		t.Error("expected dry run to change nothing")
+	}
+	opts.DryRun = false
+	stats, err = SyncDirs(src, dst, opts)
+	if err != nil || stats.Copied != 2 || stats.Deleted != 1 ||
+		stats.Bytes != 10 {
+		t.Errorf("unexpected stats %+v %v", stats, err)
+	}
+	report, err := DiffDirs(src, dst, DiffOptions{})
+	if err != nil || !report.Same() {
+		t.Errorf("expected mirrored trees got %+v %v", report, err)
+	}
+}
+
+func Test_SyncDirs_new(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	makeTestTree(t, src, map[string]string{"a.txt": "alpha",
+		"sub/b.txt": "beta"})
+	stats, err := SyncDirs(src, dst, SyncOptions{DryRun: true})
+	if err != nil || stats.Copied != 2 || PathExists(dst) {
+		t.Errorf("unexpected dry run stats %+v %v", stats, err)
+	}
+	stats, err = SyncDirs(src, dst, SyncOptions{})
+	if err != nil || stats.Copied != 2 || stats.Bytes != 9 {
+		t.Errorf("unexpected stats %+v %v", stats, err)
+	}
+}
+
+func Test_SyncDirsContext(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	makeTestTree(t, src, map[string]string{"a.txt": "alpha",
+		"sub/b.txt": "beta"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := SyncDirsContext(ctx, src, dst, SyncOptions{}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+	var done, total int64
+	stats, err := SyncDirsContext(context.Background(), src, dst,
+		SyncOptions{}, func(d, t int64, _ string) { done, total = d, t })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done != 9 || total != 9 || stats.Bytes != 9 {
+		t.Errorf("expected 9/9/9 got %d/%d/%d", done, total, stats.Bytes)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// LoadDotEnv reads the given .env file and returns its KEY=VALUE pairs.
+// Blank lines and lines beginning with # are ignored, and a leading
+// "export " is allowed (so the file can also be sourced by a shell).
+// Values may be unquoted (with surrounding whitespace and any " #..."
+// comment stripped), single-quoted (taken literally), or double-quoted
+// (with \n, \t, \r, \", and \\ escapes). Later keys replace earlier ones.
+// See also [ApplyDotEnv].
+func LoadDotEnv(filename string) (map[string]string, error) {
+	rx := regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_.]*)\s*=`)
+	env := make(map[string]string)
+	lino := 0
+	for line, err := range ReadUtf8Lines(filename) {
+		if err != nil {
+			return nil, err
+		}
+		lino++
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		match := rx.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("%s:%d: invalid dotenv line", filename,
+				lino)
+		}
+		value, ok := dotEnvValue(strings.TrimSpace(line[len(match[0]):]))
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: invalid dotenv value", filename,
+				lino)
+		}
+		env[match[1]] = value
+	}
+	return env, nil
+}
+
+// dotEnvValue returns the unquoted value and true, or false if the value
+// is invalid (e.g., has an unterminated quote).
+func dotEnvValue(text string) (string, bool) {
+	if text == "" {
+		return "", true
+	}
+	var rest string
+	var value string
+	switch text[0] {
+	case '\'':
+		end := strings.IndexByte(text[1:], '\'')
+		if end == -1 {
+			return "", false
+		}
+		value, rest = text[1:end+1], text[end+2:]
+	case '"':
+		var builder strings.Builder
+		i := 1
+		for ; i < len(text) && text[i] != '"'; i++ {
+			c := text[i]
+			if c == '\\' && i+1 < len(text) {
+				i++
+				switch c = text[i]; c {
+				case 'n':
+					c = '\n'
+				case 't':
+					c = '\t'
+				case 'r':
+					c = '\r'
+				case '"', '\\':
+				default:
+					builder.WriteByte('\\')
+				}
+			}
+			builder.WriteByte(c)
+		}
+		if i == len(text) {
+			return "", false
+		}
+		value, rest = builder.String(), text[i+1:]
+	default:
+		if i := strings.Index(text, " #"); i > -1 {
+			text = text[:i]
+		}
+		return strings.TrimSpace(text), true
+	}
+	rest = strings.TrimSpace(rest)
+	return value, rest == "" || rest[0] == '#'
+}
+
+// ApplyDotEnv reads the given .env file (see [LoadDotEnv]) and sets each
+// of its variables in the process's environment, except for those that
+// are already set, so real environment variables take precedence.
+func ApplyDotEnv(filename string) error {
+	env, err := LoadDotEnv(filename)
+	if err != nil {
+		return err
+	}
+	for key, value := range env {
+		if _, found := os.LookupEnv(key); !found {
+			if err = os.Setenv(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package ufile
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_LoadDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, ".env")
+	lines := []string{
+		"# settings",
+		"",
+		"NAME=plain value # comment",
+		"export PORT = 8080",
+		`GREETING="hello\n\"world\"" # comment`,
+		`RAW='no \n escapes'`,
+		"EMPTY=",
+		"URL=http://example.com/#anchor",
+	}
+	if err := WriteTextFile(filename, lines); err != nil {
+		t.Fatal(err)
+	}
+	env, err := LoadDotEnv(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"NAME": "plain value", "PORT": "8080",
+		"GREETING": "hello\n\"world\"", "RAW": `no \n escapes`, "EMPTY": "",
+		"URL": "http://example.com/#anchor"}
+	if !maps.Equal(env, expected) {
+		t.Errorf("expected %q got %q", expected, env)
+	}
+	for _, line := range []string{"NO EQUALS", `BAD="unterminated`,
+		`BAD='x' trailing`} {
+		if err = WriteTextFile(filename, []string{"A=1", line}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = LoadDotEnv(filename); err == nil {
+			t.Errorf("expected error for %q", line)
+		}
+	}
+}
+
+func Test_ApplyDotEnv(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), ".env")
+	t.Setenv("UFILE_TEST_SET", "real")
+	t.Setenv("UFILE_TEST_NEW", "")
+	os.Unsetenv("UFILE_TEST_NEW")
+	if err := WriteTextFile(filename, []string{"UFILE_TEST_SET=env",
+		"UFILE_TEST_NEW=env"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyDotEnv(filename); err != nil {
+		t.Fatal(err)
+	}
+	if value := os.Getenv("UFILE_TEST_SET"); value != "real" {
+		t.Errorf("expected %q got %q", "real", value)
+	}
+	if value := os.Getenv("UFILE_TEST_NEW"); value != "env" {
+		t.Errorf("expected %q got %q", "env", value)
+	}
+}
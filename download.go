@@ -0,0 +1,168 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned (wrapped) by [DownloadFile] when the
+// downloaded file's checksum isn't the expected one.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// DownloadOptions are used by [DownloadFile].
+//
+// Client is the HTTP client to use (or [http.DefaultClient] if nil). If
+// Checksum is nonempty it is the expected hex checksum of the whole file
+// using Algo. If Progress is not nil it is called as bytes are received
+// with the total being the file's size if known (or -1).
+type DownloadOptions struct {
+	Client   *http.Client
+	Checksum string
+	Algo     HashAlgo
+	Progress ProgressFunc
+}
+
+// DownloadFile downloads url to dst. The data is streamed to dst plus
+// ".part", and if that file already exists (e.g., from an interrupted
+// download) the download resumes from its end using an HTTP Range request
+// with an If-Range header (so that if the remote file has changed, or the
+// server doesn't support ranges, the download starts again). The remote
+// file's validator (its ETag or Last-Modified time) is kept in dst plus
+// ".part.etag" for this purpose; a .part file without one is downloaded
+// again from the start. Once complete the file is optionally verified
+// (see [DownloadOptions]) and then renamed to dst. If the checksum
+// doesn't match the .part file is removed and an error wrapping
+// [ErrChecksumMismatch] is returned. If ctx is cancelled the download
+// stops with the context's error and the .part file is kept for resuming.
+func DownloadFile(ctx context.Context, url, dst string,
+	opts DownloadOptions,
+) error {
+	part := dst + ".part"
+	var offset int64
+	validator := ""
+	if info, err := os.Stat(part); err == nil && info.Mode().IsRegular() {
+		if raw, err := os.ReadFile(part + ".etag"); err == nil {
+			offset = info.Size()
+			validator = string(raw)
+		}
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url,
+		nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		request.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+
+			"-")
+		request.Header.Set("If-Range", validator)
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	flags := os.O_WRONLY | os.O_CREATE
+	total := int64(-1)
+	switch {
+	case response.StatusCode == http.StatusPartialContent && offset > 0:
+		if !strings.HasPrefix(response.Header.Get("Content-Range"),
+			fmt.Sprintf("bytes %d-", offset)) {
+			return fmt.Errorf("download %q: unexpected Content-Range %q",
+				url, response.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+		if response.ContentLength >= 0 {
+			total = offset + response.ContentLength
+		}
+	case response.StatusCode == http.StatusRequestedRangeNotSatisfiable &&
+		offset > 0:
+		if response.Header.Get("Content-Range") ==
+			fmt.Sprintf("bytes */%d", offset) { // .part is complete
+			return finishDownload(part, dst, opts)
+		}
+		// .part is stale (e.g., longer than the remote file) so start again
+		removeDownloadPart(part)
+		response.Body.Close()
+		return DownloadFile(ctx, url, dst, opts)
+	case response.StatusCode == http.StatusOK:
+		flags |= os.O_TRUNC
+		offset = 0
+		total = response.ContentLength
+		if err = saveDownloadValidator(part, response.Header); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("download %q: %s", url, response.Status)
+	}
+	file, err := os.OpenFile(part, flags, modeDefault)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	track := newTracker(ctx, opts.Progress, total)
+	track.done = offset
+	if _, err = io.Copy(file, track.reader(response.Body, dst)); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return finishDownload(part, dst, opts)
+}
+
+// finishDownload verifies the downloaded part file if required and
+// renames it to dst.
+func finishDownload(part, dst string, opts DownloadOptions) error {
+	if opts.Checksum != "" {
+		sum, err := Checksum(part, opts.Algo)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(sum, opts.Checksum) {
+			removeDownloadPart(part)
+			return fmt.Errorf("%w for %q: expected %s got %s",
+				ErrChecksumMismatch, dst, opts.Checksum, sum)
+		}
+	}
+	if err := rename(part, dst); err != nil {
+		return err
+	}
+	_ = os.Remove(part + ".etag")
+	return nil
+}
+
+// saveDownloadValidator saves the response's strong ETag, or if it has
+// none, its Last-Modified time, for use with If-Range when resuming; if
+// it has neither any previously saved validator is removed.
+func saveDownloadValidator(part string, header http.Header) error {
+	validator := header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = header.Get("Last-Modified")
+	}
+	if validator == "" {
+		_ = os.Remove(part + ".etag")
+		return nil
+	}
+	return os.WriteFile(part+".etag", []byte(validator), modeDefault)
+}
+
+// removeDownloadPart removes the .part file and its validator.
+func removeDownloadPart(part string) {
+	_ = os.Remove(part)
+	_ = os.Remove(part + ".etag")
+}
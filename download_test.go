@@ -0,0 +1,105 @@
+package ufile
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_DownloadFile(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789", 10000))
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(
+		writer http.ResponseWriter, request *http.Request,
+	) {
+		if request.URL.Path == "/missing" {
+			http.NotFound(writer, request)
+			return
+		}
+		ranges = append(ranges, request.Header.Get("Range"))
+		writer.Header().Set("ETag", `"v1"`)
+		http.ServeContent(writer, request, "data.bin", time.Time{},
+			bytes.NewReader(data))
+	}))
+	defer server.Close()
+	hash := sha256.Sum256(data)
+	sum := hex.EncodeToString(hash[:])
+	dst := filepath.Join(t.TempDir(), "data.bin")
+	writePart := func(part []byte, etag string) {
+		if err := os.WriteFile(dst+".part", part, ModeURW); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst+".part.etag", []byte(etag),
+			ModeURW); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writePart(data[:12345], `"v1"`)
+	var done, total int64
+	err := DownloadFile(context.Background(), server.URL, dst,
+		DownloadOptions{Checksum: sum, Algo: SHA256,
+			Progress: func(d, t int64, _ string) { done, total = d, t }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=12345-" {
+		t.Errorf("expected a resumed download got %q", ranges)
+	}
+	if size := int64(len(data)); done != size || total != size {
+		t.Errorf("expected %d/%d got %d/%d", size, size, done, total)
+	}
+	if got, err := os.ReadFile(dst); err != nil || !bytes.Equal(got,
+		data) {
+		t.Errorf("downloaded data differs %v", err)
+	}
+	if PathExists(dst+".part") || PathExists(dst+".part.etag") {
+		t.Error("expected .part file to be renamed")
+	}
+	for _, part := range []struct {
+		data []byte
+		etag string
+	}{
+		{bytes.Repeat([]byte("x"), 100), `"v0"`},        // remote file changed
+		{append(bytes.Clone(data), "stale"...), `"v1"`}, // too long
+		{data, `"v1"`}, // complete
+	} {
+		writePart(part.data, part.etag)
+		err = DownloadFile(context.Background(), server.URL, dst,
+			DownloadOptions{Checksum: sum, Algo: SHA256})
+		if err != nil {
+			t.Errorf("%d bytes %s: %v", len(part.data), part.etag, err)
+		}
+		if got, _ := os.ReadFile(dst); !bytes.Equal(got, data) {
+			t.Errorf("%d bytes %s: downloaded data differs",
+				len(part.data), part.etag)
+		}
+	}
+	err = DownloadFile(context.Background(), server.URL, dst,
+		DownloadOptions{Checksum: strings.Repeat("0", 64), Algo: SHA256})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected %v got %v", ErrChecksumMismatch, err)
+	}
+	if PathExists(dst + ".part") {
+		t.Error("expected bad .part file to be removed")
+	}
+	err = DownloadFile(context.Background(), server.URL+"/missing", dst,
+		DownloadOptions{})
+	if err == nil || PathExists(dst+".part") {
+		t.Errorf("expected not found error got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = DownloadFile(ctx, server.URL, dst, DownloadOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"iter"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding identifies a text file's character encoding.
+type Encoding uint8
+
+const (
+	EncodingUTF8 Encoding = iota
+	EncodingUTF16LE
+	EncodingUTF16BE
+	EncodingLatin1
+	EncodingWindows1252
+)
+
+// String returns the Encoding's name, e.g., "UTF-16LE".
+func (me Encoding) String() string {
+	switch me {
+	case EncodingUTF16LE:
+		return "UTF-16LE"
+	case EncodingUTF16BE:
+		return "UTF-16BE"
+	case EncodingLatin1:
+		return "ISO-8859-1"
+	case EncodingWindows1252:
+		return "Windows-1252"
+	default:
+		return "UTF-8"
+	}
+}
+
+const encodingSampleSize = 64 * 1024
+
+// DetectEncoding returns the most likely encoding of the given file and a
+// confidence between 0.0 and 1.0. A BOM gives certainty; otherwise the
+// first 64 KB are examined for UTF-16 NUL patterns and UTF-8 validity,
+// falling back to Latin-1 or (if bytes 0x80-0x9F are present)
+// Windows-1252. See also [ReadLinesEncoded].
+func DetectEncoding(filename string) (Encoding, float64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return EncodingUTF8, 0, err
+	}
+	defer file.Close()
+	sample := make([]byte, encodingSampleSize)
+	n, err := io.ReadFull(file, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return EncodingUTF8, 0, err
+	}
+	encoding, confidence := detectEncoding(sample[:n], n < len(sample))
+	return encoding, confidence, nil
+}
+
+func detectEncoding(sample []byte, complete bool) (Encoding, float64) {
+	switch {
+	case bytes.HasPrefix(sample, utf8BOM):
+		return EncodingUTF8, 1
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE, 1
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE, 1
+	case len(sample) == 0:
+		return EncodingUTF8, 1
+	}
+	evenNuls, oddNuls := 0, 0
+	for i, c := range sample {
+		if c == 0 {
+			if i%2 == 0 {
+				evenNuls++
+			} else {
+				oddNuls++
+			}
+		}
+	}
+	pairs := float64(len(sample) / 2)
+	if pairs > 0 {
+		if float64(oddNuls)/pairs > 0.3 && oddNuls > 3*evenNuls {
+			return EncodingUTF16LE, 0.8
+		}
+		if float64(evenNuls)/pairs > 0.3 && evenNuls > 3*oddNuls {
+			return EncodingUTF16BE, 0.8
+		}
+	}
+	if !complete { // the sample may end mid-rune
+		for i := 1; i < utf8.UTFMax && i <= len(sample); i++ {
+			if utf8.RuneStart(sample[len(sample)-i]) {
+				if !utf8.FullRune(sample[len(sample)-i:]) {
+					sample = sample[:len(sample)-i]
+				}
+				break
+			}
+		}
+	}
+	if utf8.Valid(sample) {
+		for _, c := range sample {
+			if c >= utf8.RuneSelf {
+				return EncodingUTF8, 1 // valid non-ASCII UTF-8
+			}
+		}
+		return EncodingUTF8, 0.9 // 7-bit ASCII is valid in all of them
+	}
+	for _, c := range sample {
+		if 0x80 <= c && c <= 0x9F {
+			return EncodingWindows1252, 0.6
+		}
+	}
+	return EncodingLatin1, 0.6
+}
+
+// ReadLinesEncoded reads the given file which is in the given encoding and
+// returns an iterator of (line, error) for every line (converted to UTF-8)
+// with EOL (and any BOM) stripped off. See also [DetectEncoding] and
+// [ReadUtf8Lines].
+func ReadLinesEncoded(filename string, enc Encoding,
+) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		file, err := os.Open(filename)
+		if err != nil {
+			yield("", err) // failed to open file
+			return         // we cannot progress from here
+		}
+		defer file.Close()
+		yieldLines(bufio.NewReader(newDecodingReader(file, enc)), yield)
+	}
+}
+
+// decodingReader is an io.Reader that converts its source from the given
+// encoding to UTF-8.
+type decodingReader struct {
+	source  *bufio.Reader
+	enc     Encoding
+	pending []byte
+	err     error
+	started bool
+}
+
+func newDecodingReader(reader io.Reader, enc Encoding) *decodingReader {
+	return &decodingReader{source: bufio.NewReader(reader), enc: enc}
+}
+
+func (me *decodingReader) Read(p []byte) (int, error) {
+	if !me.started {
+		me.started = true
+		me.skipBOM()
+	}
+	for me.err == nil && len(me.pending) < len(p) {
+		var r rune
+		if r, me.err = me.readRune(); me.err == nil {
+			me.pending = utf8.AppendRune(me.pending, r)
+		}
+	}
+	if len(me.pending) == 0 {
+		return 0, me.err
+	}
+	n := copy(p, me.pending)
+	me.pending = me.pending[n:]
+	return n, nil
+}
+
+func (me *decodingReader) skipBOM() {
+	var bom []byte
+	switch me.enc {
+	case EncodingUTF8:
+		bom = utf8BOM
+	case EncodingUTF16LE:
+		bom = []byte{0xFF, 0xFE}
+	case EncodingUTF16BE:
+		bom = []byte{0xFE, 0xFF}
+	default:
+		return
+	}
+	if prefix, err := me.source.Peek(len(bom)); err == nil &&
+		bytes.Equal(prefix, bom) {
+		_, _ = me.source.Discard(len(bom))
+	}
+}
+
+func (me *decodingReader) readRune() (rune, error) {
+	switch me.enc {
+	case EncodingUTF16LE, EncodingUTF16BE:
+		unit, err := me.readUnit()
+		if err != nil {
+			return 0, err
+		}
+		r := rune(unit)
+		if utf16.IsSurrogate(r) {
+			next, err := me.source.Peek(2)
+			if err != nil {
+				return utf8.RuneError, nil
+			}
+			low := me.unit(next)
+			if decoded := utf16.DecodeRune(r, rune(low)); decoded !=
+				utf8.RuneError {
+				_, _ = me.source.Discard(2)
+				return decoded, nil
+			}
+			return utf8.RuneError, nil
+		}
+		return r, nil
+	case EncodingLatin1:
+		c, err := me.source.ReadByte()
+		return rune(c), err
+	case EncodingWindows1252:
+		c, err := me.source.ReadByte()
+		if 0x80 <= c && c <= 0x9F {
+			return windows1252[c-0x80], err
+		}
+		return rune(c), err
+	default:
+		r, _, err := me.source.ReadRune()
+		return r, err
+	}
+}
+
+func (me *decodingReader) readUnit() (uint16, error) {
+	var pair [2]byte
+	if _, err := io.ReadFull(me.source, pair[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return utf8.RuneError, nil // odd trailing byte
+		}
+		return 0, err
+	}
+	return me.unit(pair[:]), nil
+}
+
+func (me *decodingReader) unit(pair []byte) uint16 {
+	if me.enc == EncodingUTF16BE {
+		return uint16(pair[0])<<8 | uint16(pair[1])
+	}
+	return uint16(pair[1])<<8 | uint16(pair[0])
+}
+
+// windows1252 maps bytes 0x80-0x9F to runes; the rest match Latin-1.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+const textSampleSize = 8 * 1024
+
+// IsTextFile returns true if the given file appears to be text; otherwise
+// returns false. The first 8 KB are examined: a UTF-16 BOM means text, any
+// other NUL byte means binary, valid UTF-8 means text, and otherwise at
+// least 90% of the bytes must be printable ASCII, whitespace, or Latin-1.
+// See also [IsBinaryFile].
+func IsTextFile(filename string) (bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	sample := make([]byte, textSampleSize)
+	n, err := io.ReadFull(file, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return isText(sample[:n], n < len(sample)), nil
+}
+
+// IsBinaryFile returns true if the given file doesn't appear to be text;
+// otherwise returns false. See also [IsTextFile].
+func IsBinaryFile(filename string) (bool, error) {
+	isText, err := IsTextFile(filename)
+	return !isText, err
+}
+
+func isText(sample []byte, complete bool) bool {
+	if bytes.HasPrefix(sample, []byte{0xFF, 0xFE}) ||
+		bytes.HasPrefix(sample, []byte{0xFE, 0xFF}) {
+		return true // UTF-16 with BOM
+	}
+	if bytes.IndexByte(sample, 0) > -1 {
+		return false
+	}
+	if encoding, _ := detectEncoding(sample, complete); encoding ==
+		EncodingUTF8 {
+		return true
+	}
+	good := 0
+	for _, c := range sample {
+		if (0x20 <= c && c < 0x7F) || c >= 0xA0 || c == '\t' ||
+			c == '\n' || c == '\r' || c == '\f' || c == '\v' || c == 0x1B {
+			good++
+		}
+	}
+	return float64(good)/float64(len(sample)) >= 0.9
+}
@@ -0,0 +1,72 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_DetectEncoding_ReadLinesEncoded(t *testing.T) {
+	dir := t.TempDir()
+	texts := [][]byte{
+		[]byte("caf\xc3\xa9\r\n\xf0\x9f\x98\x80"),
+		[]byte("\xff\xfec\x00a\x00f\x00\xe9\x00\r\x00\n\x00=\xd8\x00\xde"),
+		[]byte("\x00c\x00a\x00f\x00\xe9\x00\n\xd8=\xde\x00"),
+		[]byte("caf\xe9\n\xe9"),
+		[]byte("caf\xe9\n\x80"),
+	}
+	encodings := []Encoding{EncodingUTF8, EncodingUTF16LE, EncodingUTF16BE,
+		EncodingLatin1, EncodingWindows1252}
+	expected := [][]string{{"café", "😀"}, {"café", "😀"}, {"café", "😀"},
+		{"café", "é"}, {"café", "€"}}
+	for i, text := range texts {
+		filename := filepath.Join(dir, "encoded.txt")
+		if err := os.WriteFile(filename, text, ModeURW); err != nil {
+			t.Fatal(err)
+		}
+		encoding, confidence, err := DetectEncoding(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoding != encodings[i] || confidence <= 0.5 {
+			t.Errorf("expected %s got %s (%.1f)", encodings[i], encoding,
+				confidence)
+		}
+		lines := []string{}
+		for line, err := range ReadLinesEncoded(filename, encoding) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, line)
+		}
+		if slices.Compare(lines, expected[i]) != 0 {
+			t.Errorf("%s: expected %q got %q", encoding, expected[i], lines)
+		}
+	}
+}
+
+func Test_IsTextFile(t *testing.T) {
+	dir := t.TempDir()
+	texts := [][]byte{[]byte("plain ASCII\n"), []byte("café ☺\n"),
+		[]byte("caf\xe9 Latin-1\n"), []byte("\xff\xfea\x00b\x00"),
+		[]byte("\x7fELF\x02\x01\x01\x00\x00\x00"),
+		[]byte("\x01\x02\x03\x04\x05\x06\x80\x81\x82"), {}}
+	expected := []bool{true, true, true, true, false, false, true}
+	for i, text := range texts {
+		filename := filepath.Join(dir, "sample.dat")
+		if err := os.WriteFile(filename, text, ModeURW); err != nil {
+			t.Fatal(err)
+		}
+		isText, err := IsTextFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isText != expected[i] {
+			t.Errorf("%q: expected %t got %t", text, expected[i], isText)
+		}
+		if isBinary, _ := IsBinaryFile(filename); isBinary == isText {
+			t.Errorf("%q: IsBinaryFile == IsTextFile", text)
+		}
+	}
+}
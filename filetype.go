@@ -0,0 +1,100 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+type magic struct {
+	offset    int
+	signature []byte
+	mime      string
+	ext       string
+}
+
+// magics are checked in order so more specific signatures come first.
+var magics = []magic{
+	{0, []byte("\x89PNG\r\n\x1a\n"), "image/png", ".png"},
+	{0, []byte("\xff\xd8\xff"), "image/jpeg", ".jpg"},
+	{0, []byte("GIF87a"), "image/gif", ".gif"},
+	{0, []byte("GIF89a"), "image/gif", ".gif"},
+	{0, []byte("BM"), "image/bmp", ".bmp"},
+	{0, []byte("II*\x00"), "image/tiff", ".tif"},
+	{0, []byte("MM\x00*"), "image/tiff", ".tif"},
+	{0, []byte("\x00\x00\x01\x00"), "image/vnd.microsoft.icon", ".ico"},
+	{0, []byte("%PDF-"), "application/pdf", ".pdf"},
+	{0, []byte("PK\x03\x04"), "application/zip", ".zip"},
+	{0, []byte("PK\x05\x06"), "application/zip", ".zip"},
+	{0, []byte("\x1f\x8b"), "application/gzip", ".gz"},
+	{0, []byte("BZh"), "application/x-bzip2", ".bz2"},
+	{0, []byte("\xfd7zXZ\x00"), "application/x-xz", ".xz"},
+	{0, []byte("\x28\xb5\x2f\xfd"), "application/zstd", ".zst"},
+	{0, []byte("7z\xbc\xaf\x27\x1c"), "application/x-7z-compressed", ".7z"},
+	{0, []byte("Rar!\x1a\x07"), "application/vnd.rar", ".rar"},
+	{257, []byte("ustar"), "application/x-tar", ".tar"},
+	{0, []byte("\x7fELF"), "application/x-elf", ""},
+	{0, []byte("MZ"), "application/vnd.microsoft.portable-executable",
+		".exe"},
+	{0, []byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary", ""},
+	{0, []byte("\xce\xfa\xed\xfe"), "application/x-mach-binary", ""},
+	{0, []byte("\xca\xfe\xba\xbe"), "application/java-vm", ".class"},
+	{0, []byte("\x00asm"), "application/wasm", ".wasm"},
+	{0, []byte("SQLite format 3\x00"), "application/vnd.sqlite3",
+		".sqlite"},
+	{0, []byte("ID3"), "audio/mpeg", ".mp3"},
+	{0, []byte("fLaC"), "audio/flac", ".flac"},
+	{0, []byte("OggS"), "audio/ogg", ".ogg"},
+	{4, []byte("ftyp"), "video/mp4", ".mp4"},
+	{0, []byte("\x1a\x45\xdf\xa3"), "video/x-matroska", ".mkv"},
+	{0, []byte("wOFF"), "font/woff", ".woff"},
+	{0, []byte("wOF2"), "font/woff2", ".woff2"},
+	{0, []byte("%!PS"), "application/postscript", ".ps"},
+	{0, []byte("{\\rtf"), "application/rtf", ".rtf"},
+}
+
+// DetectFileType returns the MIME type and conventional extension (which
+// may be "" if there isn't one, e.g., for ELF executables) of the given
+// file by examining its leading bytes rather than its name. Unrecognized
+// files are reported as "text/plain" and ".txt" if they look like text
+// (see [IsTextFile]), or otherwise as "application/octet-stream" and "".
+func DetectFileType(filename string) (mime string, ext string, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+	header := make([]byte, 512)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", "", err
+	}
+	mime, ext = detectFileType(header[:n])
+	return mime, ext, nil
+}
+
+func detectFileType(header []byte) (string, string) {
+	if len(header) >= 12 && bytes.HasPrefix(header, []byte("RIFF")) {
+		switch string(header[8:12]) {
+		case "WEBP":
+			return "image/webp", ".webp"
+		case "WAVE":
+			return "audio/wav", ".wav"
+		case "AVI ":
+			return "video/x-msvideo", ".avi"
+		}
+	}
+	for _, m := range magics {
+		if len(header) >= m.offset+len(m.signature) &&
+			bytes.Equal(header[m.offset:m.offset+len(m.signature)],
+				m.signature) {
+			return m.mime, m.ext
+		}
+	}
+	if isText(header, len(header) < 512) {
+		return "text/plain", ".txt"
+	}
+	return "application/octet-stream", ""
+}
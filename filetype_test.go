@@ -0,0 +1,37 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_DetectFileType(t *testing.T) {
+	dir := t.TempDir()
+	tar := make([]byte, 512)
+	copy(tar[257:], "ustar\x0000")
+	headers := [][]byte{[]byte("\x89PNG\r\n\x1a\n\x00\x00"),
+		[]byte("%PDF-1.7\n"), []byte("PK\x03\x04\x14\x00"),
+		[]byte("\x1f\x8b\x08\x00"), []byte("\x7fELF\x02\x01\x01"),
+		[]byte("MZ\x90\x00"), []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), tar,
+		[]byte("just some text\n"), []byte("\x00\x01\x02\x03")}
+	expected := [][2]string{{"image/png", ".png"},
+		{"application/pdf", ".pdf"}, {"application/zip", ".zip"},
+		{"application/gzip", ".gz"}, {"application/x-elf", ""},
+		{"application/vnd.microsoft.portable-executable", ".exe"},
+		{"image/webp", ".webp"}, {"application/x-tar", ".tar"},
+		{"text/plain", ".txt"}, {"application/octet-stream", ""}}
+	for i, header := range headers {
+		filename := filepath.Join(dir, "unknown.bin")
+		if err := os.WriteFile(filename, header, ModeURW); err != nil {
+			t.Fatal(err)
+		}
+		mime, ext, err := DetectFileType(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mime != expected[i][0] || ext != expected[i][1] {
+			t.Errorf("expected %v got %q %q", expected[i], mime, ext)
+		}
+	}
+}
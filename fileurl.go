@@ -0,0 +1,79 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PathToFileURL returns the given path (made absolute if necessary) as a
+// file URL with special characters percent-encoded, e.g.,
+// `/home/mark/my file.txt` → `file:///home/mark/my%20file.txt`. On Windows
+// drive paths become, e.g., `file:///C:/Users/mark/a.txt`, and UNC paths,
+// e.g., `\\server\share\a.txt`, become `file://server/share/a.txt`.
+// See also [FileURLToPath].
+func PathToFileURL(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		var err error
+		if path, err = filepath.Abs(path); err != nil {
+			return "", err
+		}
+	}
+	return pathToFileURL(path, runtime.GOOS == "windows"), nil
+}
+
+func pathToFileURL(path string, windows bool) string {
+	fileURL := url.URL{Scheme: "file", Path: path}
+	if windows {
+		path = strings.ReplaceAll(path, `\`, "/")
+		if strings.HasPrefix(path, "//") { // UNC
+			host, rest, _ := strings.Cut(path[2:], "/")
+			fileURL.Host = host
+			fileURL.Path = "/" + rest
+		} else {
+			fileURL.Path = "/" + path
+		}
+	}
+	return fileURL.String()
+}
+
+// FileURLToPath returns the local path for the given file URL (decoding
+// any percent-encoded characters). A host of "" or "localhost" means a
+// local file. On Windows, e.g., `file:///C:/a.txt` becomes `C:\a.txt`,
+// and a URL with a host becomes a UNC path; elsewhere a URL with a host
+// is an error. See also [PathToFileURL].
+func FileURLToPath(fileURL string) (string, error) {
+	return fileURLToPath(fileURL, runtime.GOOS == "windows")
+}
+
+func fileURLToPath(fileURL string, windows bool) (string, error) {
+	parsed, err := url.Parse(fileURL)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(parsed.Scheme, "file") {
+		return "", fmt.Errorf("not a file URL: %q", fileURL)
+	}
+	path := parsed.Path
+	host := parsed.Host
+	if strings.EqualFold(host, "localhost") {
+		host = ""
+	}
+	if !windows {
+		if host != "" {
+			return "", fmt.Errorf("file URL with remote host: %q", fileURL)
+		}
+		return path, nil
+	}
+	if host != "" {
+		return `\\` + host + strings.ReplaceAll(path, "/", `\`), nil
+	}
+	if len(path) >= 3 && path[0] == '/' && volumeName(path[1:]) != "" {
+		path = path[1:] // drop the / before the drive letter
+	}
+	return strings.ReplaceAll(path, "/", `\`), nil
+}
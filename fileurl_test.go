@@ -0,0 +1,45 @@
+package ufile
+
+import "testing"
+
+func Test_FileURL(t *testing.T) {
+	for _, item := range []struct {
+		path    string
+		url     string
+		windows bool
+	}{
+		{"/home/mark/my file#1.txt",
+			"file:///home/mark/my%20file%231.txt", false},
+		{"/", "file:///", false},
+		{`C:\Users\mark\a b.txt`, "file:///C:/Users/mark/a%20b.txt", true},
+		{`\\server\share\dir\a.txt`, "file://server/share/dir/a.txt",
+			true},
+	} {
+		fileURL := pathToFileURL(item.path, item.windows)
+		if fileURL != item.url {
+			t.Errorf("expected %q got %q", item.url, fileURL)
+		}
+		path, err := fileURLToPath(fileURL, item.windows)
+		if err != nil || path != item.path {
+			t.Errorf("expected %q got %q %v", item.path, path, err)
+		}
+	}
+	path, err := fileURLToPath("file://localhost/etc/hosts", false)
+	if err != nil || path != "/etc/hosts" {
+		t.Errorf("expected /etc/hosts got %q %v", path, err)
+	}
+	if _, err = fileURLToPath("file://server/x", false); err == nil {
+		t.Error("expected error for remote host")
+	}
+	if _, err = FileURLToPath("https://example.com/x"); err == nil {
+		t.Error("expected error for non-file URL")
+	}
+	fileURL, err := PathToFileURL("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path, err = FileURLToPath(fileURL); err != nil ||
+		path != AbsPath("a.txt") {
+		t.Errorf("expected %q got %q %v", AbsPath("a.txt"), path, err)
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EntryType is a bit set of the kinds of entry a [Query] matches. (An
+// [Entry]'s own kind is given by [Entry.Type].) TypeFile is for regular
+// files only; named pipes, sockets, and devices have their own types.
+type EntryType uint8
+
+const (
+	TypeFile EntryType = 1 << iota
+	TypeDir
+	TypeSymlink
+	TypeFIFO
+	TypeSocket
+	TypeDevice
+	TypeAny EntryType = 0
+)
+
+func entryTypeOf(mode fs.FileMode) EntryType {
+	switch {
+	case mode&fs.ModeSymlink != 0:
+		return TypeSymlink
+	case mode.IsDir():
+		return TypeDir
+	case mode&fs.ModeNamedPipe != 0:
+		return TypeFIFO
+	case mode&fs.ModeSocket != 0:
+		return TypeSocket
+	case mode&fs.ModeDevice != 0:
+		return TypeDevice
+	default:
+		return TypeFile
+	}
+}
+
+// Type returns the entry's kind, e.g., [TypeFile] or [TypeFIFO], or
+// [TypeAny] if the entry has no file info (e.g., for an error entry).
+func (me Entry) Type() EntryType {
+	if me.FileInfo == nil {
+		return TypeAny
+	}
+	return entryTypeOf(me.Mode())
+}
+
+// Query holds the criteria used by [Find]. The zero Query matches every
+// entry at every depth. Use [NewQuery] and the builder methods to create a
+// query, e.g.,
+//
+//	q := ufile.NewQuery().Name("*.go").MinSize(1024).Type(ufile.TypeFile)
+type Query struct {
+	glob        string
+	rx          *regexp.Regexp
+	minSize     int64
+	maxSize     int64
+	hasMaxSize  bool
+	after       time.Time
+	before      time.Time
+	types       EntryType
+	minDepth    int
+	maxDepth    int
+	hasMaxDepth bool
+	normalize   bool
+	ignore      *IgnoreMatcher
+}
+
+// NewQuery returns a Query that matches every entry.
+func NewQuery() Query { return Query{} }
+
+// Name returns a copy of the query that only matches entries whose names
+// match the given glob (see [filepath.Match]).
+func (me Query) Name(glob string) Query {
+	me.glob = glob
+	return me
+}
+
+// Regexp returns a copy of the query that only matches entries whose names
+// match the given regexp.
+func (me Query) Regexp(rx *regexp.Regexp) Query {
+	me.rx = rx
+	return me
+}
+
+// MinSize returns a copy of the query that only matches entries of at
+// least the given size in bytes.
+func (me Query) MinSize(size int64) Query {
+	me.minSize = size
+	return me
+}
+
+// MaxSize returns a copy of the query that only matches entries of at
+// most the given size in bytes.
+func (me Query) MaxSize(size int64) Query {
+	me.maxSize = size
+	me.hasMaxSize = true
+	return me
+}
+
+// ModifiedAfter returns a copy of the query that only matches entries
+// modified after the given time.
+func (me Query) ModifiedAfter(t time.Time) Query {
+	me.after = t
+	return me
+}
+
+// ModifiedBefore returns a copy of the query that only matches entries
+// modified before the given time.
+func (me Query) ModifiedBefore(t time.Time) Query {
+	me.before = t
+	return me
+}
+
+// Type returns a copy of the query that only matches entries of the given
+// type(s), e.g., `TypeFile | TypeSymlink`.
+func (me Query) Type(types EntryType) Query {
+	me.types = types
+	return me
+}
+
+// MinDepth returns a copy of the query that only matches entries at least
+// the given depth below the root (the root itself is at depth 0).
+func (me Query) MinDepth(depth int) Query {
+	me.minDepth = depth
+	return me
+}
+
+// MaxDepth returns a copy of the query that doesn't descend more than the
+// given depth below the root (the root itself is at depth 0).
+func (me Query) MaxDepth(depth int) Query {
+	me.maxDepth = depth
+	me.hasMaxDepth = true
+	return me
+}
+
+// NormalizeUnicode returns a copy of the query that normalizes entry
+// names and the name glob to NFC before matching them (see
+// [NormalizePathUnicode]), so that a name matches regardless of how it is
+// normalized. (The regexp, if any, should be written to match NFC.)
+func (me Query) NormalizeUnicode() Query {
+	me.normalize = true
+	return me
+}
+
+// Ignore returns a copy of the query that skips entries (and doesn't
+// descend into folders) that the given matcher ignores, matching paths
+// relative to the root (see [IgnoreMatcher]).
+func (me Query) Ignore(matcher *IgnoreMatcher) Query {
+	me.ignore = matcher
+	return me
+}
+
+// Matches returns true if the given entry at the given depth satisfies
+// the query; otherwise returns false.
+func (me Query) Matches(entry Entry, depth int) bool {
+	if depth < me.minDepth || (me.hasMaxDepth && depth > me.maxDepth) {
+		return false
+	}
+	if me.types != TypeAny && me.types&entry.Type() == 0 {
+		return false
+	}
+	name := entry.Name()
+	glob := me.glob
+	if me.normalize {
+		name = NormalizePathUnicode(name, NFC)
+		glob = NormalizePathUnicode(glob, NFC)
+	}
+	if glob != "" {
+		if matched, err := filepath.Match(glob, name); err != nil ||
+			!matched {
+			return false
+		}
+	}
+	if me.rx != nil && !me.rx.MatchString(name) {
+		return false
+	}
+	size := entry.Size()
+	if size < me.minSize || (me.hasMaxSize && size > me.maxSize) {
+		return false
+	}
+	modTime := entry.ModTime()
+	if !me.after.IsZero() && !modTime.After(me.after) {
+		return false
+	}
+	if !me.before.IsZero() && !modTime.Before(me.before) {
+		return false
+	}
+	return true
+}
+
+// Find walks the tree rooted at root (including root itself) and returns
+// an iterator of (entry, error) for every entry that matches the query.
+// Symlinks are not followed. An error for a particular path is yielded
+// with an Entry containing just that path, and the walk continues.
+func Find(root string, q Query) iter.Seq2[Entry, error] {
+	return find(filepath.Clean(root), q, os.PathSeparator, filepath.WalkDir)
+}
+
+// find implements [Find] and [FindFS] using the given path separator and
+// walk function.
+func find(root string, q Query, sep byte,
+	walk func(root string, fn fs.WalkDirFunc) error,
+) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		_ = walk(root, func(path string, dirEntry fs.DirEntry, err error,
+		) error {
+			if err != nil {
+				if !yield(Entry{Path: path}, err) {
+					return filepath.SkipAll
+				}
+				return nil
+			}
+			if path != root && q.ignore.Match(filepath.ToSlash(
+				relPath(root, path, sep)), dirEntry.IsDir()) {
+				if dirEntry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			depth := pathDepth(root, path, sep)
+			info, err := dirEntry.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil // removed during the walk
+				}
+				if !yield(Entry{Path: path}, err) {
+					return filepath.SkipAll
+				}
+				return nil
+			}
+			entry := Entry{path, info}
+			if q.Matches(entry, depth) && !yield(entry, nil) {
+				return filepath.SkipAll
+			}
+			if dirEntry.IsDir() && q.hasMaxDepth && depth >= q.maxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+}
+
+// pathDepth returns how many sep-separated components path has below
+// root, which path must start with (unless root is ".").
+func pathDepth(root, path string, sep byte) int {
+	if path == root {
+		return 0
+	}
+	return strings.Count(relPath(root, path, sep), string(sep)) + 1
+}
+
+// relPath returns path relative to root, which path must start with
+// (unless root is "."), using sep as the separator.
+func relPath(root, path string, sep byte) string {
+	if root == "." {
+		return path
+	}
+	return strings.TrimLeft(path[len(root):], string(sep))
+}
+
+// WalkParallel walks the tree rooted at root (including root itself)
+// calling fn for every entry using up to workers goroutines (or
+// GOMAXPROCS if workers < 1), so fn must be safe for concurrent use and
+// entries are processed in no particular order. Symlinks are not
+// followed. The walk stops at the first error (from the walk or from fn)
+// which is returned. See also [WalkParallelIgnore].
+func WalkParallel(root string, workers int, fn func(Entry) error) error {
+	return WalkParallelIgnore(root, workers, nil, fn)
+}
+
+// WalkParallelIgnore works like [WalkParallel] but skips entries that
+// ignore ignores (see [IgnoreMatcher]), without descending into ignored
+// folders. Paths are matched relative to root, which is never skipped.
+func WalkParallelIgnore(root string, workers int, ignore *IgnoreMatcher,
+	fn func(Entry) error,
+) error {
+	entries := make(chan Entry)
+	stop := make(chan struct{})
+	var once sync.Once
+	var fnErr error
+	var wg sync.WaitGroup
+	for range workerCount(workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range entries {
+				if err := fn(entry); err != nil {
+					once.Do(func() {
+						fnErr = err
+						close(stop)
+					})
+				}
+			}
+		}()
+	}
+	err := filepath.WalkDir(root, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if path != root && ignore != nil {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			if ignore.Match(filepath.ToSlash(rel), dirEntry.IsDir()) {
+				if dirEntry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // removed during the walk
+			}
+			return err
+		}
+		select {
+		case entries <- Entry{path, info}:
+			return nil
+		case <-stop:
+			return filepath.SkipAll
+		}
+	})
+	close(entries)
+	wg.Wait()
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+func workerCount(workers int) int {
+	if workers < 1 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+// parallelOrdered calls work for every index from 0 to n-1 using up to
+// workers goroutines (or GOMAXPROCS if workers < 1) and calls emit with
+// each result in index order, stopping early if emit returns false.
+func parallelOrdered[T any](n, workers int, work func(int) T,
+	emit func(T) bool,
+) {
+	results := make([]chan T, n)
+	for i := range results {
+		results[i] = make(chan T, 1)
+	}
+	limit := make(chan struct{}, workerCount(workers))
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := range n {
+			select {
+			case limit <- struct{}{}:
+			case <-done:
+				return
+			}
+			go func() {
+				results[i] <- work(i)
+				<-limit
+			}()
+		}
+	}()
+	for _, result := range results {
+		if !emit(<-result) {
+			return
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package ufile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Find(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.go", "b.txt", "sub/c.go",
+		"sub/deep/d.go", "sub/big.go"} {
+		filename := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		size := 10
+		if filepath.Base(name) == "big.go" {
+			size = 2000
+		}
+		if err := os.WriteFile(filename, make([]byte, size),
+			ModeURW); err != nil {
+			t.Fatal(err)
+		}
+	}
+	find := func(q Query) []string {
+		names := []string{}
+		for entry, err := range Find(root, q) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			rel, _ := filepath.Rel(root, entry.Path)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		slices.Sort(names)
+		return names
+	}
+	check := func(q Query, expected ...string) {
+		t.Helper()
+		if got := find(q); slices.Compare(got, expected) != 0 {
+			t.Errorf("expected %q got %q", expected, got)
+		}
+	}
+	check(NewQuery().Name("*.go"), "a.go", "sub/big.go", "sub/c.go",
+		"sub/deep/d.go")
+	check(NewQuery().Name("*.go").MaxDepth(2), "a.go", "sub/big.go",
+		"sub/c.go")
+	check(NewQuery().Type(TypeDir).MinDepth(1), "sub", "sub/deep")
+	check(NewQuery().Type(TypeFile).MinSize(1000), "sub/big.go")
+	check(NewQuery().Type(TypeFile).MaxSize(1000).MinDepth(2), "sub/c.go",
+		"sub/deep/d.go")
+	check(NewQuery().Regexp(regexp.MustCompile(`^[ab]\.`)), "a.go",
+		"b.txt")
+	check(NewQuery().Type(TypeFile).ModifiedAfter(
+		time.Now().Add(time.Hour)))
+	check(NewQuery().Type(TypeFile).ModifiedBefore(
+		time.Now().Add(time.Hour)).Name("*.txt"), "b.txt")
+}
+
+func Test_WalkParallel(t *testing.T) {
+	root := t.TempDir()
+	makeTestTree(t, root, map[string]string{"a.txt": "alpha",
+		"sub/b.txt": "beta", "sub/deeper/c.txt": "gamma"})
+	var mutex sync.Mutex
+	var names []string
+	err := WalkParallel(root, 3, func(entry Entry) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		names = append(names, entry.Name())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(names)
+	expected := []string{"a.txt", "b.txt", "c.txt", "deeper",
+		filepath.Base(root), "sub"}
+	slices.Sort(expected)
+	if !slices.Equal(names, expected) {
+		t.Errorf("expected %v got %v", expected, names)
+	}
+	stop := errors.New("stop")
+	err = WalkParallel(root, 2, func(entry Entry) error {
+		if entry.Name() == "b.txt" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("expected %v got %v", stop, err)
+	}
+	if err = WalkParallel(filepath.Join(root, "missing"), 0,
+		func(Entry) error { return nil }); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FilesystemInfo describes a filesystem as returned by [FSInfo].
+//
+// Type is the filesystem's type as named by the platform, e.g., "ext4",
+// "btrfs", "tmpfs", "apfs", "NTFS", or "" if unknown. MaxNameLength is the
+// maximum length of a file name in bytes (or UTF-16 code units on
+// Windows). If Probed is true CaseSensitive, Symlinks, and Hardlinks were
+// found by creating temporary files; otherwise (e.g., for a read-only
+// folder) they are the platform's or filesystem type's usual values.
+// ReadOnly is true if the filesystem is mounted read-only and Network is
+// true if it is a network filesystem (see [IsReadOnly] and
+// [IsNetworkPath]).
+type FilesystemInfo struct {
+	Type          string
+	CaseSensitive bool
+	MaxNameLength int
+	Symlinks      bool
+	Hardlinks     bool
+	Probed        bool
+	ReadOnly      bool
+	Network       bool
+}
+
+// FSInfo returns information about the filesystem that path (a file or
+// folder) is on, so that higher-level code can choose strategies that
+// suit it. Case sensitivity and symlink and hard link support are probed
+// by creating (and then removing) temporary files in path's folder if it
+// is writable.
+func FSInfo(path string) (FilesystemInfo, error) {
+	dir, fsInfo, err := fsInfo(path)
+	if err == nil && !fsInfo.ReadOnly {
+		probeFS(dir, &fsInfo)
+	}
+	return fsInfo, err
+}
+
+// IsReadOnly returns true if path (a file or folder) is on a filesystem
+// that is mounted read-only; otherwise returns false. (Permissions aren't
+// considered.) See also [FSInfo].
+func IsReadOnly(path string) (bool, error) {
+	_, fsInfo, err := fsInfo(path)
+	return fsInfo.ReadOnly, err
+}
+
+// IsNetworkPath returns true if path (a file or folder) is on a network
+// filesystem (e.g., NFS or SMB, or a mapped network drive or UNC path on
+// Windows) or is in a cloud-sync folder (e.g., Dropbox, OneDrive, Google
+// Drive, or iCloud Drive) where writes and watching may be slow or
+// unreliable; otherwise returns false. See also [FSInfo].
+func IsNetworkPath(path string) (bool, error) {
+	_, fsInfo, err := fsInfo(path)
+	return fsInfo.Network || isCloudSyncPath(AbsPath(path)), err
+}
+
+// fsInfo returns path's folder (or path itself if it is a folder) and its
+// filesystem's information (without probing).
+func fsInfo(path string) (string, FilesystemInfo, error) {
+	var fsInfo FilesystemInfo
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fsInfo, err
+	}
+	dir := path
+	if !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	return dir, fsInfo, fillFSInfo(dir, &fsInfo)
+}
+
+// isCloudSyncPath returns true if the absolute path is in one of the
+// usual cloud-sync folders; otherwise returns false.
+func isCloudSyncPath(path string) bool {
+	path = filepath.ToSlash(path) + "/"
+	for _, folder := range []string{"/Dropbox/", "/OneDrive/",
+		"/OneDrive - ", "/Google Drive/", "/My Drive/", "/iCloud Drive/",
+		"/iCloudDrive/", "/Library/Mobile Documents/",
+		"/Library/CloudStorage/"} {
+		if strings.Contains(path, folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeFS sets fsInfo's CaseSensitive, Symlinks, and Hardlinks fields by
+// experiment if possible, in which case Probed is set to true.
+func probeFS(dir string, fsInfo *FilesystemInfo) {
+	probe, err := os.MkdirTemp(dir, ".fsprobe-*")
+	if err != nil {
+		return // e.g., read-only so keep the defaults
+	}
+	defer os.RemoveAll(probe)
+	name := filepath.Join(probe, "Probe")
+	if err = os.WriteFile(name, nil, modeDefault); err != nil {
+		return
+	}
+	fsInfo.Probed = true
+	_, err = os.Lstat(filepath.Join(probe, strings.ToLower("Probe")))
+	fsInfo.CaseSensitive = err != nil
+	fsInfo.Symlinks = os.Symlink(name, filepath.Join(probe, "s")) == nil
+	fsInfo.Hardlinks = os.Link(name, filepath.Join(probe, "h")) == nil
+}
@@ -0,0 +1,23 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// fillFSInfo sets fsInfo's Type and MaxNameLength and its defaults.
+func fillFSInfo(dir string, fsInfo *FilesystemInfo) error {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(dir, &stat); err != nil {
+		return err
+	}
+	fsInfo.Type = unix.ByteSliceToString(stat.Fstypename[:])
+	fsInfo.MaxNameLength = 255
+	fsInfo.ReadOnly = stat.Flags&unix.MNT_RDONLY != 0
+	fsInfo.Network = stat.Flags&unix.MNT_LOCAL == 0
+	fsInfo.CaseSensitive = false // the default for APFS and HFS+
+	fsInfo.Symlinks = true
+	fsInfo.Hardlinks = true
+	return nil
+}
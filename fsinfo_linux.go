@@ -0,0 +1,64 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// linuxFSTypes maps statfs(2) magic numbers to filesystem type names.
+var linuxFSTypes = map[uint32]string{
+	unix.AFS_SUPER_MAGIC:       "afs",
+	unix.BCACHEFS_SUPER_MAGIC:  "bcachefs",
+	unix.BTRFS_SUPER_MAGIC:     "btrfs",
+	unix.CEPH_SUPER_MAGIC:      "ceph",
+	unix.CRAMFS_MAGIC:          "cramfs",
+	unix.ECRYPTFS_SUPER_MAGIC:  "ecryptfs",
+	unix.EXFAT_SUPER_MAGIC:     "exfat",
+	unix.EXT4_SUPER_MAGIC:      "ext4", // also ext2 and ext3
+	unix.F2FS_SUPER_MAGIC:      "f2fs",
+	unix.FUSE_SUPER_MAGIC:      "fuse",
+	unix.ISOFS_SUPER_MAGIC:     "iso9660",
+	unix.MSDOS_SUPER_MAGIC:     "vfat",
+	unix.NFS_SUPER_MAGIC:       "nfs",
+	unix.OVERLAYFS_SUPER_MAGIC: "overlay",
+	unix.PROC_SUPER_MAGIC:      "proc",
+	unix.RAMFS_MAGIC:           "ramfs",
+	unix.SQUASHFS_MAGIC:        "squashfs",
+	unix.SYSFS_MAGIC:           "sysfs",
+	unix.TMPFS_MAGIC:           "tmpfs",
+	unix.V9FS_MAGIC:            "9p",
+	unix.XFS_SUPER_MAGIC:       "xfs",
+	0x2fc12fc1:                 "zfs",
+	0x5346544e:                 "ntfs",
+	0x7366746e:                 "ntfs3",
+	0xff534d42:                 "cifs",
+	0xfe534d42:                 "smb2",
+}
+
+// fillFSInfo sets fsInfo's Type and MaxNameLength and its defaults.
+func fillFSInfo(dir string, fsInfo *FilesystemInfo) error {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(dir, &stat); err != nil {
+		return err
+	}
+	fsInfo.Type = linuxFSTypes[uint32(stat.Type)]
+	fsInfo.MaxNameLength = int(stat.Namelen)
+	fsInfo.ReadOnly = int64(stat.Flags)&unix.ST_RDONLY != 0
+	switch fsInfo.Type {
+	case "nfs", "cifs", "smb2", "afs", "ceph", "9p":
+		fsInfo.Network = true
+	}
+	switch fsInfo.Type {
+	case "vfat", "exfat":
+		fsInfo.CaseSensitive = false
+	case "ntfs", "ntfs3", "cifs", "smb2":
+		fsInfo.CaseSensitive = false
+		fsInfo.Hardlinks = true
+	default:
+		fsInfo.CaseSensitive = true
+		fsInfo.Symlinks = true
+		fsInfo.Hardlinks = true
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build !linux && !darwin && !windows
+
+package ufile
+
+// fillFSInfo sets fsInfo's defaults since the filesystem type can't be
+// determined on this platform.
+func fillFSInfo(dir string, fsInfo *FilesystemInfo) error {
+	fsInfo.MaxNameLength = 255
+	fsInfo.CaseSensitive = true
+	fsInfo.Symlinks = true
+	fsInfo.Hardlinks = true
+	return nil
+}
@@ -0,0 +1,49 @@
+package ufile
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_FSInfo(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"a.txt": "alpha"})
+	info, err := FSInfo(filepath.Join(dir, "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Probed || info.MaxNameLength < 1 {
+		t.Errorf("expected probed info got %+v", info)
+	}
+	if runtime.GOOS == "linux" && info.Type == "" {
+		t.Errorf("expected a filesystem type got %+v", info)
+	}
+	if tree, _ := TreeString(dir); tree != ".\n└── a.txt\n" {
+		t.Errorf("expected probe files to be removed got\n%s", tree)
+	}
+	if _, err = FSInfo(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing path")
+	}
+}
+
+func Test_IsReadOnlyIsNetworkPath(t *testing.T) {
+	dir := t.TempDir()
+	if readOnly, err := IsReadOnly(dir); err != nil || readOnly {
+		t.Errorf("expected writable got %t %v", readOnly, err)
+	}
+	if network, err := IsNetworkPath(dir); err != nil || network {
+		t.Errorf("expected local got %t %v", network, err)
+	}
+	if _, err := IsReadOnly(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing path")
+	}
+	synced := filepath.Join(dir, "Dropbox", "notes")
+	makeTestTree(t, dir, map[string]string{"Dropbox/notes/": ""})
+	if network, err := IsNetworkPath(synced); err != nil || !network {
+		t.Errorf("expected cloud-sync got %t %v", network, err)
+	}
+	if isCloudSyncPath("/home/me/Dropboxes/x") {
+		t.Error("expected not cloud-sync")
+	}
+}
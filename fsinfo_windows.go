@@ -0,0 +1,58 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// fillFSInfo sets fsInfo's Type and MaxNameLength and its defaults using
+// the volume's information.
+func fillFSInfo(dir string, fsInfo *FilesystemInfo) error {
+	root, flags, err := volumeInfo(dir, fsInfo)
+	if err != nil {
+		return err
+	}
+	fsInfo.ReadOnly = flags&windows.FILE_READ_ONLY_VOLUME != 0
+	if rootp, err := windows.UTF16PtrFromString(root); err == nil {
+		fsInfo.Network = windows.GetDriveType(rootp) ==
+			windows.DRIVE_REMOTE
+	}
+	if strings.HasPrefix(root, `\\`) &&
+		!strings.HasPrefix(root, `\\?\`) ||
+		strings.HasPrefix(root, `\\?\UNC\`) {
+		fsInfo.Network = true
+	}
+	fsInfo.CaseSensitive = flags&windows.FILE_CASE_SENSITIVE_SEARCH != 0
+	fsInfo.Symlinks = flags&windows.FILE_SUPPORTS_REPARSE_POINTS != 0
+	fsInfo.Hardlinks = flags&windows.FILE_SUPPORTS_HARD_LINKS != 0
+	return nil
+}
+
+// volumeInfo returns the root of the volume that dir is on and the
+// volume's flags, and sets fsInfo's Type and MaxNameLength.
+func volumeInfo(dir string, fsInfo *FilesystemInfo) (string, uint32,
+	error,
+) {
+	dirp, err := windows.UTF16PtrFromString(LongPath(dir))
+	if err != nil {
+		return "", 0, err
+	}
+	rootBuffer := make([]uint16, windows.MAX_LONG_PATH)
+	if err = windows.GetVolumePathName(dirp, &rootBuffer[0],
+		uint32(len(rootBuffer))); err != nil {
+		return "", 0, err
+	}
+	var maxLength, flags uint32
+	nameBuffer := make([]uint16, windows.MAX_PATH+1)
+	if err = windows.GetVolumeInformation(&rootBuffer[0], nil, 0, nil,
+		&maxLength, &flags, &nameBuffer[0],
+		uint32(len(nameBuffer))); err != nil {
+		return "", 0, err
+	}
+	fsInfo.Type = windows.UTF16ToString(nameBuffer)
+	fsInfo.MaxNameLength = int(maxLength)
+	return windows.UTF16ToString(rootBuffer), flags, nil
+}
@@ -0,0 +1,45 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// SyncFile flushes the given file's data and metadata to stable storage
+// (using fsync(2), or F_FULLFSYNC on macOS). See also [SyncDir].
+func SyncFile(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// SyncDir flushes the given folder's entries to stable storage, so that
+// files that have been created, renamed, or removed in it are guaranteed
+// to stay that way after a crash. It does nothing on Windows (which
+// doesn't support syncing folders), and nothing on filesystems that
+// don't support it. See also [SyncFile].
+func SyncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = file.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) &&
+		!errors.Is(err, errors.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
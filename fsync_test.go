@@ -0,0 +1,20 @@
+package ufile
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func Test_SyncFileDir(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"a.txt": "alpha"})
+	if err := SyncFile(filepath.Join(dir, "a.txt")); err != nil {
+		t.Error(err)
+	}
+	if err := SyncDir(dir); err != nil {
+		t.Error(err)
+	}
+	if err := SyncFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"io/fs"
+	"iter"
+	"path"
+)
+
+// ReadTextFileFS works like [ReadTextFile] but reads the named file from
+// fsys (e.g., an [embed.FS] or [testing/fstest.MapFS]).
+func ReadTextFileFS(fsys fs.FS, name string) ([]string, error) {
+	raw, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if raw, err = gunzipRaw(name, raw); err != nil {
+		return nil, err
+	}
+	return rawLines(raw), nil
+}
+
+// ReadUtf8LinesFS works like [ReadUtf8Lines] but reads the named file
+// from fsys.
+func ReadUtf8LinesFS(fsys fs.FS, name string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		file, err := fsys.Open(name)
+		if err != nil {
+			yield("", err) // failed to open file
+			return         // we cannot progress from here
+		}
+		defer file.Close()
+		yieldLines(bufio.NewReader(file), yield)
+	}
+}
+
+// FileExistsFS returns true if the named path exists in fsys and is a
+// file; otherwise returns false. See also [FileExists].
+func FileExistsFS(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && !info.IsDir()
+}
+
+// PathExistsFS returns true if the named path exists in fsys; otherwise
+// returns false. See also [PathExists].
+func PathExistsFS(fsys fs.FS, name string) bool {
+	_, err := fs.Stat(fsys, name)
+	return err == nil
+}
+
+// IsDirFS returns true if the named path is a folder in fsys; otherwise
+// returns false. See also [IsDir].
+func IsDirFS(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && info.IsDir()
+}
+
+// WalkFS walks the tree rooted at root in fsys (including root itself)
+// and returns an iterator of (entry, error) for every entry, with each
+// entry's Path being slash-separated as usual for [fs.FS]. See also
+// [FindFS].
+func WalkFS(fsys fs.FS, root string) iter.Seq2[Entry, error] {
+	return FindFS(fsys, root, NewQuery())
+}
+
+// FindFS works like [Find] but walks the tree rooted at root in fsys.
+func FindFS(fsys fs.FS, root string, q Query) iter.Seq2[Entry, error] {
+	return find(path.Clean(root), q, '/',
+		func(root string, fn fs.WalkDirFunc) error {
+			return fs.WalkDir(fsys, root, fn)
+		})
+}
@@ -0,0 +1,68 @@
+package ufile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func Test_FS(t *testing.T) {
+	var compressed bytes.Buffer
+	gzwriter := gzip.NewWriter(&compressed)
+	_, _ = gzwriter.Write([]byte("one\ntwo\n"))
+	_ = gzwriter.Close()
+	fsys := fstest.MapFS{
+		"a.txt":            {Data: []byte("alpha\r\nbeta\n")},
+		"sub/b.txt.gz":     {Data: compressed.Bytes()},
+		"sub/deeper/c.txt": {Data: []byte("gamma")},
+	}
+	lines, err := ReadTextFileFS(fsys, "a.txt")
+	if err != nil || !slices.Equal(lines, []string{"alpha", "beta"}) {
+		t.Errorf("unexpected %q %v", lines, err)
+	}
+	lines, err = ReadTextFileFS(fsys, "sub/b.txt.gz")
+	if err != nil || !slices.Equal(lines, []string{"one", "two"}) {
+		t.Errorf("unexpected %q %v", lines, err)
+	}
+	lines = nil
+	for line, err := range ReadUtf8LinesFS(fsys, "a.txt") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if !slices.Equal(lines, []string{"alpha", "beta"}) {
+		t.Errorf("expected %q got %q", []string{"alpha", "beta"}, lines)
+	}
+	if !FileExistsFS(fsys, "a.txt") || FileExistsFS(fsys, "sub") ||
+		!IsDirFS(fsys, "sub") || IsDirFS(fsys, "a.txt") ||
+		!PathExistsFS(fsys, "sub/deeper") || PathExistsFS(fsys, "x") {
+		t.Error("unexpected existence results")
+	}
+	var paths []string
+	for entry, err := range WalkFS(fsys, ".") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, entry.Path)
+	}
+	expected := []string{".", "a.txt", "sub", "sub/b.txt.gz", "sub/deeper",
+		"sub/deeper/c.txt"}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("expected %q got %q", expected, paths)
+	}
+	paths = nil
+	for entry, err := range FindFS(fsys, ".",
+		NewQuery().Type(TypeFile).MaxDepth(2)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, entry.Path)
+	}
+	expected = []string{"a.txt", "sub/b.txt.gz"}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("expected %q got %q", expected, paths)
+	}
+}
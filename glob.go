@@ -0,0 +1,148 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MatchOptions are used by [MatchGlob].
+//
+// IsDir says that the path is a folder (which patterns with a trailing /
+// require). If MatchBase is true patterns that don't contain a / (like
+// "*.go" in .gitignore and .editorconfig files) are matched against the
+// path's last component. If IgnoreCase is true matching is
+// case-insensitive.
+type MatchOptions struct {
+	IsDir      bool
+	MatchBase  bool
+	IgnoreCase bool
+}
+
+// MatchGlob returns true if the slash-separated path matches the glob
+// pattern; otherwise returns false. Unlike [filepath.Match], the pattern
+// is matched against the whole path and may contain ** (which matches any
+// number of folders, e.g., "src/**/*.go" matches "src/a.go" and
+// "src/a/b/c.go"), {a,b} alternatives (which may be nested), [!...]
+// negated classes (as well as [^...]), and a trailing / (which only
+// matches folders; see [MatchOptions]). As elsewhere, * and ? don't match
+// /, and \ escapes the next character. A leading / is ignored since paths
+// are matched from their start. A malformed pattern matches nothing. See
+// also [IgnoreMatcher].
+func MatchGlob(pattern, path string, opts MatchOptions) bool {
+	path = strings.Trim(filepath.ToSlash(path), "/")
+	if opts.IgnoreCase {
+		pattern = strings.ToLower(pattern)
+		path = strings.ToLower(path)
+	}
+	if strings.HasSuffix(pattern, "/") {
+		if !opts.IsDir {
+			return false
+		}
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	parts := strings.Split(path, "/")
+	for _, alternative := range expandBraces(pattern) {
+		alternative = strings.TrimLeft(alternative, "/")
+		if opts.MatchBase && !strings.Contains(alternative, "/") {
+			if matchGlobPart(alternative, parts[len(parts)-1]) {
+				return true
+			}
+		} else if matchGlobParts(strings.Split(alternative, "/"), parts) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandBraces returns the patterns that result from expanding the
+// pattern's {a,b} alternatives. Braces without a top-level comma or
+// without a closing brace are left as literals.
+func expandBraces(pattern string) []string {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			end, commas := matchingBrace(pattern, i)
+			if end < 0 {
+				return []string{pattern}
+			}
+			if len(commas) == 0 {
+				continue
+			}
+			prefix, suffix := pattern[:i], pattern[end+1:]
+			var patterns []string
+			start := i + 1
+			for _, comma := range append(commas, end) {
+				patterns = append(patterns, expandBraces(
+					prefix+pattern[start:comma]+suffix)...)
+				start = comma + 1
+			}
+			return patterns
+		}
+	}
+	return []string{pattern}
+}
+
+// matchingBrace returns the index of the } that closes the { at
+// pattern[start] and the indexes of its top-level commas, or -1 if it
+// isn't closed.
+func matchingBrace(pattern string, start int) (int, []int) {
+	var commas []int
+	depth := 0
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, commas
+			}
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		}
+	}
+	return -1, nil
+}
+
+// matchGlobParts returns true if the path parts match the pattern parts,
+// where a "**" part matches zero or more path parts (or one or more if
+// it is the last pattern part); otherwise returns false.
+func matchGlobParts(patterns, parts []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			patterns = patterns[1:]
+			if len(patterns) == 0 {
+				return len(parts) > 0
+			}
+			for i := range len(parts) + 1 {
+				if matchGlobParts(patterns, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 || !matchGlobPart(patterns[0], parts[0]) {
+			return false
+		}
+		patterns, parts = patterns[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// matchGlobPart returns true if name matches the glob pattern (see
+// [path.Match], but also accepting [!...] for a negated class); otherwise
+// returns false.
+func matchGlobPart(pattern, name string) bool {
+	matched, _ := path.Match(strings.ReplaceAll(pattern, "[!", "[^"),
+		name)
+	return matched
+}
@@ -0,0 +1,43 @@
+package ufile
+
+import "testing"
+
+func Test_MatchGlob(t *testing.T) {
+	base := MatchOptions{MatchBase: true}
+	dir := MatchOptions{IsDir: true}
+	for _, test := range []struct {
+		pattern  string
+		path     string
+		opts     MatchOptions
+		expected bool
+	}{
+		{"*.go", "main.go", MatchOptions{}, true},
+		{"*.go", "cmd/main.go", MatchOptions{}, false},
+		{"*.go", "cmd/main.go", base, true},
+		{"src/**/*.go", "src/a.go", MatchOptions{}, true},
+		{"src/**/*.go", "src/a/b/c.go", MatchOptions{}, true},
+		{"src/**/*.go", "lib/a.go", MatchOptions{}, false},
+		{"**/test", "a/b/test", MatchOptions{}, true},
+		{"docs/**", "docs", MatchOptions{}, false},
+		{"docs/**", "docs/x/y.md", MatchOptions{}, true},
+		{"*.{js,ts}", "app.ts", MatchOptions{}, true},
+		{"*.{js,ts}", "app.py", MatchOptions{}, false},
+		{"{src,lib/{a,b}}/*.c", "lib/b/x.c", MatchOptions{}, true},
+		{"{src,lib/{a,b}}/*.c", "lib/c/x.c", MatchOptions{}, false},
+		{"{single}", "{single}", MatchOptions{}, true},
+		{`\{a,b\}`, "{a,b}", MatchOptions{}, true},
+		{"[!x]y", "zy", MatchOptions{}, true},
+		{"[!x]y", "xy", MatchOptions{}, false},
+		{"build/", "build", MatchOptions{}, false},
+		{"build/", "build", dir, true},
+		{"/build", "build", MatchOptions{}, true},
+		{"README", "readme", MatchOptions{IgnoreCase: true}, true},
+		{"[", "[", MatchOptions{}, false},
+	} {
+		if got := MatchGlob(test.pattern, test.path, test.opts); got !=
+			test.expected {
+			t.Errorf("%q %q: expected %t got %t", test.pattern, test.path,
+				test.expected, got)
+		}
+	}
+}
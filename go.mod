@@ -1,5 +1,10 @@
 module github.com/mark-summerfield/ufile
 
-go 1.23
+go 1.23.0
 
-require github.com/mark-summerfield/utext v0.0.0-20250527072059-af9de8cedc6e
+require (
+	github.com/mark-summerfield/utext v0.0.0-20250527072059-af9de8cedc6e
+	golang.org/x/sys v0.33.0
+)
+
+require golang.org/x/text v0.25.0
@@ -0,0 +1,185 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreMatcher matches slash-separated relative paths against
+// .gitignore-style patterns. Blank lines and lines beginning with # are
+// ignored; a leading ! negates a pattern (re-including what an earlier
+// pattern excluded); a trailing / matches folders only; a pattern
+// containing a / (other than a trailing one) is anchored to the folder of
+// the ignore file it came from, otherwise it matches names at any depth;
+// and ** matches any number of folders. Later patterns (and patterns from
+// deeper ignore files) take precedence over earlier ones. A nil
+// *IgnoreMatcher matches nothing. Matchers can be used with [Query.Ignore]
+// (for [Find]), [WalkParallelIgnore], [CopyTreeOptions], [ZipOptions], and
+// [SyncOptions]. See also [MatchGlob], [NewIgnoreMatcher],
+// [ParseIgnoreFile], and [LoadIgnoreFiles].
+type IgnoreMatcher struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	base     string // slash-separated folder the pattern is relative to
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// NewIgnoreMatcher returns an IgnoreMatcher for the given patterns (each
+// as it would appear on a line in a .gitignore file) relative to the root.
+func NewIgnoreMatcher(patterns ...string) *IgnoreMatcher {
+	matcher := &IgnoreMatcher{}
+	matcher.add("", patterns)
+	return matcher
+}
+
+// ParseIgnoreFile returns an IgnoreMatcher for the patterns in the given
+// .gitignore-style file; paths are matched relative to the file's folder.
+func ParseIgnoreFile(path string) (*IgnoreMatcher, error) {
+	lines, err := ReadTextFile(path)
+	if err != nil {
+		return nil, err
+	}
+	matcher := &IgnoreMatcher{}
+	matcher.add("", lines)
+	return matcher, nil
+}
+
+// LoadIgnoreFiles returns an IgnoreMatcher for all the ignore files with
+// the given name (e.g., ".gitignore") in the tree rooted at root (which
+// is where paths are matched relative to). As with git, nested ignore
+// files apply to their own subtrees and those in ignored folders are
+// skipped.
+func LoadIgnoreFiles(root, name string) (*IgnoreMatcher, error) {
+	matcher := &IgnoreMatcher{}
+	err := filepath.WalkDir(root, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if !dirEntry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		} else if matcher.Match(rel, true) {
+			return filepath.SkipDir
+		}
+		lines, err := ReadTextFile(filepath.Join(path, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		matcher.add(rel, lines)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matcher, nil
+}
+
+// add parses the given .gitignore-style lines as rules relative to base.
+func (me *IgnoreMatcher) add(base string, lines []string) {
+	for _, line := range lines {
+		if rule, ok := parseIgnoreLine(line); ok {
+			rule.base = base
+			me.rules = append(me.rules, rule)
+		}
+	}
+}
+
+// parseIgnoreLine returns the rule for the given .gitignore line and true,
+// or false if the line is blank or a comment.
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	var rule ignoreRule
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || line[0] == '#' {
+		return rule, false
+	}
+	if line[0] == '!' {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line,
+		`\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimLeft(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+// Match returns true if the slash-separated path rel (relative to the
+// matcher's root), which is a folder if isDir is true, or any of its
+// parent folders is ignored; otherwise returns false. (As with git, a
+// file can't be re-included if a parent folder is ignored.)
+func (me *IgnoreMatcher) Match(rel string, isDir bool) bool {
+	if me == nil || len(me.rules) == 0 {
+		return false
+	}
+	rel = strings.Trim(path.Clean(rel), "/")
+	for i := range len(rel) {
+		if rel[i] == '/' && me.ignored(rel[:i], true) {
+			return true
+		}
+	}
+	return me.ignored(rel, isDir)
+}
+
+// ignored returns true if the last rule matching rel excludes it;
+// otherwise returns false.
+func (me *IgnoreMatcher) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range me.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if rule.base != "" {
+			var ok bool
+			if sub, ok = strings.CutPrefix(rel, rule.base+"/"); !ok {
+				continue
+			}
+		}
+		var matched bool
+		if rule.anchored {
+			matched = matchGlobParts(strings.Split(rule.pattern, "/"),
+				strings.Split(sub, "/"))
+		} else {
+			matched = matchGlobPart(rule.pattern, path.Base(sub))
+		}
+		if matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,142 @@
+package ufile
+
+import (
+	"archive/zip"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func Test_IgnoreMatcher(t *testing.T) {
+	matcher := NewIgnoreMatcher("# comment", "", "*.log", "!keep.log",
+		"build/", "/TODO", "docs/**/*.tmp", `\#hash`, "a/**", "[!x]y")
+	for _, test := range []struct {
+		rel      string
+		isDir    bool
+		expected bool
+	}{
+		{"app.log", false, true},
+		{"src/deep/app.log", false, true},
+		{"keep.log", false, false},
+		{"src/keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build/out.o", false, true},
+		{"TODO", false, true},
+		{"src/TODO", false, false},
+		{"docs/x.tmp", false, true},
+		{"docs/a/b/x.tmp", false, true},
+		{"src/docs/x.tmp", false, false},
+		{"#hash", false, true},
+		{"a", true, false},
+		{"a/b", false, true},
+		{"zy", false, true},
+		{"xy", false, false},
+		{"main.go", false, false},
+	} {
+		if got := matcher.Match(test.rel, test.isDir); got != test.expected {
+			t.Errorf("%q: expected %t got %t", test.rel, test.expected,
+				got)
+		}
+	}
+	var none *IgnoreMatcher
+	if none.Match("app.log", false) {
+		t.Error("expected nil matcher to match nothing")
+	}
+}
+
+func Test_LoadIgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+	makeTestTree(t, root, map[string]string{
+		".gitignore": "*.o\nout/\n", "main.c": "", "main.o": "",
+		"lib/.gitignore": "!keep.o\n*.c\n", "lib/keep.o": "",
+		"lib/util.c": "", "lib/util.h": "", "out/.gitignore": "!*\n",
+		"out/app": ""})
+	matcher, err := LoadIgnoreFiles(root, ".gitignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for entry, err := range Find(root, NewQuery().Ignore(matcher).Type(
+		TypeFile)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel, _ := filepath.Rel(root, entry.Path)
+		found = append(found, filepath.ToSlash(rel))
+	}
+	expected := []string{".gitignore", "lib/.gitignore", "lib/keep.o",
+		"lib/util.h", "main.c"}
+	if !slices.Equal(found, expected) {
+		t.Errorf("expected %v got %v", expected, found)
+	}
+	var mutex sync.Mutex
+	var walked []string
+	err = WalkParallelIgnore(root, 2, matcher, func(entry Entry) error {
+		if entry.Mode().IsRegular() {
+			rel, _ := filepath.Rel(root, entry.Path)
+			mutex.Lock()
+			defer mutex.Unlock()
+			walked = append(walked, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	slices.Sort(walked)
+	if err != nil || !slices.Equal(walked, expected) {
+		t.Errorf("expected %v got %v %v", expected, walked, err)
+	}
+	dst := filepath.Join(t.TempDir(), "copy")
+	err = CopyTreeOpt(root, dst, CopyTreeOptions{Ignore: matcher})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTree := `.
+├── .gitignore
+├── lib/
+│   ├── .gitignore
+│   ├── keep.o
+│   └── util.h
+└── main.c
+`
+	if tree, _ := TreeString(dst); tree != expectedTree {
+		t.Errorf("expected\n%s\ngot\n%s", expectedTree, tree)
+	}
+	mirror := filepath.Join(t.TempDir(), "mirror")
+	makeTestTree(t, mirror, map[string]string{"main.o": "", "stale": ""})
+	_, err = SyncDirs(root, mirror, SyncOptions{Delete: true,
+		Ignore: matcher})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTree = `.
+├── .gitignore
+├── lib/
+│   ├── .gitignore
+│   ├── keep.o
+│   └── util.h
+├── main.c
+└── main.o
+`
+	if tree, _ := TreeString(mirror); tree != expectedTree {
+		t.Errorf("expected\n%s\ngot\n%s", expectedTree, tree)
+	}
+	zipPath := filepath.Join(t.TempDir(), "src.zip")
+	if err = ZipDir(root, zipPath, ZipOptions{Ignore: matcher}); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+	expected = []string{".gitignore", "lib/", "lib/.gitignore",
+		"lib/keep.o", "lib/util.h", "main.c"}
+	if !slices.Equal(names, expected) {
+		t.Errorf("expected %v got %v", expected, names)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// InboxOptions are used by [ProcessInbox].
+//
+// Interval is how often the inbox folder is checked (default 1s). A file
+// is only handled once its size and modification time haven't changed
+// for Settle (default 2s), since it may still be being written. If
+// Include is nonempty only files whose names match one of its globs (see
+// [filepath.Match]) are handled. DoneDir and FailedDir are where handled
+// files are moved to (by default the inbox folder's "done" and "failed"
+// subfolders).
+type InboxOptions struct {
+	Interval  time.Duration
+	Settle    time.Duration
+	Include   []string
+	DoneDir   string
+	FailedDir string
+}
+
+// inboxFile is the state of a file in the inbox as last seen.
+type inboxFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+}
+
+// ProcessInbox watches folder dir (a "hot" or drop folder) and calls
+// handle with the path of each regular file that appears in it (or is
+// already there) once the file appears to have been fully written. If
+// handle succeeds the file is moved to the done folder; otherwise to the
+// failed folder (unless handle has itself moved or removed the file).
+// Moved files keep their names unless a file with the same name is
+// already there, in which case "-2", "-3", etc., is added before the
+// extension. Hidden files (whose names begin with '.', e.g., temporary
+// files) and folders are ignored. This returns the context's error when
+// ctx is cancelled, or an error if dir can't be read or a file can't be
+// moved. See also [InboxOptions].
+func ProcessInbox(ctx context.Context, dir string,
+	handle func(path string) error, opts InboxOptions,
+) error {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = 2 * time.Second
+	}
+	if opts.DoneDir == "" {
+		opts.DoneDir = filepath.Join(dir, "done")
+	}
+	if opts.FailedDir == "" {
+		opts.FailedDir = filepath.Join(dir, "failed")
+	}
+	files := make(map[string]inboxFile)
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := processInbox(dir, handle, opts, files); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// processInbox does one check of the inbox, handling every settled file.
+func processInbox(dir string, handle func(path string) error,
+	opts InboxOptions, files map[string]inboxFile,
+) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") ||
+			(len(opts.Include) > 0 &&
+				!matchesAnyGlob(opts.Include, name, name)) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed since the ReadDir
+		}
+		seen[name] = true
+		old, ok := files[name]
+		if !ok || old.size != info.Size() ||
+			!old.modTime.Equal(info.ModTime()) {
+			files[name] = inboxFile{info.Size(), info.ModTime(), now}
+			continue
+		}
+		if now.Sub(old.since) < opts.Settle {
+			continue
+		}
+		delete(files, name)
+		path := filepath.Join(dir, name)
+		target := opts.DoneDir
+		if handle(path) != nil {
+			target = opts.FailedDir
+		}
+		if PathExists(path) {
+			if err = moveToInboxDir(path, target); err != nil {
+				return err
+			}
+		}
+	}
+	for name := range files {
+		if !seen[name] {
+			delete(files, name)
+		}
+	}
+	return nil
+}
+
+// moveToInboxDir moves path into folder dir (creating it if necessary)
+// using a unique name.
+func moveToInboxDir(path, dir string) error {
+	if err := EnsureDir(dir, 0o755); err != nil {
+		return err
+	}
+	stem, ext := SplitExt(filepath.Base(path))
+	dst := filepath.Join(dir, stem+ext)
+	for i := 2; PathExists(dst); i++ {
+		dst = filepath.Join(dir, fmt.Sprintf("%s-%d%s", stem, i, ext))
+	}
+	return Move(path, dst, MoveOptions{})
+}
@@ -0,0 +1,50 @@
+package ufile
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ProcessInbox(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"a.csv": "1,2",
+		"bad.csv": "x", "notes.txt": "skip", ".a.csv.tmp": "partial",
+		"done/a.csv": "earlier"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var handled []string
+	err := ProcessInbox(ctx, dir, func(path string) error {
+		name := filepath.Base(path)
+		handled = append(handled, name)
+		if len(handled) == 2 {
+			cancel()
+		}
+		if strings.HasPrefix(name, "bad") {
+			return errors.New("bad data")
+		}
+		return nil
+	}, InboxOptions{Interval: 5 * time.Millisecond,
+		Settle: 20 * time.Millisecond, Include: []string{"*.csv"}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled got %v", err)
+	}
+	if len(handled) != 2 {
+		t.Errorf("expected 2 handled files got %q", handled)
+	}
+	expected := `.
+├── .a.csv.tmp
+├── done/
+│   ├── a-2.csv
+│   └── a.csv
+├── failed/
+│   └── bad.csv
+└── notes.txt
+`
+	if tree, _ := TreeString(dir); tree != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, tree)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Ini holds the contents of a .ini file. Comments (lines beginning with ;
+// or #), blank lines, and the order of sections and keys are preserved
+// when it is saved. Keys that precede any [section] header are in the ""
+// section. Section names and keys are case-sensitive and values have
+// surrounding whitespace stripped. Create with [LoadIni] or [NewIni]; see
+// also [GetIniFile].
+type Ini struct {
+	filename string
+	lines    []iniLine
+}
+
+type iniLine struct {
+	section string
+	key     string // "" for headers, comments, and blank lines
+	value   string
+	text    string
+}
+
+// NewIni returns an empty Ini that will be saved to the given filename.
+func NewIni(filename string) *Ini {
+	return &Ini{filename: filename}
+}
+
+// LoadIni reads the given .ini file and returns it as an Ini.
+func LoadIni(filename string) (*Ini, error) {
+	ini := NewIni(filename)
+	section := ""
+	lino := 0
+	for text, err := range ReadUtf8Lines(filename) {
+		if err != nil {
+			return nil, err
+		}
+		lino++
+		line := iniLine{section: section, text: text}
+		stripped := strings.TrimSpace(text)
+		switch {
+		case stripped == "" || stripped[0] == ';' || stripped[0] == '#':
+		case stripped[0] == '[':
+			if !strings.HasSuffix(stripped, "]") {
+				return nil, fmt.Errorf("%s:%d: invalid section header",
+					filename, lino)
+			}
+			section = strings.TrimSpace(stripped[1 : len(stripped)-1])
+			line.section = section
+		default:
+			key, value, found := strings.Cut(stripped, "=")
+			key = strings.TrimSpace(key)
+			if !found || key == "" {
+				return nil, fmt.Errorf("%s:%d: invalid key=value line",
+					filename, lino)
+			}
+			line.key = key
+			line.value = strings.TrimSpace(value)
+		}
+		ini.lines = append(ini.lines, line)
+	}
+	return ini, nil
+}
+
+// Filename returns the filename the Ini was loaded from or will be saved
+// to.
+func (me *Ini) Filename() string { return me.filename }
+
+// Sections returns the names of the Ini's sections in order, including ""
+// if any keys precede the first section header.
+func (me *Ini) Sections() []string {
+	var sections []string
+	for _, line := range me.lines {
+		if (line.key != "" || line.isHeader()) &&
+			!slices.Contains(sections, line.section) {
+			sections = append(sections, line.section)
+		}
+	}
+	return sections
+}
+
+// Keys returns the given section's keys in order.
+func (me *Ini) Keys(section string) []string {
+	var keys []string
+	for _, line := range me.lines {
+		if line.section == section && line.key != "" &&
+			!slices.Contains(keys, line.key) {
+			keys = append(keys, line.key)
+		}
+	}
+	return keys
+}
+
+// Get returns the value of the given section's key and true, or "" and
+// false if there is no such key.
+func (me *Ini) Get(section, key string) (string, bool) {
+	if i := me.find(section, key); i > -1 {
+		return me.lines[i].value, true
+	}
+	return "", false
+}
+
+// GetString returns the value of the given section's key, or def if
+// there is no such key.
+func (me *Ini) GetString(section, key, def string) string {
+	if value, ok := me.Get(section, key); ok {
+		return value
+	}
+	return def
+}
+
+// GetInt returns the value of the given section's key as an int, or def
+// if there is no such key or its value isn't an int.
+func (me *Ini) GetInt(section, key string, def int) int {
+	if value, ok := me.Get(section, key); ok {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return def
+}
+
+// GetBool returns the value of the given section's key as a bool, or def
+// if there is no such key or its value isn't a bool. Accepted values are
+// those accepted by [strconv.ParseBool] plus (case-insensitively) "yes",
+// "no", "on", and "off".
+func (me *Ini) GetBool(section, key string, def bool) bool {
+	if value, ok := me.Get(section, key); ok {
+		switch strings.ToLower(value) {
+		case "yes", "on":
+			return true
+		case "no", "off":
+			return false
+		}
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// Set sets the given section's key to value, adding the key (and the
+// section) if necessary.
+func (me *Ini) Set(section, key, value string) {
+	line := iniLine{section: section, key: key, value: value,
+		text: key + " = " + value}
+	if i := me.find(section, key); i > -1 {
+		me.lines[i] = line
+		return
+	}
+	at := -1
+	for i, other := range me.lines {
+		if other.section == section && (other.key != "" ||
+			other.isHeader()) {
+			at = i + 1 // after the section's header or last key
+		}
+	}
+	if at == -1 {
+		if section == "" {
+			at = len(me.lines)
+			for i, other := range me.lines {
+				if other.isHeader() {
+					at = i
+					break
+				}
+			}
+			me.lines = slices.Insert(me.lines, at, line)
+			return
+		}
+		if len(me.lines) > 0 {
+			me.lines = append(me.lines, iniLine{section: section})
+		}
+		me.lines = append(me.lines, iniLine{section: section,
+			text: "[" + section + "]"})
+		at = len(me.lines)
+	}
+	me.lines = slices.Insert(me.lines, at, line)
+}
+
+// SetInt sets the given section's key to the given int value.
+func (me *Ini) SetInt(section, key string, value int) {
+	me.Set(section, key, strconv.Itoa(value))
+}
+
+// SetBool sets the given section's key to the given bool value.
+func (me *Ini) SetBool(section, key string, value bool) {
+	me.Set(section, key, strconv.FormatBool(value))
+}
+
+// Save writes the Ini to its file atomically.
+func (me *Ini) Save() error {
+	return writeAtomic(me.filename, func(out *bufio.Writer) error {
+		return writeLines(out, func(yield func(string) bool) {
+			for _, line := range me.lines {
+				if !yield(line.text) {
+					return
+				}
+			}
+		})
+	})
+}
+
+// find returns the index of the last line with the given section and key,
+// or -1 if there isn't one.
+func (me *Ini) find(section, key string) int {
+	for i := len(me.lines) - 1; i >= 0; i-- {
+		if me.lines[i].section == section && me.lines[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (me iniLine) isHeader() bool {
+	return me.key == "" && strings.HasPrefix(strings.TrimSpace(me.text),
+		"[")
+}
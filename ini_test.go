@@ -0,0 +1,80 @@
+package ufile
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_Ini(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.ini")
+	lines := []string{
+		"; global settings",
+		"debug = yes",
+		"",
+		"[Window]",
+		"# size in pixels",
+		"width = 800",
+		"height=x",
+		"",
+		"[Recent]",
+		"file1 = /tmp/a.txt",
+	}
+	if err := WriteTextFile(filename, lines); err != nil {
+		t.Fatal(err)
+	}
+	ini, err := LoadIni(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sections := ini.Sections(); !slices.Equal(sections,
+		[]string{"", "Window", "Recent"}) {
+		t.Errorf("unexpected sections %q", sections)
+	}
+	if !ini.GetBool("", "debug", false) {
+		t.Error("expected debug true")
+	}
+	if width := ini.GetInt("Window", "width", 0); width != 800 {
+		t.Errorf("expected 800 got %d", width)
+	}
+	if height := ini.GetInt("Window", "height", 600); height != 600 {
+		t.Errorf("expected 600 got %d", height)
+	}
+	if name := ini.GetString("Recent", "file1", ""); name != "/tmp/a.txt" {
+		t.Errorf("expected %q got %q", "/tmp/a.txt", name)
+	}
+	ini.SetInt("Window", "width", 1024)
+	ini.Set("Window", "x", "10")
+	ini.Set("", "verbose", "no")
+	ini.SetBool("Font", "bold", true)
+	if err = ini.Save(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ReadTextFile(filename)
+	expected := []string{
+		"; global settings",
+		"debug = yes",
+		"verbose = no",
+		"",
+		"[Window]",
+		"# size in pixels",
+		"width = 1024",
+		"height=x",
+		"x = 10",
+		"",
+		"[Recent]",
+		"file1 = /tmp/a.txt",
+		"",
+		"[Font]",
+		"bold = true",
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+	if err = WriteTextFile(filename, []string{"[bad"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadIni(filename); err == nil {
+		t.Error("expected invalid section header error")
+	}
+}
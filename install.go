@@ -0,0 +1,237 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// templateFuncs are the extra functions available in the templates used
+// by [ApplyManifest] and [RenderTree], e.g., {{env "HOME"}}.
+var templateFuncs = template.FuncMap{"env": os.Getenv}
+
+// ManifestOptions are used by [ApplyManifestOpt].
+//
+// Data is the data passed to the templates of entries marked "template"
+// (see [text/template]).
+type ManifestOptions struct {
+	Data any
+}
+
+// installEntry is a line of an install manifest.
+type installEntry struct {
+	src      string
+	dst      string
+	mode     fs.FileMode // 0 means use the source's permissions
+	template bool
+}
+
+// ApplyManifest copies the files listed in the given install manifest
+// into folder destRoot (which is created if necessary). Each nonblank
+// line of the manifest that doesn't begin with '#' has a source path
+// (relative to the manifest's folder), a destination path (relative to
+// destRoot), and optionally an octal mode and the word "template", all
+// separated by whitespace, e.g.,
+//
+//	bin/app          bin/myapp        0755
+//	etc/app.conf     "etc/my app.conf" 0600 template
+//	share            share/myapp
+//
+// Paths containing spaces must be double-quoted (with Go escapes). A
+// source folder is copied recursively. Files are copied atomically with
+// their source's permissions (or the given mode) and, if "template" is
+// given, their contents are treated as [text/template] templates which
+// can use {{env "NAME"}} to get environment variables. The whole manifest
+// is checked before anything is copied: a destination path that would
+// escape destRoot causes [ErrUnsafePath] to be returned. See also
+// [ApplyManifestOpt].
+func ApplyManifest(manifestPath, destRoot string) error {
+	return ApplyManifestOpt(manifestPath, destRoot, ManifestOptions{})
+}
+
+// ApplyManifestOpt works like [ApplyManifest] but with the given options.
+func ApplyManifestOpt(manifestPath, destRoot string,
+	opts ManifestOptions,
+) error {
+	entries, err := readInstallManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	srcRoot := filepath.Dir(manifestPath)
+	for _, entry := range entries {
+		src := filepath.Join(srcRoot, entry.src)
+		dst := filepath.Join(destRoot, entry.dst)
+		err = filepath.WalkDir(src, func(path string,
+			dirEntry fs.DirEntry, err error,
+		) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(src, path)
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(dst, rel)
+			switch {
+			case dirEntry.IsDir():
+				return EnsureDir(target, 0o755)
+			case dirEntry.Type().IsRegular():
+				return installFile(path, target, entry, opts.Data)
+			}
+			return nil // skip symlinks, sockets, etc.
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readInstallManifest returns the install manifest's entries, or an error
+// if any line is invalid or any source is missing.
+func readInstallManifest(manifestPath string) ([]installEntry, error) {
+	var entries []installEntry
+	lino := 0
+	for line, err := range ReadUtf8Lines(manifestPath) {
+		if err != nil {
+			return nil, err
+		}
+		lino++
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		entry, err := parseInstallLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", manifestPath, lino, err)
+		}
+		if _, err = os.Stat(filepath.Join(filepath.Dir(manifestPath),
+			entry.src)); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", manifestPath, lino, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseInstallLine returns the entry for a (nonblank, noncomment) install
+// manifest line.
+func parseInstallLine(line string) (installEntry, error) {
+	var entry installEntry
+	fields, err := splitQuotedFields(line)
+	if err != nil {
+		return entry, err
+	}
+	if len(fields) < 2 {
+		return entry, fmt.Errorf("expected source and destination")
+	}
+	entry.src = filepath.FromSlash(fields[0])
+	entry.dst = filepath.FromSlash(fields[1])
+	if !filepath.IsLocal(entry.dst) {
+		return entry, fmt.Errorf("%w: %q", ErrUnsafePath, fields[1])
+	}
+	for _, field := range fields[2:] {
+		if field == "template" {
+			entry.template = true
+		} else if mode, err := strconv.ParseUint(field, 8, 32); err == nil &&
+			mode <= 0o777 {
+			entry.mode = fs.FileMode(mode)
+		} else {
+			return entry, fmt.Errorf("invalid option %q", field)
+		}
+	}
+	return entry, nil
+}
+
+// splitQuotedFields returns line's whitespace-separated fields, any of
+// which may be double-quoted (see [strconv.Unquote]).
+func splitQuotedFields(line string) ([]string, error) {
+	var fields []string
+	for line = strings.TrimSpace(line); line != ""; line =
+		strings.TrimSpace(line) {
+		if line[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted field %s", line)
+			}
+			field, _ := strconv.Unquote(quoted)
+			fields = append(fields, field)
+			line = line[len(quoted):]
+		} else {
+			end := strings.IndexFunc(line, unicode.IsSpace)
+			if end == -1 {
+				end = len(line)
+			}
+			fields = append(fields, line[:end])
+			line = line[end:]
+		}
+	}
+	return fields, nil
+}
+
+// installFile atomically copies (or if entry.template is true, renders)
+// file src to dst, creating dst's folder if necessary, and gives it
+// entry's mode if set.
+func installFile(src, dst string, entry installEntry, data any) error {
+	if err := EnsureParentDir(dst); err != nil {
+		return err
+	}
+	if entry.template {
+		raw, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		if err = renderToFile(src, string(raw), dst, data); err != nil {
+			return err
+		}
+		if entry.mode == 0 {
+			info, err := os.Stat(src)
+			if err != nil {
+				return err
+			}
+			entry.mode = info.Mode().Perm()
+		}
+	} else if _, err := copyFile(src, dst, CloneAuto, nil); err != nil {
+		return err
+	}
+	if entry.mode != 0 {
+		return os.Chmod(dst, entry.mode)
+	}
+	return nil
+}
+
+// renderToFile executes text as a template named name with the given data
+// and atomically writes the result to filename.
+func renderToFile(name, text, filename string, data any) error {
+	rendered, err := renderString(name, text, data)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(filename, func(out *bufio.Writer) error {
+		_, err := out.WriteString(rendered)
+		return err
+	})
+}
+
+// renderString returns the result of executing text as a template named
+// name with the given data.
+func renderString(name, text string, data any) (string, error) {
+	tmpl, err := template.New(filepath.Base(name)).Funcs(
+		templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buffer strings.Builder
+	if err = tmpl.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
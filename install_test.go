@@ -0,0 +1,83 @@
+package ufile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func Test_ApplyManifest(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "pkg")
+	dst := filepath.Join(dir, "root")
+	conf := `user={{.User}} home={{env "UFILE_TEST_HOME"}}`
+	makeTestTree(t, src, map[string]string{"bin/app": "#!/bin/sh",
+		"app.conf": conf, "share/doc/README": "read me",
+		"share/icon.png": "png", "install.txt": `# installer manifest
+bin/app          bin/myapp          0755
+
+app.conf         "etc/my app.conf"  0600 template
+share            share/myapp`})
+	t.Setenv("UFILE_TEST_HOME", "/home/me")
+	manifest := filepath.Join(src, "install.txt")
+	if err := ApplyManifestOpt(manifest, dst,
+		ManifestOptions{Data: map[string]string{"User": "me"}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `.
+├── bin/
+│   └── myapp
+├── etc/
+│   └── my app.conf
+└── share/
+    └── myapp/
+        ├── doc/
+        │   └── README
+        └── icon.png
+`
+	if tree, _ := TreeString(dst); tree != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, tree)
+	}
+	conf = filepath.Join(dst, "etc", "my app.conf")
+	if lines, _ := ReadTextFile(conf); !slices.Equal(lines,
+		[]string{"user=me home=/home/me"}) {
+		t.Errorf("expected rendered template got %q", lines)
+	}
+	if runtime.GOOS != "windows" {
+		for name, mode := range map[string]os.FileMode{conf: 0o600,
+			filepath.Join(dst, "bin", "myapp"): 0o755} {
+			if info, _ := os.Stat(name); info.Mode().Perm() != mode {
+				t.Errorf("%s: expected %o got %o", name, mode,
+					info.Mode().Perm())
+			}
+		}
+	}
+	for text, expected := range map[string]error{
+		"app.conf ../escape":           ErrUnsafePath,
+		"missing.txt etc/x":            os.ErrNotExist,
+		"app.conf etc/x 0999":          nil,
+		`"app.conf etc/x`:              nil,
+		"app.conf":                     nil,
+		"app.conf etc/x tmpl":          nil,
+		"app.conf etc/x 0644 template": nil, // no .User
+	} {
+		if err := os.WriteFile(manifest, []byte(text), ModeURW); err != nil {
+			t.Fatal(err)
+		}
+		err := ApplyManifest(manifest, dst)
+		if err == nil || (expected != nil && !errors.Is(err, expected)) {
+			t.Errorf("%q: expected error %v got %v", text, expected, err)
+		}
+	}
+}
+
+func Test_SplitQuotedFields(t *testing.T) {
+	fields, err := splitQuotedFields("  a\t\"b c\"  \"d\\te\" f ")
+	expected := []string{"a", "b c", "d\te", "f"}
+	if err != nil || !slices.Equal(fields, expected) {
+		t.Errorf("expected %q got %q %v", expected, fields, err)
+	}
+}
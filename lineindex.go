@@ -0,0 +1,117 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const defaultLineIndexEvery = 1000
+
+// LineIndex records the byte offsets of every Nth line of a text file so
+// that any line can be read by seeking to the nearest indexed line and
+// reading forward at most N-1 lines, which makes random access into huge
+// files fast. Line indexes are 0-based. The index is only valid for as
+// long as the file is unchanged. Create with [BuildLineIndex] or
+// [BuildLineIndexOpt].
+type LineIndex struct {
+	filename string
+	every    int
+	offsets  []int64 // offsets[k] is where line k*every starts
+	lines    int
+}
+
+// BuildLineIndex reads the given file and returns an index of the offsets
+// of every 1000th line. See also [BuildLineIndexOpt].
+func BuildLineIndex(filename string) (LineIndex, error) {
+	return BuildLineIndexOpt(filename, defaultLineIndexEvery)
+}
+
+// BuildLineIndexOpt works like [BuildLineIndex] but indexes every every'th
+// line: smaller values give faster access but a larger index.
+func BuildLineIndexOpt(filename string, every int) (LineIndex, error) {
+	index := LineIndex{filename: filename, every: every}
+	if every < 1 {
+		return index, fmt.Errorf("invalid line index interval %d", every)
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return index, err
+	}
+	defer file.Close()
+	reader := bufio.NewReaderSize(file, 64*1024)
+	var offset int64
+	atLineStart := true
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(chunk) > 0 {
+			if atLineStart {
+				if index.lines%every == 0 {
+					index.offsets = append(index.offsets, offset)
+				}
+				index.lines++
+			}
+			offset += int64(len(chunk))
+			atLineStart = chunk[len(chunk)-1] == '\n'
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return index, err
+		}
+	}
+	return index, nil
+}
+
+// Lines returns the number of lines in the indexed file.
+func (me LineIndex) Lines() int { return me.lines }
+
+// ReadLine returns line i (counting from 0) with EOL stripped off.
+func (me LineIndex) ReadLine(i int) (string, error) {
+	lines, err := me.ReadRange(i, i+1)
+	if err != nil {
+		return "", err
+	}
+	return lines[0], nil
+}
+
+// ReadRange returns lines from up to but excluding to (counting from 0)
+// with EOL stripped off.
+func (me LineIndex) ReadRange(from, to int) ([]string, error) {
+	if from < 0 || to > me.lines || from > to {
+		return nil, fmt.Errorf("line range [%d, %d) out of range [0, %d)",
+			from, to, me.lines)
+	}
+	if from == to {
+		return []string{}, nil
+	}
+	file, err := os.Open(me.filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	k := from / me.every
+	if _, err = file.Seek(me.offsets[k], io.SeekStart); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(file)
+	lines := make([]string, 0, to-from)
+	for i := k * me.every; i < to; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF // file has shrunk
+			}
+			return nil, err
+		}
+		if i >= from {
+			lines = append(lines, strings.TrimRight(line, "\r\n"))
+		}
+	}
+	return lines, nil
+}
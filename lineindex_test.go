@@ -0,0 +1,48 @@
+package ufile
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_LineIndex(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "lines.txt")
+	var lines []string
+	for i := range 2500 {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := WriteTextFile(filename, lines); err != nil {
+		t.Fatal(err)
+	}
+	for _, every := range []int{1, 7, 1000} {
+		index, err := BuildLineIndexOpt(filename, every)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if index.Lines() != len(lines) {
+			t.Errorf("expected %d got %d", len(lines), index.Lines())
+		}
+		for _, i := range []int{0, 6, 7, 999, 1000, 1001, 2499} {
+			line, err := index.ReadLine(i)
+			if err != nil || line != lines[i] {
+				t.Errorf("expected %q got %q %v", lines[i], line, err)
+			}
+		}
+		got, err := index.ReadRange(995, 1005)
+		if err != nil || !slices.Equal(got, lines[995:1005]) {
+			t.Errorf("expected %q got %q %v", lines[995:1005], got, err)
+		}
+		if _, err = index.ReadLine(2500); err == nil {
+			t.Error("expected out of range error")
+		}
+	}
+	if err := WriteTextFile(filename, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	index, err := BuildLineIndex(filename)
+	if err != nil || index.Lines() != 2 {
+		t.Errorf("expected 2 lines got %d %v", index.Lines(), err)
+	}
+}
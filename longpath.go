@@ -0,0 +1,40 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"runtime"
+	"strings"
+)
+
+// LongPath returns path in the form that lets Windows APIs accept paths
+// longer than 260 characters: made absolute and clean, with "/"s
+// converted to "\"s, and prefixed with `\\?\`, e.g., `C:\a\b` becomes
+// `\\?\C:\a\b` and the UNC path `\\server\share\a` becomes
+// `\\?\UNC\server\share\a`. Paths that already have a `\\?\` or `\\.\`
+// prefix are returned unchanged. On other platforms path is returned
+// unchanged. The os package already does this for its own functions; the
+// package uses LongPath for the Windows APIs it calls directly, and it is
+// useful for paths passed to other programs or APIs.
+func LongPath(path string) string {
+	if runtime.GOOS != "windows" {
+		return path
+	}
+	return longPath(AbsPath(path))
+}
+
+// longPath returns the given absolute Windows path with the appropriate
+// long path prefix.
+func longPath(path string) string {
+	if strings.HasPrefix(path, `\\?\`) || strings.HasPrefix(path, `\\.\`) {
+		return path
+	}
+	path = strings.ReplaceAll(path, "/", `\`)
+	if strings.HasPrefix(path, `\\`) {
+		return `\\?\UNC\` + path[2:]
+	}
+	if len(path) >= 3 && path[1] == ':' && path[2] == '\\' {
+		return `\\?\` + path
+	}
+	return path // relative so can't be prefixed
+}
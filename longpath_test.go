@@ -0,0 +1,27 @@
+package ufile
+
+import (
+	"runtime"
+	"testing"
+)
+
+func Test_LongPath(t *testing.T) {
+	for path, expected := range map[string]string{
+		`C:\a\b.txt`:            `\\?\C:\a\b.txt`,
+		`C:/a/b.txt`:            `\\?\C:\a\b.txt`,
+		`\\server\share\a.txt`:  `\\?\UNC\server\share\a.txt`,
+		`\\?\C:\already`:        `\\?\C:\already`,
+		`\\.\pipe\name`:         `\\.\pipe\name`,
+		`relative\a.txt`:        `relative\a.txt`,
+		`//server/share/x/y.go`: `\\?\UNC\server\share\x\y.go`,
+	} {
+		if got := longPath(path); got != expected {
+			t.Errorf("expected %q got %q", expected, got)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		if got := LongPath("/a/b"); got != "/a/b" {
+			t.Errorf("expected %q got %q", "/a/b", got)
+		}
+	}
+}
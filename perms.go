@@ -0,0 +1,147 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ViolationKind identifies the problem reported by a [Violation].
+type ViolationKind uint8
+
+const (
+	ViolationWorldWritable ViolationKind = iota
+	ViolationSetuid
+	ViolationSetgid
+	ViolationMode
+)
+
+// String returns the ViolationKind's description, e.g., "world-writable".
+func (me ViolationKind) String() string {
+	switch me {
+	case ViolationSetuid:
+		return "setuid"
+	case ViolationSetgid:
+		return "setgid"
+	case ViolationMode:
+		return "unexpected mode"
+	default:
+		return "world-writable"
+	}
+}
+
+// Violation is a permissions problem reported by [AuditPermissions].
+type Violation struct {
+	Path string
+	Mode fs.FileMode
+	Kind ViolationKind
+}
+
+// AuditPolicy is used by [AuditPermissions]. The zero AuditPolicy reports
+// world-writable files and folders and setuid or setgid files.
+//
+// If AllowWorldWritable is true world-writable entries aren't reported
+// (world-writable folders with the sticky bit set, such as /tmp, never
+// are). If AllowSetuid is true setuid and setgid entries aren't reported.
+// If FileMask is nonzero regular files with any permission bits not in
+// FileMask are reported (e.g., a FileMask of 0o644 reports executable or
+// group-writable files); DirMask does the same for folders.
+type AuditPolicy struct {
+	AllowWorldWritable bool
+	AllowSetuid        bool
+	FileMask           fs.FileMode
+	DirMask            fs.FileMode
+}
+
+// ChmodTree sets the permissions of every regular file in the tree rooted
+// at root to fileMode and of every folder (including root) to dirMode.
+// Symlinks (and their targets) are left unchanged. Folders are changed
+// after their contents so that a restrictive dirMode doesn't prevent the
+// walk. On Windows only the owner-writable bit has any effect (see
+// [os.Chmod]).
+func ChmodTree(root string, fileMode, dirMode fs.FileMode) error {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case dirEntry.IsDir():
+			dirs = append(dirs, path)
+		case dirEntry.Type().IsRegular():
+			return os.Chmod(path, fileMode)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, dir := range slices.Backward(dirs) {
+		if err = os.Chmod(dir, dirMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AuditPermissions walks the tree rooted at root (including root itself)
+// and returns an iterator of (violation, error) for every permissions
+// problem according to policy. An entry may have more than one violation.
+// Symlinks aren't followed or reported. An error for a particular path is
+// yielded with a Violation containing just that path, and the walk
+// continues. On Windows permission bits are synthesized (see [os.Chmod])
+// so this is only really useful on Unix-like platforms.
+func AuditPermissions(root string,
+	policy AuditPolicy,
+) iter.Seq2[Violation, error] {
+	return func(yield func(Violation, error) bool) {
+		for entry, err := range Find(root, NewQuery()) {
+			if err != nil {
+				if !yield(Violation{Path: entry.Path}, err) {
+					return
+				}
+				continue
+			}
+			for _, kind := range policy.violations(entry.Mode()) {
+				if !yield(Violation{entry.Path, entry.Mode(), kind},
+					nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// violations returns the kinds of violation mode has under the policy.
+func (me AuditPolicy) violations(mode fs.FileMode) []ViolationKind {
+	var kinds []ViolationKind
+	if mode&fs.ModeSymlink != 0 {
+		return kinds
+	}
+	if !me.AllowWorldWritable && mode.Perm()&0o002 != 0 &&
+		!(mode.IsDir() && mode&fs.ModeSticky != 0) {
+		kinds = append(kinds, ViolationWorldWritable)
+	}
+	if !me.AllowSetuid && mode&fs.ModeSetuid != 0 {
+		kinds = append(kinds, ViolationSetuid)
+	}
+	if !me.AllowSetuid && mode&fs.ModeSetgid != 0 {
+		kinds = append(kinds, ViolationSetgid)
+	}
+	mask := me.FileMask
+	if mode.IsDir() {
+		mask = me.DirMask
+	} else if !mode.IsRegular() {
+		mask = 0
+	}
+	if mask != 0 && mode.Perm()&^mask != 0 {
+		kinds = append(kinds, ViolationMode)
+	}
+	return kinds
+}
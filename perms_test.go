@@ -0,0 +1,72 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func Test_ChmodTree(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions")
+	}
+	root := filepath.Join(t.TempDir(), "root")
+	makeTestTree(t, root, map[string]string{"a.txt": "a",
+		"sub/b.txt": "b", "sub/empty/": ""})
+	if err := ChmodTree(root, 0o640, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]os.FileMode{".": 0o750,
+		"a.txt": 0o640, "sub": 0o750, "sub/b.txt": 0o640,
+		"sub/empty": 0o750} {
+		info, err := os.Stat(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != expected {
+			t.Errorf("%s: expected %o got %o", name, expected, perm)
+		}
+	}
+}
+
+func Test_AuditPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions")
+	}
+	root := filepath.Join(t.TempDir(), "root")
+	makeTestTree(t, root, map[string]string{"ok.txt": "a",
+		"open.txt": "b", "tool": "c", "shared/": "", "tmp/": ""})
+	for name, mode := range map[string]os.FileMode{".": 0o755,
+		"ok.txt": 0o644, "open.txt": 0o666, "tool": 0o755 | os.ModeSetuid,
+		"shared": 0o777, "tmp": 0o777 | os.ModeSticky} {
+		if err := os.Chmod(filepath.Join(root, name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	audit := func(policy AuditPolicy) []string {
+		var problems []string
+		for violation, err := range AuditPermissions(root, policy) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			rel, _ := filepath.Rel(root, violation.Path)
+			problems = append(problems, rel+" "+violation.Kind.String())
+		}
+		slices.Sort(problems)
+		return problems
+	}
+	expected := []string{"open.txt world-writable", "shared world-writable",
+		"tool setuid"}
+	if problems := audit(AuditPolicy{}); !slices.Equal(problems,
+		expected) {
+		t.Errorf("expected %q got %q", expected, problems)
+	}
+	expected = []string{"open.txt unexpected mode", "tool unexpected mode"}
+	if problems := audit(AuditPolicy{AllowWorldWritable: true,
+		AllowSetuid: true, FileMask: 0o644}); !slices.Equal(problems,
+		expected) {
+		t.Errorf("expected %q got %q", expected, problems)
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build unix
+
+package ufile
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ChownTree sets the owner and group of every entry in the tree rooted at
+// root (including root itself, and symlinks rather than their targets) to
+// uid and gid. A uid or gid of -1 leaves that value unchanged (see
+// [os.Lchown]). Changing the owner usually requires root privileges.
+// This is only available on Unix-like platforms.
+func ChownTree(root string, uid, gid int) error {
+	return filepath.WalkDir(root, func(path string, _ fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
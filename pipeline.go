@@ -0,0 +1,122 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"iter"
+	"strings"
+	"unicode"
+)
+
+// FilterLines returns an iterator of the (line, error) pairs from lines
+// for which keep returns true. Errors are always passed through. For
+// example:
+//
+//	lines := ufile.FilterLines(ufile.ReadUtf8Lines(filename),
+//		func(line string) bool { return strings.Contains(line, "TODO") })
+//
+// See also [MapLines], [SkipBlank], [StripComments], and [WriteLines].
+func FilterLines(lines iter.Seq2[string, error],
+	keep func(string) bool,
+) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for line, err := range lines {
+			if err != nil || keep(line) {
+				if !yield(line, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// MapLines returns an iterator of the (line, error) pairs from lines with
+// each line replaced by fn(line). Errors are passed through unchanged.
+// See also [FilterLines].
+func MapLines(lines iter.Seq2[string, error],
+	fn func(string) string,
+) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for line, err := range lines {
+			if err == nil {
+				line = fn(line)
+			}
+			if !yield(line, err) {
+				return
+			}
+		}
+	}
+}
+
+// SkipBlank returns an iterator of the (line, error) pairs from lines
+// excluding empty or whitespace-only lines. See also [FilterLines].
+func SkipBlank(lines iter.Seq2[string, error]) iter.Seq2[string, error] {
+	return FilterLines(lines, func(line string) bool {
+		return strings.TrimSpace(line) != ""
+	})
+}
+
+// StripComments returns an iterator of the (line, error) pairs from lines
+// with comments removed: lines whose first nonwhitespace text is prefix
+// (e.g., "#" or "//") are skipped and trailing comments (i.e., prefix
+// preceded by whitespace) are removed along with the whitespace. So
+// "x = 1 # one" becomes "x = 1" but "url = a.html#top" is unchanged.
+// Combine with [SkipBlank] to also skip blank lines. See also
+// [FilterLines].
+func StripComments(lines iter.Seq2[string, error],
+	prefix string,
+) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for line, err := range lines {
+			if err == nil {
+				if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+					continue
+				}
+				line = stripTrailingComment(line, prefix)
+			}
+			if !yield(line, err) {
+				return
+			}
+		}
+	}
+}
+
+// stripTrailingComment returns line without its first whitespace-preceded
+// comment (and the whitespace).
+func stripTrailingComment(line, prefix string) string {
+	for i := 1; i < len(line); i++ {
+		if strings.HasPrefix(line[i:], prefix) &&
+			unicode.IsSpace(rune(line[i-1])) {
+			return strings.TrimRightFunc(line[:i], unicode.IsSpace)
+		}
+	}
+	return line
+}
+
+// WriteLines writes the lines from the given iterator to the given
+// filename atomically, adding the platform-appropriate EOL to each line
+// written. If the iterator yields an error, writing stops, filename is
+// left unchanged, and the error is returned. For example:
+//
+//	err := ufile.WriteLines(outfile, ufile.SkipBlank(ufile.StripComments(
+//		ufile.ReadUtf8Lines(infile), "#")))
+//
+// See also [WriteTextFile] and [AppendLines].
+func WriteLines(filename string, lines iter.Seq2[string, error]) error {
+	return writeAtomic(filename, func(out *bufio.Writer) error {
+		eol := platformEOL()
+		for line, err := range lines {
+			if err != nil {
+				return err
+			}
+			if _, err = out.WriteString(line); err != nil {
+				return err
+			}
+			if _, err = out.WriteString(eol); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
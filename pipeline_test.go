@@ -0,0 +1,42 @@
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func Test_LinePipeline(t *testing.T) {
+	dir := t.TempDir()
+	infile := filepath.Join(dir, "in.conf")
+	outfile := filepath.Join(dir, "out.conf")
+	if err := WriteTextFile(infile, []string{"# header", "", "a = 1 # one",
+		"  // not a comment here", "url = a.html#top", "   ", "\t# note",
+		"b = 2"}); err != nil {
+		t.Fatal(err)
+	}
+	lines := MapLines(SkipBlank(StripComments(ReadUtf8Lines(infile), "#")),
+		strings.ToUpper)
+	lines = FilterLines(lines, func(line string) bool {
+		return !strings.HasPrefix(strings.TrimSpace(line), "//")
+	})
+	if err := WriteLines(outfile, lines); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadTextFile(outfile)
+	expected := []string{"A = 1", "URL = A.HTML#TOP", "B = 2"}
+	if err != nil || slices.Compare(got, expected) != 0 {
+		t.Errorf("expected %q got %q %v", expected, got, err)
+	}
+	err = WriteLines(outfile, SkipBlank(ReadUtf8Lines(filepath.Join(dir,
+		"missing"))))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist got %v", err)
+	}
+	if got, _ = ReadTextFile(outfile); slices.Compare(got, expected) != 0 {
+		t.Errorf("expected unchanged %q got %q", expected, got)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"fmt"
+	"os"
+)
+
+// Preallocate makes the given file (which is created if it doesn't exist)
+// at least size bytes long, with disk space reserved for the whole size
+// where the platform supports it: fallocate(2) on Linux, F_PREALLOCATE on
+// macOS, and SetEndOfFile on Windows. This lets downloads and database
+// files fail early if there isn't enough space, and reduces
+// fragmentation. Elsewhere (or if the filesystem doesn't support
+// preallocation) the file is extended without reserving space. Files
+// larger than size are left unchanged. See also [TruncateTo].
+func Preallocate(path string, size int64) error {
+	if size < 0 {
+		return fmt.Errorf("invalid size %d", size)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, modeDefault)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() >= size {
+		return nil
+	}
+	if err = preallocate(file, info.Size(), size); err != nil {
+		if err = file.Truncate(size); err != nil {
+			return err
+		}
+	}
+	return file.Close()
+}
+
+// TruncateTo changes the size of the given existing file to size bytes,
+// discarding the data beyond size if it is smaller than the file, or
+// extending the file with zero bytes (typically as a hole; see
+// [IsSparse]) if it is larger. See also [Preallocate].
+func TruncateTo(path string, size int64) error {
+	if size < 0 {
+		return fmt.Errorf("invalid size %d", size)
+	}
+	return os.Truncate(path, size)
+}
@@ -0,0 +1,26 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// preallocate extends file from size to newSize bytes with the space
+// allocated using F_PREALLOCATE, preferably contiguously.
+func preallocate(file *os.File, size, newSize int64) error {
+	store := unix.Fstore_t{Flags: unix.F_ALLOCATECONTIG |
+		unix.F_ALLOCATEALL, Posmode: unix.F_PEOFPOSMODE,
+		Length: newSize - size}
+	err := unix.FcntlFstore(file.Fd(), unix.F_PREALLOCATE, &store)
+	if err != nil { // contiguous space isn't available
+		store.Flags = unix.F_ALLOCATEALL
+		if err = unix.FcntlFstore(file.Fd(), unix.F_PREALLOCATE,
+			&store); err != nil {
+			return err
+		}
+	}
+	return file.Truncate(newSize)
+}
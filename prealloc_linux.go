@@ -0,0 +1,15 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// preallocate extends file from size to newSize bytes with the space
+// allocated using fallocate(2).
+func preallocate(file *os.File, size, newSize int64) error {
+	return unix.Fallocate(int(file.Fd()), 0, size, newSize-size)
+}
@@ -0,0 +1,16 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build !linux && !darwin
+
+package ufile
+
+import (
+	"errors"
+	"os"
+)
+
+// preallocate always fails so that the file is extended by truncation,
+// which on Windows (SetEndOfFile) allocates the space.
+func preallocate(file *os.File, size, newSize int64) error {
+	return errors.ErrUnsupported
+}
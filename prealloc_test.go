@@ -0,0 +1,37 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Preallocate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.bin")
+	if err := Preallocate(filename, 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	size, allocated, err := ApparentAndAllocatedSize(filename)
+	if err != nil || size != 1<<20 {
+		t.Errorf("expected %d got %d %v", 1<<20, size, err)
+	}
+	if allocated < size {
+		t.Logf("space not reserved (%d of %d)", allocated, size)
+	}
+	if err = Preallocate(filename, 10); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filename); info.Size() != 1<<20 {
+		t.Errorf("expected unchanged size got %d", info.Size())
+	}
+	if err = TruncateTo(filename, 10); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filename); info.Size() != 10 {
+		t.Errorf("expected 10 got %d", info.Size())
+	}
+	if err = TruncateTo(filepath.Join(filepath.Dir(filename), "missing"),
+		10); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"path/filepath"
+)
+
+// ProgressFunc is called repeatedly during long-running operations (e.g.,
+// [CopyTreeContext]) with the number of bytes processed so far, the total
+// number expected (or -1 if unknown), and the file currently being
+// processed.
+type ProgressFunc func(done, total int64, current string)
+
+// tracker accumulates progress, reports it to a (possibly nil)
+// ProgressFunc, and checks for cancellation. A nil *tracker does nothing.
+type tracker struct {
+	ctx      context.Context
+	progress ProgressFunc
+	done     int64
+	total    int64
+}
+
+func newTracker(ctx context.Context, progress ProgressFunc,
+	total int64,
+) *tracker {
+	return &tracker{ctx: ctx, progress: progress, total: total}
+}
+
+// add records that n more bytes of current have been processed and
+// returns the context's error if it has been cancelled.
+func (me *tracker) add(n int64, current string) error {
+	if me == nil {
+		return nil
+	}
+	me.done += n
+	if me.progress != nil {
+		me.progress(me.done, me.total, current)
+	}
+	return me.ctx.Err()
+}
+
+// err returns the context's error if it has been cancelled.
+func (me *tracker) err() error {
+	if me == nil {
+		return nil
+	}
+	return me.ctx.Err()
+}
+
+// reader returns reader wrapped so that reads are tracked as being from
+// current and fail once the context is cancelled.
+func (me *tracker) reader(reader io.Reader, current string) io.Reader {
+	if me == nil {
+		return reader
+	}
+	return &trackingReader{reader, me, current}
+}
+
+type trackingReader struct {
+	reader  io.Reader
+	track   *tracker
+	current string
+}
+
+func (me *trackingReader) Read(p []byte) (int, error) {
+	if err := me.track.err(); err != nil {
+		return 0, err
+	}
+	n, err := me.reader.Read(p)
+	if n > 0 {
+		if trackErr := me.track.add(int64(n), me.current); trackErr !=
+			nil && err == nil {
+			err = trackErr
+		}
+	}
+	return n, err
+}
+
+// DirSize returns the total size in bytes of the regular files in the
+// tree rooted at path (or of path itself if it is a file). Symlinks are
+// not followed. See also [DirSizeContext].
+func DirSize(path string) (int64, error) {
+	return dirSize(path, nil)
+}
+
+// DirSizeContext works like [DirSize] but stops with the context's error
+// if ctx is cancelled, and if progress is not nil calls it after each
+// file with the running total (the overall total is reported as -1).
+func DirSizeContext(ctx context.Context, path string,
+	progress ProgressFunc,
+) (int64, error) {
+	return dirSize(path, newTracker(ctx, progress, -1))
+}
+
+func dirSize(path string, track *tracker) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if !dirEntry.Type().IsRegular() {
+			return track.err()
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return track.add(info.Size(), path)
+	})
+	return size, err
+}
+
+// CopyTree copies src (a folder, regular file, or symlink, which isn't
+// followed) to dst, recursively for folders, preserving permissions and
+// modification times. Files are copied as by [CopyFile] and other kinds
+// of file (devices, sockets, etc.) are skipped. See also
+// [CopyTreeOpt], [CopyTreeContext], and [SyncDirs].
+func CopyTree(src, dst string) error {
+	return copyEntry(src, dst, nil, nil, nil)
+}
+
+// CopyTreeOptions are used by [CopyTreeOpt].
+//
+// Entries (with slash-separated paths relative to the source folder) that
+// Ignore ignores aren't copied (see [IgnoreMatcher]).
+type CopyTreeOptions struct {
+	Ignore *IgnoreMatcher
+}
+
+// CopyTreeOpt works like [CopyTree] but skips the entries that opts
+// excludes.
+func CopyTreeOpt(src, dst string, opts CopyTreeOptions) error {
+	var skip func(string, bool) bool
+	if opts.Ignore != nil {
+		skip = opts.Ignore.Match
+	}
+	return copyEntry(src, dst, nil, nil, skip)
+}
+
+// CopyTreeContext works like [CopyTree] but stops with the context's
+// error if ctx is cancelled (leaving whatever has been copied so far, but
+// no partial files), and if progress is not nil calls it as bytes are
+// copied with the total being src's [DirSize].
+func CopyTreeContext(ctx context.Context, src, dst string,
+	progress ProgressFunc,
+) error {
+	var total int64 = -1
+	if progress != nil {
+		var err error
+		if total, err = DirSizeContext(ctx, src, nil); err != nil {
+			return err
+		}
+	}
+	return copyEntry(src, dst, newTracker(ctx, progress, total), nil,
+		nil)
+}
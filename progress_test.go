@@ -0,0 +1,97 @@
+package ufile
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_DirSize_CopyTree(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	makeTestTree(t, src, map[string]string{"a.txt": "alpha",
+		"sub/b.txt": "beta", "sub/deeper/c.txt": strings.Repeat("c", 1e5)})
+	size, err := DirSize(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 100009 {
+		t.Errorf("expected %d got %d", 100009, size)
+	}
+	dst := filepath.Join(dir, "dst")
+	var done, total int64
+	err = CopyTreeContext(context.Background(), src, dst,
+		func(d, t int64, _ string) { done, total = d, t })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done != size || total != size {
+		t.Errorf("expected %d/%d got %d/%d", size, size, done, total)
+	}
+	report, err := DiffDirs(src, dst, DiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Same() {
+		t.Errorf("expected same trees got %+v", report)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = CopyTreeContext(ctx, src, filepath.Join(dir, "cancelled"), nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+	if err = CopyTree(src, filepath.Join(dir, "plain")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_ChecksumContext_ZipDirContext(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	makeTestTree(t, src, map[string]string{"a.txt": "alpha",
+		"b.txt": strings.Repeat("b", 1e5)})
+	filename := filepath.Join(src, "b.txt")
+	expected, err := Checksum(filename, SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var done, total int64
+	sum, err := ChecksumContext(context.Background(), filename, SHA256,
+		func(d, t int64, _ string) { done, total = d, t })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != expected {
+		t.Errorf("expected %q got %q", expected, sum)
+	}
+	if done != 1e5 || total != 1e5 {
+		t.Errorf("expected %d/%d got %d/%d", int(1e5), int(1e5), done,
+			total)
+	}
+	zipPath := filepath.Join(dir, "src.zip")
+	err = ZipDirContext(context.Background(), src, zipPath, ZipOptions{},
+		func(d, t int64, _ string) { done, total = d, t })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done != 100005 || total != 100005 {
+		t.Errorf("expected %d/%d got %d/%d", 100005, 100005, done, total)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = ChecksumContext(ctx, filename, SHA256, nil); !errors.Is(err,
+		context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+	cancelled := filepath.Join(dir, "cancelled.zip")
+	err = ZipDirContext(ctx, src, cancelled, ZipOptions{}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+	if PathExists(cancelled) {
+		t.Error("expected no zip file after cancellation")
+	}
+}
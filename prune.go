@@ -0,0 +1,283 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PruneOptions are used by [PruneOlderThan].
+//
+// If Include is nonempty only files whose names or slash-separated paths
+// relative to the folder match one of its globs (see [filepath.Match])
+// are candidates for removal. Entries matching any of the Exclude globs
+// are never removed (and excluded folders aren't descended into). If Dirs
+// is true, folders that are (or become) empty and are themselves older
+// than the given age are removed too. If DryRun is true nothing is
+// removed but the count reports what would be.
+type PruneOptions struct {
+	Include []string
+	Exclude []string
+	Dirs    bool
+	DryRun  bool
+}
+
+// PruneOlderThan removes the files (including symlinks, which aren't
+// followed) in the tree rooted at folder dir that were last modified more
+// than age ago, and returns how many entries were removed. The folder
+// itself is never removed. This is useful for cleaning caches and
+// temporary folders. See also [PruneEmptyDirs].
+func PruneOlderThan(dir string, age time.Duration, opts PruneOptions,
+) (removed int, err error) {
+	dir = filepath.Clean(dir)
+	cutoff := time.Now().Add(-age)
+	gone := map[string]bool{}
+	dirTimes := map[string]time.Time{} // before any removals
+	err = filepath.WalkDir(dir, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchesAnyGlob(opts.Exclude, dirEntry.Name(), rel) {
+			if dirEntry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		if dirEntry.IsDir() {
+			dirTimes[path] = info.ModTime()
+			return nil
+		}
+		if len(opts.Include) > 0 &&
+			!matchesAnyGlob(opts.Include, dirEntry.Name(), rel) {
+			return nil
+		}
+		if info.ModTime().Before(cutoff) {
+			if !opts.DryRun {
+				if err = os.Remove(path); err != nil {
+					return err
+				}
+			}
+			gone[path] = true
+			removed++
+		}
+		return nil
+	})
+	if err != nil || !opts.Dirs {
+		return removed, err
+	}
+	count, err := removeEmptyDirs(dir, false, opts.DryRun, gone,
+		func(path string, _ fs.FileInfo) bool {
+			modTime, ok := dirTimes[path] // excluded folders aren't present
+			return ok && modTime.Before(cutoff)
+		})
+	return removed + count, err
+}
+
+// PruneEmptyDirs removes every empty folder below root, bottom-up (so a
+// folder containing only empty folders is removed too), and returns how
+// many were removed. Root itself is kept. See also [PruneEmptyDirsOpt].
+func PruneEmptyDirs(root string) (int, error) {
+	return PruneEmptyDirsOpt(root, false)
+}
+
+// PruneEmptyDirsOpt works like [PruneEmptyDirs] and if includeRoot is true
+// also removes root if it is (or becomes) empty.
+func PruneEmptyDirsOpt(root string, includeRoot bool) (int, error) {
+	return removeEmptyDirs(filepath.Clean(root), includeRoot, false,
+		map[string]bool{}, nil)
+}
+
+// removeEmptyDirs removes the folders below root (and root itself if
+// includeRoot is true), bottom-up, that are empty, or that only contain
+// entries in gone, and for which prunable (if not nil) returns true. It
+// adds the removed folders to gone and returns how many there were. If
+// dryRun is true nothing is removed.
+func removeEmptyDirs(root string, includeRoot, dryRun bool,
+	gone map[string]bool, prunable func(string, fs.FileInfo) bool,
+) (int, error) {
+	removed := 0
+	var visit func(dir string) (bool, error)
+	visit = func(dir string) (bool, error) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return false, err
+		}
+		empty := true
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if gone[path] {
+				continue
+			}
+			if entry.IsDir() {
+				isEmpty, err := visit(path)
+				if err != nil {
+					return false, err
+				}
+				if isEmpty {
+					ok, err := removeEmptyDir(path, dryRun, prunable)
+					if err != nil {
+						return false, err
+					}
+					if ok {
+						gone[path] = true
+						removed++
+						continue
+					}
+				}
+			}
+			empty = false
+		}
+		return empty, nil
+	}
+	empty, err := visit(root)
+	if err != nil || !empty || !includeRoot {
+		return removed, err
+	}
+	ok, err := removeEmptyDir(root, dryRun, prunable)
+	if ok {
+		gone[root] = true
+		removed++
+	}
+	return removed, err
+}
+
+// removeEmptyDir removes the given empty folder (unless dryRun is true) if
+// prunable is nil or returns true for it, and returns whether it was (or
+// would be) removed.
+func removeEmptyDir(dir string, dryRun bool,
+	prunable func(string, fs.FileInfo) bool,
+) (bool, error) {
+	if prunable != nil {
+		info, err := os.Lstat(dir)
+		if err != nil {
+			return false, err
+		}
+		if !prunable(dir, info) {
+			return false, nil
+		}
+	}
+	if dryRun {
+		return true, nil
+	}
+	return true, os.Remove(dir)
+}
+
+// ErrRemoveRefused is returned (wrapped) by [RemoveTreeSafe] when asked to
+// remove a path its guardrails forbid.
+var ErrRemoveRefused = errors.New("refusing to remove")
+
+// RemoveOptions are used by [RemoveTreeSafe].
+//
+// If MinDepth is greater than 0 paths with fewer components (after
+// resolving symlinks in the path's folder and ignoring any volume name)
+// are refused, e.g., with a MinDepth of 3, `/home/mark/tmp` is allowed but
+// `/home/mark` is refused. If AllowedBase is nonempty paths not strictly
+// inside it are refused. If DryRun is true the checks are done but
+// nothing is removed.
+type RemoveOptions struct {
+	MinDepth    int
+	AllowedBase string
+	DryRun      bool
+}
+
+// RemoveTreeSafe removes path and anything it contains like
+// [os.RemoveAll], but first refuses to remove a filesystem root, the
+// user's home folder (or any folder containing it), the current folder
+// (or any folder containing it), or anything forbidden by opts. A symlink
+// is removed rather than what it points to.
+func RemoveTreeSafe(path string, opts RemoveOptions) error {
+	resolved, err := resolveParent(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // nothing to remove
+		}
+		return err
+	}
+	refuse := func(why string) error {
+		return fmt.Errorf("%w %q: %s", ErrRemoveRefused, path, why)
+	}
+	volume := filepath.VolumeName(resolved)
+	rest := strings.Trim(resolved[len(volume):], `/\`)
+	if rest == "" {
+		return refuse("filesystem root")
+	}
+	depth := len(strings.FieldsFunc(rest, func(c rune) bool {
+		return c == '/' || c == os.PathSeparator
+	}))
+	if opts.MinDepth > 0 && depth < opts.MinDepth {
+		return refuse(fmt.Sprintf("depth %d < %d", depth, opts.MinDepth))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if home, err = resolveParent(home); err == nil &&
+			isWithin(home, resolved, true) {
+			return refuse("home folder")
+		}
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if cwd, err = resolveParent(cwd); err == nil &&
+			isWithin(cwd, resolved, true) {
+			return refuse("current folder")
+		}
+	}
+	if opts.AllowedBase != "" {
+		base, err := resolveParent(opts.AllowedBase)
+		if err != nil {
+			return err
+		}
+		if !isWithin(resolved, base, false) {
+			return refuse("outside " + opts.AllowedBase)
+		}
+	}
+	if opts.DryRun {
+		return nil
+	}
+	return removeAll(resolved)
+}
+
+// resolveParent returns path made absolute with any symlinks in its
+// folder resolved (but not path itself, which may be a symlink).
+func resolveParent(path string) (string, error) {
+	path = AbsPath(path)
+	dir, name := filepath.Split(path)
+	if name == "" {
+		return path, nil // root
+	}
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// isWithin returns true if path is inside folder dir (or is dir if orSame
+// is true); otherwise returns false.
+func isWithin(path, dir string, orSame bool) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	if rel == "." {
+		return orSame
+	}
+	return filepath.IsLocal(rel)
+}
@@ -0,0 +1,126 @@
+package ufile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_PruneOlderThan(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"old.tmp": "", "old.log": "",
+		"new.tmp": "", "sub/old.tmp": "", "keep/old.tmp": "",
+		"empty/": ""})
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"old.tmp", "old.log", "sub/old.tmp",
+		"keep/old.tmp", "sub", "keep", "empty"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts := PruneOptions{Include: []string{"*.tmp"},
+		Exclude: []string{"keep"}, Dirs: true, DryRun: true}
+	before, err := TreeString(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := PruneOlderThan(dir, 24*time.Hour, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 4 { // old.tmp, sub/old.tmp, sub, empty
+		t.Errorf("expected 4 got %d", removed)
+	}
+	if after, _ := TreeString(dir); after != before {
+		t.Errorf("expected dry run to change nothing got\n%s", after)
+	}
+	opts.DryRun = false
+	if removed, err = PruneOlderThan(dir, 24*time.Hour, opts); err != nil {
+		t.Fatal(err)
+	}
+	if removed != 4 {
+		t.Errorf("expected 4 got %d", removed)
+	}
+	expected := `.
+├── keep/
+│   └── old.tmp
+├── new.tmp
+└── old.log
+`
+	if tree, _ := TreeString(dir); tree != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, tree)
+	}
+}
+
+func Test_PruneEmptyDirs(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "root")
+	makeTestTree(t, root, map[string]string{"a/b/c/": "", "a/d/": "",
+		"e/f.txt": "f", "e/g/": ""})
+	removed, err := PruneEmptyDirs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 5 { // a/b/c, a/b, a/d, a, e/g
+		t.Errorf("expected 5 got %d", removed)
+	}
+	expected := ".\n└── e/\n    └── f.txt\n"
+	if tree, _ := TreeString(root); tree != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, tree)
+	}
+	if err = os.Remove(filepath.Join(root, "e", "f.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if removed, err = PruneEmptyDirsOpt(root, true); err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 || PathExists(root) {
+		t.Errorf("expected root removed got %d", removed)
+	}
+}
+
+func Test_RemoveTreeSafe(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base")
+	makeTestTree(t, dir, map[string]string{"base/a/b.txt": "b",
+		"base/c.txt": "c", "other/d.txt": "d"})
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home folder")
+	}
+	refused := []string{string(filepath.Separator), home,
+		filepath.Dir(home), ".", filepath.Join(dir, "other"), base}
+	opts := RemoveOptions{AllowedBase: base}
+	for _, path := range refused {
+		if err = RemoveTreeSafe(path, opts); !errors.Is(err,
+			ErrRemoveRefused) {
+			t.Errorf("%q: expected %v got %v", path, ErrRemoveRefused,
+				err)
+		}
+	}
+	target := filepath.Join(base, "a")
+	rest := target[len(filepath.VolumeName(target)):]
+	depth := len(strings.Split(strings.Trim(filepath.ToSlash(rest), "/"),
+		"/"))
+	opts.MinDepth = depth + 1
+	if err = RemoveTreeSafe(target, opts); !errors.Is(err,
+		ErrRemoveRefused) {
+		t.Errorf("expected %v got %v", ErrRemoveRefused, err)
+	}
+	opts.MinDepth = depth
+	opts.DryRun = true
+	if err = RemoveTreeSafe(target, opts); err != nil || !IsDir(target) {
+		t.Errorf("expected dry run to succeed without removing: %v", err)
+	}
+	opts.DryRun = false
+	if err = RemoveTreeSafe(target, opts); err != nil || PathExists(target) {
+		t.Errorf("expected %q to be removed: %v", target, err)
+	}
+	if err = RemoveTreeSafe(filepath.Join(base, "missing", "x"),
+		opts); err != nil {
+		t.Errorf("expected nil got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrRenameCollision is returned (wrapped) by [RenameBatch] when two
+// renames have the same destination or a destination exists and isn't
+// itself being renamed.
+var ErrRenameCollision = errors.New("rename collision")
+
+// RenamePair is a single rename used by [RenameBatch].
+type RenamePair struct {
+	From string
+	To   string
+}
+
+// RenameOptions are used by [RenameBatch].
+//
+// If Overwrite is true existing destinations that aren't themselves being
+// renamed are replaced rather than treated as collisions. If DryRun is
+// true the renames are checked but not done.
+type RenameOptions struct {
+	Overwrite bool
+	DryRun    bool
+}
+
+// RenameBatch renames every pair's From to its To as a batch: all the
+// pairs are checked for collisions before anything is renamed, and the
+// renames are done in two phases (every From is first renamed to a
+// temporary name) so that swaps and cycles (e.g., a→b, b→a) work. If a
+// rename fails the renames done so far are undone (as far as possible),
+// and any errors from undoing them are joined to the returned error.
+// See also [PlanRename].
+func RenameBatch(pairs []RenamePair, opts RenameOptions) error {
+	pairs, err := checkRenames(pairs, opts.Overwrite)
+	if err != nil || opts.DryRun {
+		return err
+	}
+	temps := make([]string, 0, len(pairs))
+	undo := func(err error) error {
+		errs := []error{err}
+		for i := len(temps) - 1; i >= 0; i-- {
+			errs = append(errs, rename(temps[i], pairs[i].From))
+		}
+		return errors.Join(errs...)
+	}
+	for _, pair := range pairs {
+		temp := filepath.Join(filepath.Dir(pair.From),
+			"."+filepath.Base(pair.From)+"."+strconv.Itoa(os.Getpid())+
+				".ren")
+		if err = rename(pair.From, temp); err != nil {
+			return undo(err)
+		}
+		temps = append(temps, temp)
+	}
+	for i, pair := range pairs {
+		if err = rename(temps[i], pair.To); err != nil {
+			errs := []error{err}
+			for j := i - 1; j >= 0; j-- {
+				errs = append(errs, rename(pairs[j].To, temps[j]))
+			}
+			return undo(errors.Join(errs...))
+		}
+	}
+	return nil
+}
+
+// checkRenames returns the pairs with no-op renames removed or an error if
+// any of them collide.
+func checkRenames(pairs []RenamePair, overwrite bool) ([]RenamePair,
+	error,
+) {
+	froms := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		from := AbsPath(pair.From)
+		if froms[from] {
+			return nil, fmt.Errorf("%w: %q renamed more than once",
+				ErrRenameCollision, pair.From)
+		}
+		froms[from] = true
+	}
+	tos := make(map[string]bool, len(pairs))
+	result := make([]RenamePair, 0, len(pairs))
+	for _, pair := range pairs {
+		if _, err := os.Lstat(pair.From); err != nil {
+			return nil, err
+		}
+		to := AbsPath(pair.To)
+		if tos[to] {
+			return nil, fmt.Errorf("%w: more than one rename to %q",
+				ErrRenameCollision, pair.To)
+		}
+		tos[to] = true
+		if to == AbsPath(pair.From) {
+			continue // nothing to do
+		}
+		if !overwrite && !froms[to] && PathExists(pair.To) {
+			return nil, fmt.Errorf("%w: %q exists", ErrRenameCollision,
+				pair.To)
+		}
+		result = append(result, pair)
+	}
+	return result, nil
+}
+
+// PlanRename returns a rename pair for each of the given files with each
+// new name made from the template (and in the same folder as the file).
+// The template may contain these tokens:
+//
+//   - {n} the file's 1-based position in files; {n:3} pads it with
+//     leading zeros to a width of 3 (or whatever width is given)
+//   - {stem} the file's name without its last suffix
+//   - {ext} the file's last suffix including the ".", e.g., ".txt" (or ""
+//     if it has none, as for a dotfile like ".bashrc"; see [SplitExt])
+//   - {date} the file's modification date as YYYY-MM-DD
+//
+// For example, "photo-{n:3}{ext}" renames "IMG_1234.JPG" and
+// "IMG_1240.JPG" to "photo-001.JPG" and "photo-002.JPG". Use
+// [RenameBatch] to do the renames.
+func PlanRename(files []string, template string) ([]RenamePair, error) {
+	rx := regexp.MustCompile(`\{[^{}]*\}`)
+	pairs := make([]RenamePair, 0, len(files))
+	for i, file := range files {
+		var err error
+		name := rx.ReplaceAllStringFunc(template, func(token string) string {
+			text, tokenErr := renameToken(token[1:len(token)-1], i+1, file)
+			if tokenErr != nil && err == nil {
+				err = tokenErr
+			}
+			return text
+		})
+		if err != nil {
+			return nil, err
+		}
+		if name == "" || strings.ContainsAny(name, `/\`) {
+			return nil, fmt.Errorf("invalid name %q from template %q",
+				name, template)
+		}
+		pairs = append(pairs, RenamePair{From: file,
+			To: filepath.Join(filepath.Dir(file), name)})
+	}
+	return pairs, nil
+}
+
+func renameToken(token string, n int, file string) (string, error) {
+	stem, ext := SplitExt(filepath.Base(file))
+	switch {
+	case token == "n":
+		return strconv.Itoa(n), nil
+	case strings.HasPrefix(token, "n:"):
+		width, err := strconv.Atoi(token[2:])
+		if err != nil || width < 1 {
+			return "", fmt.Errorf("invalid rename token {%s}", token)
+		}
+		return fmt.Sprintf("%0*d", width, n), nil
+	case token == "stem":
+		return stem, nil
+	case token == "ext":
+		return ext, nil
+	case token == "date":
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		return info.ModTime().Format("2006-01-02"), nil
+	}
+	return "", fmt.Errorf("unknown rename token {%s}", token)
+}
@@ -0,0 +1,80 @@
+package ufile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_PlanRename(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"IMG_1234.JPG": "1",
+		"notes.txt": "2"})
+	date := time.Date(2024, 3, 9, 12, 0, 0, 0, time.Local)
+	files := []string{filepath.Join(dir, "IMG_1234.JPG"),
+		filepath.Join(dir, "notes.txt")}
+	for _, file := range files {
+		if err := os.Chtimes(file, date, date); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pairs, err := PlanRename(files, "{date}-{n:3}-{stem}{ext}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"2024-03-09-001-IMG_1234.JPG",
+		"2024-03-09-002-notes.txt"}
+	for i, pair := range pairs {
+		if pair.From != files[i] ||
+			pair.To != filepath.Join(dir, expected[i]) {
+			t.Errorf("expected %q got %q", expected[i], pair.To)
+		}
+	}
+	dotfile := filepath.Join(dir, ".bashrc")
+	pairs, err = PlanRename([]string{dotfile}, "{stem}.bak{ext}")
+	if err != nil || pairs[0].To != dotfile+".bak" {
+		t.Errorf("expected %q got %v %v", dotfile+".bak", pairs, err)
+	}
+	if _, err = PlanRename(files, "{bad}"); err == nil {
+		t.Error("expected error for unknown token")
+	}
+	if _, err = PlanRename(files, "{n:x}"); err == nil {
+		t.Error("expected error for invalid width")
+	}
+}
+
+func Test_RenameBatch(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"a": "A", "b": "B", "c": "C"})
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	c := filepath.Join(dir, "c")
+	err := RenameBatch([]RenamePair{{a, c}}, RenameOptions{})
+	if !errors.Is(err, ErrRenameCollision) {
+		t.Errorf("expected %v got %v", ErrRenameCollision, err)
+	}
+	err = RenameBatch([]RenamePair{{a, b}, {c, b}}, RenameOptions{})
+	if !errors.Is(err, ErrRenameCollision) {
+		t.Errorf("expected %v got %v", ErrRenameCollision, err)
+	}
+	swap := []RenamePair{{a, b}, {b, a}}
+	if err = RenameBatch(swap, RenameOptions{DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err = RenameBatch(swap, RenameOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	contents := map[string]string{a: "B", b: "A", c: "C"}
+	for name, content := range contents {
+		if lines, err := ReadTextFile(name); err != nil ||
+			lines[0] != content {
+			t.Errorf("expected %q got %q %v", content, lines, err)
+		}
+	}
+	expected := ".\n├── a\n├── b\n└── c\n" // no temporaries left
+	if tree, _ := TreeString(dir); tree != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, tree)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"os"
+	"runtime"
+	"time"
+)
+
+// RetryOptions are used by [RetryOnBusy].
+//
+// Attempts is the maximum number of times to call the operation (or 8 if
+// less than 1). Delay is how long to wait before the first retry (or 10ms
+// if 0); the delay doubles for each subsequent retry.
+type RetryOptions struct {
+	Attempts int
+	Delay    time.Duration
+}
+
+// RetryOnBusy calls op and if it fails because a file is busy (see
+// below) calls it again after a delay, up to the number of attempts given
+// by opts, returning the last error. Any other error is returned
+// immediately. On Windows a file is busy if the error is a sharing,
+// lock, or access violation, which are often caused transiently by
+// antivirus software and indexers holding files open; on Unix if it is
+// EBUSY or ETXTBSY. The package's own renames and removals (e.g., in
+// [CopyFile], [Move], [RemoveTreeSafe], and atomic writes) use this by
+// default on Windows.
+func RetryOnBusy(op func() error, opts RetryOptions) error {
+	attempts := opts.Attempts
+	if attempts < 1 {
+		attempts = 8
+	}
+	delay := opts.Delay
+	if delay == 0 {
+		delay = 10 * time.Millisecond
+	}
+	err := op()
+	for i := 1; i < attempts && err != nil && isBusy(err); i++ {
+		time.Sleep(delay)
+		delay *= 2
+		err = op()
+	}
+	return err
+}
+
+// retryOnWindows calls op using [RetryOnBusy] with the default options on
+// Windows, and just calls it once elsewhere.
+func retryOnWindows(op func() error) error {
+	if runtime.GOOS == "windows" {
+		return RetryOnBusy(op, RetryOptions{})
+	}
+	return op()
+}
+
+// rename works like [os.Rename] but retries busy files on Windows.
+func rename(oldpath, newpath string) error {
+	return retryOnWindows(func() error { return os.Rename(oldpath, newpath) })
+}
+
+// removeAll works like [os.RemoveAll] but retries busy files on Windows.
+func removeAll(path string) error {
+	return retryOnWindows(func() error { return os.RemoveAll(path) })
+}
@@ -0,0 +1,13 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build !unix && !windows
+
+package ufile
+
+// isBusy always returns false since there's no way to tell on this
+// platform.
+func isBusy(err error) bool { return false }
+
+// isCrossDevice always returns true since there's no way to tell on this
+// platform, so a failed rename is always retried as a copy.
+func isCrossDevice(err error) bool { return true }
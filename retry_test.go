@@ -0,0 +1,44 @@
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_RetryOnBusy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix errors")
+	}
+	calls := 0
+	err := RetryOnBusy(func() error {
+		calls++
+		if calls < 3 {
+			return &fs.PathError{Op: "rename", Path: "x",
+				Err: syscall.EBUSY}
+		}
+		return nil
+	}, RetryOptions{Delay: time.Millisecond})
+	if err != nil || calls != 3 {
+		t.Errorf("expected 3 calls got %d %v", calls, err)
+	}
+	calls = 0
+	err = RetryOnBusy(func() error {
+		calls++
+		return syscall.EBUSY
+	}, RetryOptions{Attempts: 4, Delay: time.Millisecond})
+	if !errors.Is(err, syscall.EBUSY) || calls != 4 {
+		t.Errorf("expected 4 calls got %d %v", calls, err)
+	}
+	calls = 0
+	err = RetryOnBusy(func() error {
+		calls++
+		return fs.ErrNotExist
+	}, RetryOptions{})
+	if !errors.Is(err, fs.ErrNotExist) || calls != 1 {
+		t.Errorf("expected 1 call got %d %v", calls, err)
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build unix
+
+package ufile
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isBusy returns true if err is EBUSY or ETXTBSY; otherwise returns false.
+func isBusy(err error) bool {
+	return errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.ETXTBSY)
+}
+
+// isCrossDevice returns true if err is EXDEV, i.e., a rename failed
+// because the paths are on different filesystems; otherwise returns false.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
@@ -0,0 +1,24 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// isBusy returns true if err is a transient sharing, lock, or access
+// violation; otherwise returns false.
+func isBusy(err error) bool {
+	return errors.Is(err, windows.ERROR_SHARING_VIOLATION) ||
+		errors.Is(err, windows.ERROR_LOCK_VIOLATION) ||
+		errors.Is(err, windows.ERROR_ACCESS_DENIED)
+}
+
+// isCrossDevice returns true if err is ERROR_NOT_SAME_DEVICE, i.e., a
+// rename failed because the paths are on different volumes; otherwise
+// returns false.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, windows.ERROR_NOT_SAME_DEVICE)
+}
@@ -0,0 +1,168 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// RotatingWriter is an [io.WriteCloser] that appends to a file and when
+// the file would exceed its maximum size rotates it, i.e., renames name to
+// name.1, name.1 to name.2.gz (compressing it), name.2.gz to name.3.gz,
+// and so on, keeping at most keep old files. It is safe for concurrent
+// use. Create with [NewRotatingWriter].
+type RotatingWriter struct {
+	mutex   sync.Mutex
+	path    string
+	maxSize int64
+	keep    int
+	file    *os.File
+	size    int64
+}
+
+// NewRotatingWriter returns a new RotatingWriter that appends to path
+// (creating it with [ModeURW] permissions if necessary) and rotates when
+// a write would make the file exceed maxSize bytes, keeping at most keep
+// old files.
+func NewRotatingWriter(path string, maxSize int64, keep int) (
+	*RotatingWriter, error,
+) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("invalid maximum size %d", maxSize)
+	}
+	writer := &RotatingWriter{path: path, maxSize: maxSize,
+		keep: max(0, keep)}
+	if err := writer.open(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write writes p to the current file, first rotating if p would make the
+// file exceed the maximum size. A single write is never split across
+// files.
+func (me *RotatingWriter) Write(p []byte) (int, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.file == nil {
+		return 0, os.ErrClosed
+	}
+	if me.size > 0 && me.size+int64(len(p)) > me.maxSize {
+		if err := me.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := me.file.Write(p)
+	me.size += int64(n)
+	return n, err
+}
+
+// Rotate forces a rotation.
+func (me *RotatingWriter) Rotate() error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.file == nil {
+		return os.ErrClosed
+	}
+	return me.rotate()
+}
+
+// Close closes the current file.
+func (me *RotatingWriter) Close() error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.file == nil {
+		return os.ErrClosed
+	}
+	err := me.file.Close()
+	me.file = nil
+	return err
+}
+
+func (me *RotatingWriter) open() error {
+	file, err := os.OpenFile(me.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE,
+		ModeURW)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	me.file = file
+	me.size = info.Size()
+	return nil
+}
+
+func (me *RotatingWriter) rotate() error {
+	if err := me.file.Close(); err != nil {
+		return err
+	}
+	me.file = nil
+	if err := me.shift(); err != nil {
+		_ = me.open() // keep writing to the unrotated file if possible
+		return err
+	}
+	return me.open()
+}
+
+func (me *RotatingWriter) shift() error {
+	if me.keep == 0 {
+		return os.Remove(me.path)
+	}
+	_ = os.Remove(me.rotatedName(me.keep))
+	for i := me.keep - 1; i >= 2; i-- {
+		name := me.rotatedName(i)
+		if PathExists(name) {
+			if err := rename(name, me.rotatedName(i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	if me.keep >= 2 {
+		name := me.rotatedName(1)
+		if PathExists(name) {
+			if err := gzipFile(name, me.rotatedName(2)); err != nil {
+				return err
+			}
+			if err := os.Remove(name); err != nil {
+				return err
+			}
+		}
+	}
+	return rename(me.path, me.rotatedName(1))
+}
+
+func (me *RotatingWriter) rotatedName(i int) string {
+	if i == 1 {
+		return fmt.Sprintf("%s.1", me.path)
+	}
+	return fmt.Sprintf("%s.%d.gz", me.path, i)
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC,
+		ModeURW)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	gzwriter := gzip.NewWriter(out)
+	if _, err = io.Copy(gzwriter, in); err != nil {
+		return err
+	}
+	if err = gzwriter.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,46 @@
+package ufile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_RotatingWriter(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	writer, err := NewRotatingWriter(filename, 20, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 10 {
+		if _, err := fmt.Fprintf(writer, "line %d\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	expected := []string{"app.log", "app.log.1", "app.log.2.gz",
+		"app.log.3.gz"}
+	if slices.Compare(names, expected) != 0 {
+		t.Errorf("expected %q got %q", expected, names)
+	}
+	lines, err := ReadTextFile(filename)
+	expected = []string{"line 8", "line 9"}
+	if err != nil || slices.Compare(lines, expected) != 0 {
+		t.Errorf("expected %q got %q %v", expected, lines, err)
+	}
+	lines, err = ReadTextFile(filename + ".2.gz")
+	expected = []string{"line 4", "line 5"}
+	if err != nil || slices.Compare(lines, expected) != 0 {
+		t.Errorf("expected %q got %q %v", expected, lines, err)
+	}
+}
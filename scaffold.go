@@ -0,0 +1,124 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExistingPolicy determines what [RenderTree] does with files that
+// already exist.
+type ExistingPolicy uint8
+
+const (
+	ExistingSkip      ExistingPolicy = iota // leave the existing file
+	ExistingOverwrite                       // replace the existing file
+	ExistingFail                            // fail with fs.ErrExist
+)
+
+// RenderOptions are used by [RenderTree].
+//
+// Existing determines what happens to files that already exist (by
+// default they are left untouched). If TemplateExt is nonempty (e.g.,
+// ".tmpl") only files with that suffix are treated as templates (and the
+// suffix is removed from their names) and other files are copied as is;
+// otherwise every file is treated as a template.
+type RenderOptions struct {
+	Existing    ExistingPolicy
+	TemplateExt string
+}
+
+// RenderTree copies the template tree in src (e.g., an [embed.FS]; use
+// [fs.Sub] for a subfolder) into folder dstDir (which is created if
+// necessary), executing file contents as [text/template] templates with
+// the given data (see [RenderOptions]). File and folder names are
+// templates too, e.g., "cmd/{{.Name}}/main.go" (and may use
+// {{env "NAME"}} as for [ApplyManifest]). Files are written atomically
+// and are given 0755 permissions if their source is executable (and
+// 0644 otherwise). A name that renders as empty or that would escape
+// dstDir causes [ErrUnsafePath] to be returned.
+func RenderTree(src fs.FS, dstDir string, data any,
+	opts RenderOptions,
+) error {
+	if err := EnsureDir(dstDir, 0o755); err != nil {
+		return err
+	}
+	return fs.WalkDir(src, ".", func(name string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		isTemplate := !dirEntry.IsDir() && (opts.TemplateExt == "" ||
+			strings.HasSuffix(name, opts.TemplateExt))
+		rel := name
+		if isTemplate && opts.TemplateExt != "" {
+			rel = strings.TrimSuffix(name, opts.TemplateExt)
+		}
+		if rel, err = renderString(name, rel, data); err != nil {
+			return err
+		}
+		rel = filepath.FromSlash(rel)
+		if !filepath.IsLocal(rel) ||
+			strings.HasSuffix(rel, string(filepath.Separator)) {
+			return fmt.Errorf("%w: %q renders as %q", ErrUnsafePath, name,
+				rel)
+		}
+		target := filepath.Join(dstDir, rel)
+		if dirEntry.IsDir() {
+			return EnsureDir(target, 0o755)
+		}
+		if !dirEntry.Type().IsRegular() {
+			return nil // skip symlinks, etc.
+		}
+		if PathExists(target) {
+			switch opts.Existing {
+			case ExistingSkip:
+				return nil
+			case ExistingFail:
+				return &fs.PathError{Op: "render", Path: target,
+					Err: fs.ErrExist}
+			}
+		}
+		return renderTreeFile(src, name, target, data, isTemplate, dirEntry)
+	})
+}
+
+// renderTreeFile atomically writes target from src's named file, rendered
+// if isTemplate is true, and sets target's permissions.
+func renderTreeFile(src fs.FS, name, target string, data any,
+	isTemplate bool, dirEntry fs.DirEntry,
+) error {
+	raw, err := fs.ReadFile(src, name)
+	if err != nil {
+		return err
+	}
+	if isTemplate {
+		err = renderToFile(name, string(raw), target, data)
+	} else {
+		err = writeAtomic(target, func(out *bufio.Writer) error {
+			_, err := out.Write(raw)
+			return err
+		})
+	}
+	if err != nil {
+		return err
+	}
+	info, err := dirEntry.Info()
+	if err != nil {
+		return err
+	}
+	var perm fs.FileMode = modeDefault
+	if info.Mode().Perm()&0o111 != 0 {
+		perm = 0o755
+	}
+	return os.Chmod(target, perm)
+}
@@ -0,0 +1,72 @@
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func Test_RenderTree(t *testing.T) {
+	src := fstest.MapFS{
+		"go.mod.tmpl":                {Data: []byte("module {{.Name}}\n")},
+		"cmd/{{.Name}}/main.go.tmpl": {Data: []byte("// {{.Name}}\n")},
+		"logo.png":                   {Data: []byte("{{raw}}")},
+		"run.sh":                     {Data: []byte("#!/bin/sh"), Mode: 0o755},
+	}
+	dst := filepath.Join(t.TempDir(), "proj")
+	data := map[string]string{"Name": "demo"}
+	opts := RenderOptions{TemplateExt: ".tmpl"}
+	if err := RenderTree(src, dst, data, opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := `.
+├── cmd/
+│   └── demo/
+│       └── main.go
+├── go.mod
+├── logo.png
+└── run.sh
+`
+	if tree, _ := TreeString(dst); tree != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, tree)
+	}
+	for name, text := range map[string]string{"go.mod": "module demo\n",
+		"cmd/demo/main.go": "// demo\n", "logo.png": "{{raw}}"} {
+		raw, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(raw) != text {
+			t.Errorf("expected %q got %q", text, raw)
+		}
+	}
+	goMod := filepath.Join(dst, "go.mod")
+	if err := os.WriteFile(goMod, []byte("edited"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RenderTree(src, dst, data, opts); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := os.ReadFile(goMod); string(raw) != "edited" {
+		t.Errorf("expected existing file skipped got %q", raw)
+	}
+	opts.Existing = ExistingFail
+	if err := RenderTree(src, dst, data, opts); !errors.Is(err,
+		fs.ErrExist) {
+		t.Errorf("expected ErrExist got %v", err)
+	}
+	opts.Existing = ExistingOverwrite
+	if err := RenderTree(src, dst, data, opts); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := os.ReadFile(goMod); string(raw) != "module demo\n" {
+		t.Errorf("expected overwritten file got %q", raw)
+	}
+	err := RenderTree(src, dst, map[string]string{"Name": "../../x"}, opts)
+	if !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("expected ErrUnsafePath got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"io"
+	"iter"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Match holds a line that matched in [GrepFiles] with its filename and
+// (1-based) line number.
+type Match struct {
+	Filename string
+	LineNo   int
+	Line     string
+}
+
+// GrepFiles returns an iterator of (match, error) for every line in every
+// file in paths that matches the pattern. Files are read a line at a time
+// using [ReadUtf8Lines] so even huge files can be searched. If a file
+// can't be read the error is yielded (with a Match containing just the
+// filename) and the search continues with the next file.
+func GrepFiles(pattern *regexp.Regexp, paths iter.Seq[string],
+) iter.Seq2[Match, error] {
+	return func(yield func(Match, error) bool) {
+		for filename := range paths {
+			lino := 0
+			for line, err := range ReadUtf8Lines(filename) {
+				if err != nil {
+					if !yield(Match{Filename: filename}, err) {
+						return
+					}
+					break // can't progress with this file
+				}
+				lino++
+				if pattern.MatchString(line) {
+					if !yield(Match{filename, lino, line}, nil) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// ReplaceOptions are used by [ReplaceInFile] and [ReplaceInFileRx].
+//
+// If Backup is true the original file is kept with a .orig suffix (any
+// existing .orig file is replaced).
+type ReplaceOptions struct {
+	Backup bool
+}
+
+// ReplaceInFile replaces every occurrence of old with new in each line of
+// the given file and returns how many replacements were made. The file is
+// streamed a line at a time (so old and new cannot span lines) to a
+// temporary file which is then atomically renamed over the original; each
+// line's original EOL is preserved. If there are no replacements the file
+// is left untouched. See also [ReplaceInFileRx].
+func ReplaceInFile(filename string, old, new string, opts ReplaceOptions) (
+	count int, err error,
+) {
+	return replaceInFile(filename, opts, func(line string) (string, int) {
+		n := strings.Count(line, old)
+		if n == 0 || old == "" {
+			return line, 0
+		}
+		return strings.ReplaceAll(line, old, new), n
+	})
+}
+
+// ReplaceInFileRx replaces every match of rx with repl (which may contain
+// $1 etc., see [regexp.Regexp.Expand]) in each line of the given file and
+// returns how many replacements were made. It works like
+// [ReplaceInFile].
+func ReplaceInFileRx(filename string, rx *regexp.Regexp, repl string,
+	opts ReplaceOptions,
+) (count int, err error) {
+	return replaceInFile(filename, opts, func(line string) (string, int) {
+		n := len(rx.FindAllStringIndex(line, -1))
+		if n == 0 {
+			return line, 0
+		}
+		return rx.ReplaceAllString(line, repl), n
+	})
+}
+
+func replaceInFile(filename string, opts ReplaceOptions,
+	replace func(string) (string, int),
+) (int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	count := 0
+	err = writeAtomic(filename, func(out *bufio.Writer) error {
+		reader := bufio.NewReader(file)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if line == "" && err == io.EOF {
+				break
+			}
+			text := strings.TrimRight(line, "\r\n")
+			eol := line[len(text):]
+			text, n := replace(text)
+			count += n
+			if _, err := out.WriteString(text); err != nil {
+				return err
+			}
+			if _, err := out.WriteString(eol); err != nil {
+				return err
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+		file.Close() // Windows can't rename over an open file
+		if count == 0 {
+			return errUnchanged
+		}
+		if opts.Backup {
+			return backupFile(filename)
+		}
+		return nil
+	})
+	if err != nil {
+		if err == errUnchanged {
+			err = nil
+		}
+		return 0, err
+	}
+	return count, nil
+}
+
+// backupFile hard links (or if that fails, copies) filename to
+// filename.orig.
+func backupFile(filename string) error {
+	backup := filename + ".orig"
+	_ = os.Remove(backup)
+	if err := os.Link(filename, backup); err != nil {
+		return copyFileData(filename, backup)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func Test_GrepFiles(t *testing.T) {
+	dir := t.TempDir()
+	one := filepath.Join(dir, "one.txt")
+	two := filepath.Join(dir, "two.txt")
+	missing := filepath.Join(dir, "missing.txt")
+	if err := WriteTextFile(one, []string{"alpha", "beta",
+		"gamma"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteTextFile(two, []string{"delta",
+		"alphabet"}); err != nil {
+		t.Fatal(err)
+	}
+	matches := []Match{}
+	errors := 0
+	for match, err := range GrepFiles(regexp.MustCompile(`^(alpha|g)`),
+		slices.Values([]string{one, missing, two})) {
+		if err != nil {
+			errors++
+			if match.Filename != missing {
+				t.Errorf("expected %q got %q", missing, match.Filename)
+			}
+			continue
+		}
+		matches = append(matches, match)
+	}
+	expected := []Match{{one, 1, "alpha"}, {one, 3, "gamma"},
+		{two, 2, "alphabet"}}
+	if errors != 1 {
+		t.Errorf("expected 1 error got %d", errors)
+	}
+	if !slices.Equal(matches, expected) {
+		t.Errorf("expected %v got %v", expected, matches)
+	}
+}
+
+func Test_ReplaceInFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "replace.txt")
+	if err := os.WriteFile(filename, []byte("cat sat\r\non the cat\nmat"),
+		ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	count, err := ReplaceInFile(filename, "cat", "dog",
+		ReplaceOptions{Backup: true})
+	if err != nil || count != 2 {
+		t.Errorf("expected 2 got %d %v", count, err)
+	}
+	raw, _ := os.ReadFile(filename)
+	if string(raw) != "dog sat\r\non the dog\nmat" {
+		t.Errorf("unexpected replacement %q", raw)
+	}
+	raw, _ = os.ReadFile(filename + ".orig")
+	if string(raw) != "cat sat\r\non the cat\nmat" {
+		t.Errorf("unexpected backup %q", raw)
+	}
+	count, err = ReplaceInFileRx(filename, regexp.MustCompile(`(\w)at`),
+		"${1}og", ReplaceOptions{})
+	if err != nil || count != 2 {
+		t.Errorf("expected 2 got %d %v", count, err)
+	}
+	raw, _ = os.ReadFile(filename)
+	if string(raw) != "dog sog\r\non the dog\nmog" {
+		t.Errorf("unexpected replacement %q", raw)
+	}
+	count, err = ReplaceInFile(filename, "zebra", "", ReplaceOptions{})
+	if err != nil || count != 0 {
+		t.Errorf("expected 0 got %d %v", count, err)
+	}
+}
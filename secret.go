@@ -0,0 +1,101 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// ErrInsecurePermissions is wrapped by the errors [WriteSecretFile] and
+// [ReadSecretFile] return when a secret file or its folder is accessible
+// by other users.
+var ErrInsecurePermissions = errors.New("insecure permissions")
+
+// SecretOptions are used by [WriteSecretFileOpt].
+//
+// If AllowLooseParent is true the secret file is written even if its
+// folder is readable or writable by other users.
+type SecretOptions struct {
+	AllowLooseParent bool
+}
+
+// WriteSecretFile atomically writes data to the given path (e.g., for
+// credentials or tokens) so that only the current user can access it. On
+// Unix the file's permissions are 0600 and an error wrapping
+// [ErrInsecurePermissions] is returned if the file's folder is readable
+// or writable by other users (as SSH requires for ~/.ssh), or is owned by
+// someone else. On Windows the file's ACL grants access to the current
+// user only. See also [WriteSecretFileOpt] and [ReadSecretFile].
+func WriteSecretFile(path string, data []byte) error {
+	return WriteSecretFileOpt(path, data, SecretOptions{})
+}
+
+// WriteSecretFileOpt works like [WriteSecretFile] but with the given
+// options.
+func WriteSecretFileOpt(path string, data []byte,
+	opts SecretOptions,
+) error {
+	dir := filepath.Dir(path)
+	if !opts.AllowLooseParent {
+		if err := checkSecretPerms(dir, 0o026); err != nil {
+			return err
+		}
+	}
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempname := file.Name()
+	if err = restrictToOwner(tempname); err == nil {
+		if _, err = file.Write(data); err == nil {
+			err = file.Sync()
+		}
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = rename(tempname, path)
+	}
+	if err != nil {
+		os.Remove(tempname)
+		return err
+	}
+	return SyncDir(dir)
+}
+
+// ReadSecretFile returns the contents of the given secret file (see
+// [WriteSecretFile]). On Unix, if the file is accessible by group or other
+// users, or is owned by someone else, the contents are returned along
+// with an error wrapping [ErrInsecurePermissions] so that the caller can
+// either warn and carry on or refuse to use the secret. (Permissions
+// aren't checked on Windows.)
+func ReadSecretFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return data, checkSecretPerms(path, 0o077)
+}
+
+// checkSecretPerms returns an error wrapping [ErrInsecurePermissions] if
+// path has any of the loose permission bits or is owned by another user.
+func checkSecretPerms(path string, loose fs.FileMode) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&loose != 0 ||
+		(info.UID != -1 && info.UID != os.Getuid()) {
+		return &fs.PathError{Op: "secret", Path: path,
+			Err: ErrInsecurePermissions}
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build !windows
+
+package ufile
+
+import (
+	"os"
+)
+
+// restrictToOwner gives path 0600 permissions.
+func restrictToOwner(path string) error {
+	return os.Chmod(path, 0o600)
+}
@@ -0,0 +1,56 @@
+package ufile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_SecretFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "private")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "token")
+	if err := WriteSecretFile(filename, []byte("s3cret")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ReadSecretFile(filename)
+	if err != nil || string(data) != "s3cret" {
+		t.Errorf("expected %q got %q %v", "s3cret", data, err)
+	}
+	if tree, _ := TreeString(dir); tree != ".\n└── token\n" {
+		t.Errorf("expected no temporary files got\n%s", tree)
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if info, _ := os.Stat(filename); info.Mode().Perm() != 0o600 {
+		t.Errorf("expected 0600 got %v", info.Mode().Perm())
+	}
+	if err = os.Chmod(filename, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err = ReadSecretFile(filename)
+	if !errors.Is(err, ErrInsecurePermissions) || string(data) != "s3cret" {
+		t.Errorf("expected data and ErrInsecurePermissions got %q %v",
+			data, err)
+	}
+	if err = os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err = WriteSecretFile(filename, []byte("new"))
+	if !errors.Is(err, ErrInsecurePermissions) {
+		t.Errorf("expected ErrInsecurePermissions got %v", err)
+	}
+	if err = WriteSecretFileOpt(filename, []byte("new"),
+		SecretOptions{AllowLooseParent: true}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = ReadSecretFile(filename); err != nil ||
+		string(data) != "new" {
+		t.Errorf("expected %q got %q %v", "new", data, err)
+	}
+}
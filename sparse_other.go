@@ -0,0 +1,22 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build !linux && !darwin
+
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// allocatedSize returns the file's size since the allocated size isn't
+// available on this platform.
+func allocatedSize(info fs.FileInfo) int64 {
+	return info.Size()
+}
+
+// nextDataRange always fails since holes can't be found on this platform.
+func nextDataRange(file *os.File, offset int64) (int64, int64, error) {
+	return 0, 0, errors.ErrUnsupported
+}
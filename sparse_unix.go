@@ -0,0 +1,41 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build linux || darwin
+
+package ufile
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// allocatedSize returns the number of bytes actually allocated on disk
+// for the file with the given info.
+func allocatedSize(info fs.FileInfo) int64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int64(stat.Blocks) * 512 // st_blocks is in 512-byte units
+	}
+	return info.Size()
+}
+
+// nextDataRange returns the start and end offsets of the first range of
+// data at or after offset in file, or io.EOF if there's only a hole.
+func nextDataRange(file *os.File, offset int64) (int64, int64, error) {
+	start, err := file.Seek(offset, unix.SEEK_DATA)
+	if err != nil {
+		if errors.Is(err, syscall.ENXIO) {
+			return 0, 0, io.EOF
+		}
+		return 0, 0, err
+	}
+	end, err := file.Seek(start, unix.SEEK_HOLE)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
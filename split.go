@@ -0,0 +1,160 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SplitFile splits the file at path into chunkSize-byte parts (the last
+// may be shorter) named path's base name plus .001, .002, etc., in folder
+// dir (which is created if necessary), and returns the parts' names.
+// See also [SplitFileOpt] and [JoinFiles].
+func SplitFile(path string, chunkSize int64, dir string) ([]string, error) {
+	return SplitFileOpt(path, chunkSize, dir, false)
+}
+
+// SplitFileOpt works like [SplitFile] and if withManifest is true also
+// writes a SHA-256 checksum manifest (in `sha256sum` format) for the parts
+// and the whole file to dir, named path's base name plus .sha256.
+// See also [JoinFilesOpt].
+func SplitFileOpt(path string, chunkSize int64, dir string,
+	withManifest bool,
+) ([]string, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	base := filepath.Base(path)
+	whole := sha256.New()
+	reader := io.TeeReader(bufio.NewReader(in), whole)
+	var parts []string
+	var sums []string
+	for i := 1; ; i++ {
+		part := filepath.Join(dir, fmt.Sprintf("%s.%03d", base, i))
+		n, sum, err := writePart(part, reader, chunkSize)
+		if err != nil {
+			return parts, err
+		}
+		if n == 0 && i > 1 {
+			os.Remove(part)
+			break
+		}
+		parts = append(parts, part)
+		sums = append(sums, checksumLine(sum, filepath.Base(part)))
+		if n < chunkSize {
+			break
+		}
+	}
+	if withManifest {
+		sums = append(sums, checksumLine(hex.EncodeToString(whole.Sum(nil)),
+			base))
+		manifest := filepath.Join(dir, base+".sha256")
+		if err = writeChecksumLines(manifest, sums); err != nil {
+			return parts, err
+		}
+	}
+	return parts, nil
+}
+
+// writePart copies up to size bytes from reader to a new file called part
+// and returns how many bytes were written and their SHA-256 as hex.
+func writePart(part string, reader io.Reader, size int64) (int64, string,
+	error,
+) {
+	out, err := os.Create(part)
+	if err != nil {
+		return 0, "", err
+	}
+	defer out.Close()
+	hash := sha256.New()
+	writer := bufio.NewWriter(io.MultiWriter(out, hash))
+	n, err := io.CopyN(writer, reader, size)
+	if err != nil && err != io.EOF {
+		return n, "", err
+	}
+	if err = writer.Flush(); err != nil {
+		return n, "", err
+	}
+	return n, hex.EncodeToString(hash.Sum(nil)), out.Close()
+}
+
+// JoinFiles concatenates the given parts (in order) into dst, which is
+// written atomically. See also [JoinFilesOpt] and [SplitFile].
+func JoinFiles(parts []string, dst string) error {
+	return JoinFilesOpt(parts, dst, "")
+}
+
+// JoinFilesOpt works like [JoinFiles] and if manifest is nonempty
+// verifies each part, and the joined result, against the SHA-256 checksums
+// in the manifest (e.g., as written by [SplitFileOpt]), failing without
+// touching dst if any part doesn't match.
+func JoinFilesOpt(parts []string, dst, manifest string) error {
+	var sums map[string]string
+	if manifest != "" {
+		var err error
+		if sums, err = readChecksumMap(manifest); err != nil {
+			return err
+		}
+	}
+	return writeAtomic(dst, func(out *bufio.Writer) error {
+		whole := sha256.New()
+		writer := io.MultiWriter(out, whole)
+		for _, part := range parts {
+			hash := sha256.New()
+			if err := appendPart(part, io.MultiWriter(writer,
+				hash)); err != nil {
+				return err
+			}
+			if sums != nil {
+				if err := checkSum(sums, filepath.Base(part),
+					hex.EncodeToString(hash.Sum(nil))); err != nil {
+					return err
+				}
+			}
+		}
+		if sums != nil {
+			name := strings.TrimSuffix(filepath.Base(manifest), ".sha256")
+			if _, ok := sums[name]; ok {
+				return checkSum(sums, name,
+					hex.EncodeToString(whole.Sum(nil)))
+			}
+		}
+		return nil
+	})
+}
+
+func appendPart(part string, writer io.Writer) error {
+	in, err := os.Open(part)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	_, err = io.Copy(writer, in)
+	return err
+}
+
+func checkSum(sums map[string]string, name, sum string) error {
+	expected, ok := sums[name]
+	if !ok {
+		return fmt.Errorf("no checksum for %q", name)
+	}
+	if !strings.EqualFold(expected, sum) {
+		return fmt.Errorf("checksum mismatch for %q", name)
+	}
+	return nil
+}
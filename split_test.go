@@ -0,0 +1,52 @@
+package ufile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_SplitFile_JoinFiles(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "big.bin")
+	data := bytes.Repeat([]byte("0123456789"), 25)
+	if err := os.WriteFile(src, data, ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	partsDir := filepath.Join(dir, "parts")
+	parts, err := SplitFileOpt(src, 100, partsDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 3 || filepath.Base(parts[2]) != "big.bin.003" {
+		t.Errorf("expected 3 parts got %q", parts)
+	}
+	manifest := filepath.Join(partsDir, "big.bin.sha256")
+	dst := filepath.Join(dir, "joined.bin")
+	if err = JoinFilesOpt(parts, dst, manifest); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := os.ReadFile(dst); !bytes.Equal(raw, data) {
+		t.Error("joined file differs from the original")
+	}
+	if err = os.WriteFile(parts[1], []byte("corrupt"), ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	if err = JoinFilesOpt(parts, dst, manifest); err == nil {
+		t.Error("expected checksum mismatch")
+	}
+	if raw, _ := os.ReadFile(dst); !bytes.Equal(raw, data) {
+		t.Error("expected failed join to leave dst untouched")
+	}
+	parts, err = SplitFile(src, 125, partsDir)
+	if err != nil || len(parts) != 2 {
+		t.Errorf("expected 2 parts got %q %v", parts, err)
+	}
+	if err = JoinFiles(parts, dst); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := os.ReadFile(dst); !bytes.Equal(raw, data) {
+		t.Error("joined file differs from the original")
+	}
+}
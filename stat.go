@@ -0,0 +1,42 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"io/fs"
+	"os"
+	"time"
+)
+
+// FileInfoEx is an [fs.FileInfo] (so it has Name, Size, Mode, ModTime,
+// IsDir, and Sys methods) extended with platform-specific information
+// that otherwise requires type-asserting Sys() per platform. Fields that
+// aren't available on the current platform are zero (or -1 for UID and
+// GID).
+type FileInfoEx struct {
+	fs.FileInfo
+	AccessTime time.Time
+	ChangeTime time.Time // inode (metadata) change time; not on Windows
+	BirthTime  time.Time // creation time if known
+	UID        int       // Unix only
+	GID        int       // Unix only
+	Owner      string    // user name (Unix) or DOMAIN\account (Windows)
+	Group      string    // group name; Unix only
+	OwnerSID   string    // Windows only, e.g., "S-1-5-21-…"
+	Links      uint64    // number of hard links
+	Device     uint64    // device ID (Unix) or volume serial (Windows)
+	Inode      uint64    // inode (Unix) or file index (Windows)
+}
+
+// Stat returns extended information about the named file, following
+// symlinks. Owner and group names are looked up on a best-effort basis
+// and are empty if they can't be found.
+func Stat(path string) (FileInfoEx, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileInfoEx{}, err
+	}
+	infoEx := FileInfoEx{FileInfo: info, UID: -1, GID: -1}
+	fillInfoEx(path, &infoEx)
+	return infoEx, nil
+}
@@ -0,0 +1,20 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"syscall"
+	"time"
+)
+
+func fillInfoEx(_ string, infoEx *FileInfoEx) {
+	stat, ok := infoEx.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	infoEx.AccessTime = time.Unix(stat.Atimespec.Unix())
+	infoEx.ChangeTime = time.Unix(stat.Ctimespec.Unix())
+	infoEx.BirthTime = time.Unix(stat.Birthtimespec.Unix())
+	fillUnixInfoEx(infoEx, stat.Uid, stat.Gid, uint64(stat.Nlink),
+		uint64(stat.Dev), stat.Ino)
+}
@@ -0,0 +1,27 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func fillInfoEx(path string, infoEx *FileInfoEx) {
+	stat, ok := infoEx.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	infoEx.AccessTime = time.Unix(stat.Atim.Unix())
+	infoEx.ChangeTime = time.Unix(stat.Ctim.Unix())
+	fillUnixInfoEx(infoEx, stat.Uid, stat.Gid, uint64(stat.Nlink),
+		uint64(stat.Dev), stat.Ino)
+	var statx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME,
+		&statx); err == nil && statx.Mask&unix.STATX_BTIME != 0 {
+		infoEx.BirthTime = time.Unix(statx.Btime.Sec,
+			int64(statx.Btime.Nsec))
+	}
+}
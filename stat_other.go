@@ -0,0 +1,7 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build !linux && !darwin && !windows
+
+package ufile
+
+func fillInfoEx(_ string, _ *FileInfoEx) {} // only the basics are known
@@ -0,0 +1,47 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_Stat(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(filename, []byte("alpha"), ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "b.txt")
+	if err := os.Link(filename, link); err != nil {
+		t.Skip("hard links not supported")
+	}
+	infoEx, err := Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if infoEx.Size() != 5 || infoEx.Name() != "a.txt" {
+		t.Errorf("unexpected %d %q", infoEx.Size(), infoEx.Name())
+	}
+	if infoEx.Links != 2 {
+		t.Errorf("expected 2 links got %d", infoEx.Links)
+	}
+	if infoEx.AccessTime.IsZero() {
+		t.Error("expected an access time")
+	}
+	linkEx, err := Stat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linkEx.Inode != infoEx.Inode || linkEx.Device != infoEx.Device {
+		t.Errorf("expected same inode got %d:%d vs %d:%d", linkEx.Device,
+			linkEx.Inode, infoEx.Device, infoEx.Inode)
+	}
+	if runtime.GOOS != "windows" && infoEx.UID != os.Getuid() {
+		t.Errorf("expected %d got %d", os.Getuid(), infoEx.UID)
+	}
+	if _, err = Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build linux || darwin
+
+package ufile
+
+import (
+	"os/user"
+	"strconv"
+)
+
+// fillUnixInfoEx sets the fields that are common to Unix-like platforms.
+func fillUnixInfoEx(infoEx *FileInfoEx, uid, gid uint32, links, dev,
+	ino uint64,
+) {
+	infoEx.UID = int(uid)
+	infoEx.GID = int(gid)
+	infoEx.Links = links
+	infoEx.Device = dev
+	infoEx.Inode = ino
+	if owner, err := user.LookupId(strconv.Itoa(infoEx.UID)); err == nil {
+		infoEx.Owner = owner.Username
+	}
+	if group, err := user.LookupGroupId(strconv.Itoa(infoEx.GID)); err ==
+		nil {
+		infoEx.Group = group.Name
+	}
+}
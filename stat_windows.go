@@ -0,0 +1,48 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+func fillInfoEx(path string, infoEx *FileInfoEx) {
+	if data, ok := infoEx.Sys().(*syscall.Win32FileAttributeData); ok {
+		infoEx.AccessTime = time.Unix(0,
+			data.LastAccessTime.Nanoseconds())
+		infoEx.BirthTime = time.Unix(0, data.CreationTime.Nanoseconds())
+	}
+	path = LongPath(path)
+	if pathp, err := windows.UTF16PtrFromString(path); err == nil {
+		handle, err := windows.CreateFile(pathp, 0,
+			windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|
+				windows.FILE_SHARE_DELETE, nil, windows.OPEN_EXISTING,
+			windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+		if err == nil {
+			var data windows.ByHandleFileInformation
+			if windows.GetFileInformationByHandle(handle, &data) == nil {
+				infoEx.Links = uint64(data.NumberOfLinks)
+				infoEx.Device = uint64(data.VolumeSerialNumber)
+				infoEx.Inode = uint64(data.FileIndexHigh)<<32 |
+					uint64(data.FileIndexLow)
+			}
+			windows.CloseHandle(handle)
+		}
+	}
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return
+	}
+	owner, _, err := sd.Owner()
+	if err != nil || owner == nil {
+		return
+	}
+	infoEx.OwnerSID = owner.String()
+	if account, domain, _, err := owner.LookupAccount(""); err == nil {
+		infoEx.Owner = domain + `\` + account
+	}
+}
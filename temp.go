@@ -0,0 +1,43 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"os"
+	"regexp"
+)
+
+// TempDirFor creates a new folder in [os.TempDir] named after appname
+// (with any non-word characters replaced by "_") plus "-" and a random
+// suffix, readable and writable only by the current user. It returns the
+// folder's name and a cleanup function that removes the folder and its
+// contents. See also [NewTempFile].
+func TempDirFor(appname string) (dir string, cleanup func(), err error) {
+	rx := regexp.MustCompile(`\W+`)
+	dir, err = os.MkdirTemp("", rx.ReplaceAllString(appname, "_")+"-*")
+	if err != nil {
+		return "", func() {}, err
+	}
+	if err = os.Chmod(dir, 0o700); err != nil {
+		os.RemoveAll(dir)
+		return "", func() {}, err
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// NewTempFile creates a new file in [os.TempDir] named using pattern (see
+// [os.CreateTemp]), readable and writable only by the current user, and
+// opened for reading and writing. It returns the file and a cleanup
+// function that closes and removes it. See also [TempDirFor].
+func NewTempFile(pattern string) (file *os.File, cleanup func(),
+	err error,
+) {
+	file, err = os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	return file, func() {
+		file.Close()
+		os.Remove(file.Name())
+	}, nil
+}
@@ -0,0 +1,50 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_TempDirFor(t *testing.T) {
+	dir, cleanup, err := TempDirFor("my app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base := filepath.Base(dir); !strings.HasPrefix(base, "my_app-") {
+		t.Errorf("expected my_app-* got %q", base)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); runtime.GOOS != "windows" &&
+		perm != 0o700 {
+		t.Errorf("expected %o got %o", 0o700, perm)
+	}
+	makeTestTree(t, dir, map[string]string{"sub/a.txt": "alpha"})
+	cleanup()
+	if PathExists(dir) {
+		t.Errorf("expected %q to be removed", dir)
+	}
+}
+
+func Test_NewTempFile(t *testing.T) {
+	file, cleanup, err := NewTempFile("ufile-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := file.Name()
+	if !strings.HasSuffix(name, ".txt") {
+		t.Errorf("expected *.txt got %q", name)
+	}
+	if _, err = file.WriteString("data"); err != nil {
+		t.Fatal(err)
+	}
+	cleanup()
+	if PathExists(name) {
+		t.Errorf("expected %q to be removed", name)
+	}
+}
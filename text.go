@@ -0,0 +1,266 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
+
+// EOLStyle identifies a text file's line endings.
+type EOLStyle uint8
+
+const (
+	EOLNone  EOLStyle = iota // no line endings at all
+	EOLLF                    // \n (Unix)
+	EOLCRLF                  // \r\n (Windows)
+	EOLCR                    // \r (classic Mac)
+	EOLMixed                 // more than one of the above
+)
+
+// String returns the EOLStyle's name, e.g., "CRLF".
+func (me EOLStyle) String() string {
+	switch me {
+	case EOLLF:
+		return "LF"
+	case EOLCRLF:
+		return "CRLF"
+	case EOLCR:
+		return "CR"
+	case EOLMixed:
+		return "mixed"
+	default:
+		return "none"
+	}
+}
+
+// eol returns the style's line ending or the platform's for EOLNone and
+// EOLMixed.
+func (me EOLStyle) eol() string {
+	switch me {
+	case EOLLF:
+		return "\n"
+	case EOLCRLF:
+		return "\r\n"
+	case EOLCR:
+		return "\r"
+	default:
+		return platformEOL()
+	}
+}
+
+func platformEOL() string {
+	if runtime.GOOS == "windows" {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// TextInfo describes the layout of a text file as read by
+// [ReadTextFileInfo] and as written by [WriteTextFileInfo].
+type TextInfo struct {
+	EOL          EOLStyle
+	BOM          bool // starts with a UTF-8 byte order mark
+	FinalNewline bool // the last line ends with an EOL
+}
+
+// ReadTextFileInfo reads the given file and returns a slice of lines with
+// EOLs (and any BOM) stripped off, and a [TextInfo] describing the
+// original EOLs, BOM, and final newline. Passing the lines and info to
+// [WriteTextFileInfo] reproduces the file exactly (unless its EOLs are
+// mixed). Will automatically uncompress .gz files.
+// See also [ReadTextFile].
+func ReadTextFileInfo(filename string) ([]string, TextInfo, error) {
+	raw, err := readRaw(filename)
+	if err != nil {
+		return nil, TextInfo{}, err
+	}
+	lines, info := splitTextInfo(raw)
+	return lines, info, nil
+}
+
+func splitTextInfo(raw []byte) ([]string, TextInfo) {
+	var info TextInfo
+	if bytes.HasPrefix(raw, utf8BOM) {
+		info.BOM = true
+		raw = raw[len(utf8BOM):]
+	}
+	lines := []string{}
+	start := 0
+	for i := 0; i < len(raw); i++ {
+		var style EOLStyle
+		switch raw[i] {
+		case '\n':
+			style = EOLLF
+		case '\r':
+			style = EOLCR
+			if i+1 < len(raw) && raw[i+1] == '\n' {
+				style = EOLCRLF
+			}
+		default:
+			continue
+		}
+		lines = append(lines, string(raw[start:i]))
+		if style == EOLCRLF {
+			i++
+		}
+		start = i + 1
+		if info.EOL == EOLNone {
+			info.EOL = style
+		} else if info.EOL != style {
+			info.EOL = EOLMixed
+		}
+	}
+	if start < len(raw) {
+		lines = append(lines, string(raw[start:]))
+	} else if len(lines) > 0 {
+		info.FinalNewline = true
+	}
+	return lines, info
+}
+
+// WriteTextFileInfo writes the given lines to the given filename using the
+// EOLs, BOM, and final newline specified by info (the platform-appropriate
+// EOL is used if info.EOL is EOLNone or EOLMixed).
+// See also [ReadTextFileInfo] and [WriteTextFile].
+func WriteTextFileInfo(filename string, lines []string, info TextInfo,
+) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	out := bufio.NewWriter(file)
+	if err = writeTextInfo(out, lines, info); err != nil {
+		return err
+	}
+	if err = out.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func writeTextInfo(out *bufio.Writer, lines []string, info TextInfo,
+) error {
+	if info.BOM {
+		if _, err := out.Write(utf8BOM); err != nil {
+			return err
+		}
+	}
+	eol := info.EOL.eol()
+	for i, line := range lines {
+		if _, err := out.WriteString(line); err != nil {
+			return err
+		}
+		if i < len(lines)-1 || info.FinalNewline {
+			if _, err := out.WriteString(eol); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ConvertEOL converts every line ending in the given file (\n, \r\n, or
+// \r) to the given style, which must be EOLLF, EOLCRLF, or EOLCR. The file
+// is streamed to a temporary file that is then atomically renamed over the
+// original (which is left untouched if no EOL needs changing).
+// See also [ConvertEOLBytes].
+func ConvertEOL(filename string, to EOLStyle) error {
+	if err := checkEOLTarget(to); err != nil {
+		return err
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	eol := to.eol()
+	err = writeAtomic(filename, func(out *bufio.Writer) error {
+		changed, err := convertEOL(bufio.NewReader(file), out, eol)
+		if err != nil {
+			return err
+		}
+		file.Close() // Windows can't rename over an open file
+		if !changed {
+			return errUnchanged
+		}
+		return nil
+	})
+	if err == errUnchanged {
+		return nil
+	}
+	return err
+}
+
+// ConvertEOLBytes returns a copy of data with every line ending (\n, \r\n,
+// or \r) converted to the given style, which must be EOLLF, EOLCRLF, or
+// EOLCR. See also [ConvertEOL].
+func ConvertEOLBytes(data []byte, to EOLStyle) ([]byte, error) {
+	if err := checkEOLTarget(to); err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	buffer.Grow(len(data))
+	out := bufio.NewWriter(&buffer)
+	if _, err := convertEOL(bufio.NewReader(bytes.NewReader(data)), out,
+		to.eol()); err != nil {
+		return nil, err
+	}
+	if err := out.Flush(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func checkEOLTarget(to EOLStyle) error {
+	if to != EOLLF && to != EOLCRLF && to != EOLCR {
+		return fmt.Errorf("cannot convert EOLs to %s", to)
+	}
+	return nil
+}
+
+// convertEOL copies reader to out replacing every EOL with eol and
+// returns whether anything was changed.
+func convertEOL(reader *bufio.Reader, out *bufio.Writer, eol string) (
+	bool, error,
+) {
+	changed := false
+	for {
+		c, err := reader.ReadByte()
+		if err == io.EOF {
+			return changed, nil
+		}
+		if err != nil {
+			return changed, err
+		}
+		var old string
+		switch c {
+		case '\n':
+			old = "\n"
+		case '\r':
+			old = "\r"
+			if next, err := reader.Peek(1); err == nil && next[0] == '\n' {
+				_, _ = reader.ReadByte()
+				old = "\r\n"
+			}
+		default:
+			if err = out.WriteByte(c); err != nil {
+				return changed, err
+			}
+			continue
+		}
+		if old != eol {
+			changed = true
+		}
+		if _, err = out.WriteString(eol); err != nil {
+			return changed, err
+		}
+	}
+}
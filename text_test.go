@@ -0,0 +1,68 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_ReadWriteTextFileInfo(t *testing.T) {
+	dir := t.TempDir()
+	texts := []string{"a\r\nb\r\n", "\xEF\xBB\xBFa\nb", "a\rb\r", "a\nb\r\n",
+		"", "one line"}
+	infos := []TextInfo{{EOLCRLF, false, true}, {EOLLF, true, false},
+		{EOLCR, false, true}, {EOLMixed, false, true},
+		{EOLNone, false, false}, {EOLNone, false, false}}
+	for i, text := range texts {
+		filename := filepath.Join(dir, "info.txt")
+		if err := os.WriteFile(filename, []byte(text), ModeURW); err != nil {
+			t.Fatal(err)
+		}
+		lines, info, err := ReadTextFileInfo(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info != infos[i] {
+			t.Errorf("%q: expected %v got %v", text, infos[i], info)
+		}
+		if text == "" {
+			if len(lines) != 0 {
+				t.Errorf("expected no lines got %q", lines)
+			}
+		} else if text != "one line" &&
+			slices.Compare(lines, []string{"a", "b"}) != 0 {
+			t.Errorf("%q: expected [a b] got %q", text, lines)
+		}
+		if info.EOL == EOLMixed {
+			continue
+		}
+		if err = WriteTextFileInfo(filename, lines, info); err != nil {
+			t.Fatal(err)
+		}
+		if raw, _ := os.ReadFile(filename); string(raw) != text {
+			t.Errorf("expected %q got %q", text, raw)
+		}
+	}
+}
+
+func Test_ConvertEOL(t *testing.T) {
+	text := "a\r\nb\nc\rd"
+	data, err := ConvertEOLBytes([]byte(text), EOLLF)
+	if err != nil || string(data) != "a\nb\nc\nd" {
+		t.Errorf("expected LFs got %q %v", data, err)
+	}
+	if _, err = ConvertEOLBytes([]byte(text), EOLMixed); err == nil {
+		t.Error("expected error for EOLMixed")
+	}
+	filename := filepath.Join(t.TempDir(), "eol.txt")
+	if err := os.WriteFile(filename, []byte(text), ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	if err = ConvertEOL(filename, EOLCRLF); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := os.ReadFile(filename); string(raw) != "a\r\nb\r\nc\r\nd" {
+		t.Errorf("expected CRLFs got %q", raw)
+	}
+}
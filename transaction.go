@@ -0,0 +1,178 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrTransactionDone is returned when a [Transaction] is used after it has
+// been committed or rolled back.
+var ErrTransactionDone = errors.New("transaction already committed or " +
+	"rolled back")
+
+// Transaction stages writes to any number of files and then either
+// commits them all (renaming each staged file into place) or none. If a
+// rename fails during Commit the files already renamed are restored to
+// their previous state. It is safe for concurrent use. Create with
+// [NewTransaction].
+//
+//	txn := ufile.NewTransaction()
+//	defer txn.Rollback() // no-op after a successful Commit
+//	if err := txn.WriteFile(configFile, config); err != nil {
+//		return err
+//	}
+//	if err := txn.WriteFile(indexFile, index); err != nil {
+//		return err
+//	}
+//	return txn.Commit()
+type Transaction struct {
+	mutex  sync.Mutex
+	staged []stagedFile
+	done   bool
+}
+
+type stagedFile struct {
+	filename string
+	tempname string
+}
+
+// NewTransaction returns a new empty Transaction.
+func NewTransaction() *Transaction { return &Transaction{} }
+
+// WriteFile stages data to be written to filename when the transaction is
+// committed. The data is written to a temporary file in the same folder
+// (which must exist) straight away. Staging the same filename again
+// replaces the previously staged data.
+func (me *Transaction) WriteFile(filename string, data []byte) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.done {
+		return ErrTransactionDone
+	}
+	filename = AbsPath(filename)
+	tempname, err := writeTemp(filename, false, func(out *bufio.Writer) error {
+		_, err := out.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for i, staged := range me.staged {
+		if staged.filename == filename {
+			os.Remove(staged.tempname)
+			me.staged[i].tempname = tempname
+			return nil
+		}
+	}
+	me.staged = append(me.staged, stagedFile{filename, tempname})
+	return nil
+}
+
+// Commit renames every staged file into place. If any rename fails, the
+// files already renamed are restored to their previous contents (or
+// removed if they didn't previously exist) and the error is returned.
+// Once all the files are in place their folders are synced (see
+// [SyncDir]); an error from that means the files have been committed but
+// might not survive a crash.
+func (me *Transaction) Commit() error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.done {
+		return ErrTransactionDone
+	}
+	me.done = true
+	backups := make([]string, len(me.staged)) // "" means didn't exist
+	committed := 0
+	var err error
+	for i, staged := range me.staged {
+		if backups[i], err = backupForCommit(staged.filename); err != nil {
+			break
+		}
+		if err = rename(staged.tempname, staged.filename); err != nil {
+			break
+		}
+		committed++
+	}
+	if err != nil {
+		for i := range committed {
+			restoreAfterFailedCommit(me.staged[i].filename, backups[i])
+		}
+		if committed < len(me.staged) && backups[committed] != "" {
+			os.Remove(backups[committed])
+		}
+		for _, staged := range me.staged[committed:] {
+			os.Remove(staged.tempname)
+		}
+		me.staged = nil
+		return fmt.Errorf("transaction rolled back: %w", err)
+	}
+	for _, backup := range backups {
+		if backup != "" {
+			os.Remove(backup)
+		}
+	}
+	synced := make(map[string]bool)
+	for _, staged := range me.staged {
+		if dir := filepath.Dir(staged.filename); !synced[dir] {
+			synced[dir] = true
+			if syncErr := SyncDir(dir); syncErr != nil && err == nil {
+				err = syncErr
+			}
+		}
+	}
+	me.staged = nil
+	return err
+}
+
+// Rollback discards all the staged files leaving the targets untouched.
+// Calling Rollback after Commit (or a previous Rollback) does nothing.
+func (me *Transaction) Rollback() {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.done {
+		return
+	}
+	me.done = true
+	for _, staged := range me.staged {
+		os.Remove(staged.tempname)
+	}
+	me.staged = nil
+}
+
+// backupForCommit hard links (or if that fails, copies) an existing
+// filename to a backup name and returns the backup name, or returns "" if
+// filename doesn't exist.
+func backupForCommit(filename string) (string, error) {
+	if !PathExists(filename) {
+		return "", nil
+	}
+	file, err := os.CreateTemp(filepath.Dir(filename),
+		"."+filepath.Base(filename)+".*.bak")
+	if err != nil {
+		return "", err
+	}
+	backup := file.Name()
+	file.Close()
+	os.Remove(backup)
+	if err = os.Link(filename, backup); err != nil {
+		if err = copyFileData(filename, backup); err != nil {
+			os.Remove(backup)
+			return "", err
+		}
+	}
+	return backup, nil
+}
+
+func restoreAfterFailedCommit(filename, backup string) {
+	if backup == "" {
+		os.Remove(filename)
+	} else {
+		rename(backup, filename)
+	}
+}
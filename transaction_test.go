@@ -0,0 +1,72 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Transaction(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.ini")
+	index := filepath.Join(dir, "data.idx")
+	if err := os.WriteFile(config, []byte("old"), ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	txn := NewTransaction()
+	if err := txn.WriteFile(config, []byte("new config")); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.WriteFile(index, []byte("new index")); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := os.ReadFile(config); string(raw) != "old" {
+		t.Errorf("expected old got %q", raw)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := os.ReadFile(config); string(raw) != "new config" {
+		t.Errorf("expected new config got %q", raw)
+	}
+	if raw, _ := os.ReadFile(index); string(raw) != "new index" {
+		t.Errorf("expected new index got %q", raw)
+	}
+	if err := txn.Commit(); err != ErrTransactionDone {
+		t.Errorf("expected ErrTransactionDone got %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected 2 files got %d", len(entries))
+	}
+	// a failing commit: the second target is a nonempty folder
+	blocker := filepath.Join(dir, "blocker")
+	if err := os.MkdirAll(filepath.Join(blocker, "x"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	txn = NewTransaction()
+	if err := txn.WriteFile(config, []byte("newer")); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.WriteFile(blocker, []byte("oops")); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(); err == nil {
+		t.Error("expected commit to fail")
+	}
+	if raw, _ := os.ReadFile(config); string(raw) != "new config" {
+		t.Errorf("expected new config got %q", raw)
+	}
+	entries, _ = os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("expected 3 entries got %d", len(entries))
+	}
+	txn = NewTransaction()
+	if err := txn.WriteFile(config, []byte("discarded")); err != nil {
+		t.Fatal(err)
+	}
+	txn.Rollback()
+	if raw, _ := os.ReadFile(config); string(raw) != "new config" {
+		t.Errorf("expected new config got %q", raw)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// MakeTree creates the tree described by spec in folder root (which is
+// created if necessary). Each key is a slash-separated path relative to
+// root and each value is the file's contents; a key ending in "/" is an
+// (empty) folder whose value is ignored. Parent folders are created as
+// needed and existing files are overwritten. Keys may not be absolute or
+// escape root. This is especially useful for creating test fixtures, e.g.,
+//
+//	err := ufile.MakeTree(dir, map[string]string{"a.txt": "alpha",
+//		"sub/b.txt": "beta", "empty/": ""})
+//
+// See also [TreeString].
+func MakeTree(root string, spec map[string]string) error {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(spec))
+	for name := range spec {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		path, err := safeJoin(root, name)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(name, "/") {
+			if err = os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err = os.WriteFile(path, []byte(spec[name]),
+			modeDefault); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TreeString returns a tree(1)-like listing of the tree rooted at root,
+// starting with a "." line for root itself, with entries sorted by name,
+// folders suffixed with "/", and symlinks (which aren't followed) shown
+// as "name -> target". For example:
+//
+//	.
+//	├── a.txt
+//	└── sub/
+//	    └── b.txt
+//
+// See also [MakeTree].
+func TreeString(root string) (string, error) {
+	var out strings.Builder
+	out.WriteString(".\n")
+	if err := writeTree(&out, root, ""); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func writeTree(out *strings.Builder, dir, indent string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		branch, more := "├── ", "│   "
+		if i == len(entries)-1 {
+			branch, more = "└── ", "    "
+		}
+		out.WriteString(indent + branch + entry.Name())
+		path := filepath.Join(dir, entry.Name())
+		switch {
+		case entry.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			out.WriteString(" -> " + filepath.ToSlash(target) + "\n")
+		case entry.IsDir():
+			out.WriteString("/\n")
+			if err = writeTree(out, path, indent+more); err != nil {
+				return err
+			}
+		default:
+			out.WriteString("\n")
+		}
+	}
+	return nil
+}
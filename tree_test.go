@@ -0,0 +1,39 @@
+package ufile
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func Test_MakeTree_TreeString(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "root")
+	err := MakeTree(root, map[string]string{"b.txt": "beta",
+		"a/x.txt": "x", "a/deeper/y.txt": "y", "empty/": "ignored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := TreeString(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `.
+├── a/
+│   ├── deeper/
+│   │   └── y.txt
+│   └── x.txt
+├── b.txt
+└── empty/
+`
+	if tree != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, tree)
+	}
+	lines, err := ReadTextFile(filepath.Join(root, "a", "deeper", "y.txt"))
+	if err != nil || len(lines) != 1 || lines[0] != "y" {
+		t.Errorf("unexpected %q %v", lines, err)
+	}
+	err = MakeTree(root, map[string]string{"../escape.txt": ""})
+	if !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("expected %v got %v", ErrUnsafePath, err)
+	}
+}
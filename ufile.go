@@ -9,14 +9,22 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
 	_ "embed"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"iter"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
+	"syscall"
+	"unicode/utf8"
 
 	"github.com/mark-summerfield/utext"
 )
@@ -24,10 +32,18 @@ import (
 //go:embed Version.dat
 var Version string
 
+// ErrTooLarge is wrapped by the error [ReadTextFileLimited] returns for a
+// file that exceeds the size limit.
+var ErrTooLarge = errors.New("file too large")
+
+// ErrVerifyFailed is wrapped by the error returned when a write made with
+// [WriteOptions] Verify set reads back differently from what was written.
+var ErrVerifyFailed = errors.New("verification of written data failed")
+
 const ModeURW = 0o600
 
 // AbsPath returns the filename with its path absolute, or cleaned on error.
-// See also [Relativized].
+// See also [CanonicalPath], [RelativizeAll], and [ShortestPath].
 func AbsPath(filename string) string {
 	absFilename, err := filepath.Abs(filename)
 	if err == nil {
@@ -36,22 +52,205 @@ func AbsPath(filename string) string {
 	return filepath.Clean(filename)
 }
 
+// AppendTextFile appends the given lines to the given filename adding the
+// platform-appropriate EOL to each line written. If the file doesn't exist
+// it is created with [ModeURW] permissions. See also [AppendLines] and
+// [WriteTextFile].
+func AppendTextFile(filename string, lines []string) error {
+	return AppendLines(filename, slices.Values(lines))
+}
+
+// AppendLines appends the lines from the given iterator to the given
+// filename adding the platform-appropriate EOL to each line written. If
+// the file doesn't exist it is created with [ModeURW] permissions.
+// See also [AppendTextFile].
+func AppendLines(filename string, lines iter.Seq[string]) error {
+	file, err := os.OpenFile(filename,
+		os.O_WRONLY|os.O_APPEND|os.O_CREATE, ModeURW)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err = writeLines(file, lines); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
 // Barename returns the filename without any path and without any suffix.
+// A dotfile's leading dot isn't a suffix separator, e.g., ".bashrc" stays
+// ".bashrc" and ".config.tar.gz" becomes ".config", and trailing dots
+// (which Windows ignores) are dropped, e.g., "notes." becomes "notes".
+// See also [SplitExt].
 func Barename(path string) string {
 	if i := strings.LastIndexAny(path, `/\`); i > -1 {
 		path = path[i+1:]
 	}
+	path = trimTrailingDots(path)
+	dots := len(path) - len(strings.TrimLeft(path, "."))
+	if i := strings.IndexByte(path[dots:], '.'); i > -1 {
+		path = path[:dots+i]
+	}
+	return path
+}
+
+// CanonicalPath returns the canonical form of path: absolute and clean,
+// with all symlinks resolved, with any Windows 8.3 short names expanded,
+// and with each component in the case actually used on disk (see
+// [ExistsFold]). So two paths to the same file or folder (other than via
+// hard links) have the same canonical form, which makes it suitable for
+// de-duplicating paths. ".." components are resolved after the symlinks
+// that precede them (as the OS does), so "link/../f" is f in the folder
+// containing link's target, not in link's folder. If path doesn't exist
+// its longest existing prefix is canonicalized and the rest is appended.
+// See also [AbsPath].
+func CanonicalPath(path string) (string, error) {
+	if !filepath.IsAbs(path) { // don't clean before resolving symlinks
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		path = wd + string(filepath.Separator) + path
+	}
+	existing, rest := path, ""
 	for {
-		if i := strings.LastIndexByte(path, '.'); i > -1 {
-			path = path[:i]
-		} else {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			existing = resolved
 			break
 		}
+		parent, base := splitLastComponent(existing)
+		if !errors.Is(err, fs.ErrNotExist) || parent == "" || base == "" {
+			return "", err
+		}
+		// missing components can't be symlinks so can be cleaned
+		rest = filepath.Join(base, rest)
+		existing = parent
 	}
-	return path
+	existing = longPathName(existing)
+	if actual, ok := ExistsFold(existing); ok {
+		existing = actual
+	}
+	return filepath.Join(existing, rest), nil
+}
+
+// splitLastComponent returns the absolute path's parent (without
+// cleaning it, unlike [filepath.Dir]) and its last component, or "" and
+// path if path is a root.
+func splitLastComponent(path string) (parent, base string) {
+	volume := len(filepath.VolumeName(path))
+	end := len(path)
+	for end > volume+1 && os.IsPathSeparator(path[end-1]) {
+		end--
+	}
+	i := end - 1
+	for i >= volume && !os.IsPathSeparator(path[i]) {
+		i--
+	}
+	if i < volume {
+		return "", path
+	}
+	if i == volume { // keep the root's separator
+		return path[:i+1], path[i+1 : end]
+	}
+	return path[:i], path[i+1 : end]
+}
+
+// Depth returns the number of components in path not counting its root
+// (if any), e.g., 0 for "/" or "", 2 for "/a/b" or `C:\a\b`, and 3 for
+// "a/b/c.txt". See also [SplitAll].
+func Depth(path string) int {
+	parts := SplitAll(path)
+	if len(parts) > 0 && isPathRoot(parts[0]) {
+		return len(parts) - 1
+	}
+	return len(parts)
+}
+
+// EnsureDir creates folder path with the given permissions (before the
+// umask), along with any missing parents, unless it already exists. It
+// returns an error if path (or a parent) exists but isn't a folder.
+// See also [EnsureParentDir].
+func EnsureDir(path string, mode fs.FileMode) error {
+	info, err := os.Stat(path)
+	if err == nil {
+		if !info.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: path,
+				Err: syscall.ENOTDIR}
+		}
+		return nil
+	}
+	return os.MkdirAll(path, mode)
+}
+
+// EnsureParentDir creates the folder that filename is in (see [EnsureDir])
+// with [fs.ModePerm] permissions (before the umask) if it doesn't exist.
+func EnsureParentDir(filename string) error {
+	return EnsureDir(filepath.Dir(filename), fs.ModePerm)
+}
+
+// ExecutableDir returns the absolute path of the folder containing the
+// running executable, with any symlinks resolved, or of the current
+// folder if that can't be determined.
+func ExecutableDir() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return AbsPath(".")
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return filepath.Dir(exe)
+}
+
+// ExistsFold looks for path ignoring the case of each of its components
+// and returns the path with the casing actually used on disk and true, or
+// "" and false if there is no such path. On a case-sensitive filesystem
+// with several case-insensitive matches an exact match is preferred,
+// otherwise the first in name order. This is useful when the paths in
+// data created on a case-insensitive filesystem (e.g., Windows or macOS)
+// are used on a case-sensitive one, or vice versa. "." and ".."
+// components are kept as is.
+func ExistsFold(path string) (actualPath string, ok bool) {
+	path = filepath.Clean(path)
+	volume := filepath.VolumeName(path)
+	rest := path[len(volume):]
+	actualPath = "."
+	if volume != "" || filepath.IsAbs(path) ||
+		(rest != "" && os.IsPathSeparator(rest[0])) {
+		actualPath = volume + string(filepath.Separator)
+	}
+	for _, component := range strings.FieldsFunc(rest, func(c rune) bool {
+		return c < utf8.RuneSelf && os.IsPathSeparator(uint8(c))
+	}) {
+		if component == "." || component == ".." {
+			actualPath = filepath.Join(actualPath, component)
+			continue
+		}
+		entries, err := os.ReadDir(actualPath)
+		if err != nil {
+			return "", false
+		}
+		match := ""
+		for _, entry := range entries {
+			if name := entry.Name(); name == component {
+				match = name
+				break
+			} else if match == "" && strings.EqualFold(name, component) {
+				match = name
+			}
+		}
+		if match == "" {
+			return "", false
+		}
+		actualPath = filepath.Join(actualPath, match)
+	}
+	return actualPath, true
 }
 
 // FileExists returns true if the filename exists and is a file.
+// Note that this includes named pipes, sockets, and devices, so use
+// [IsRegularFile] before reading a file that might be one of these.
 // See also [PathExists].
 func FileExists(path string) bool {
 	if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
@@ -60,6 +259,56 @@ func FileExists(path string) bool {
 	return false
 }
 
+// FilesEqual returns true if files a and b have identical contents;
+// otherwise returns false. The sizes are compared first and then the
+// contents a block at a time, stopping at the first difference.
+// See also [Checksum].
+func FilesEqual(a, b string) (bool, error) {
+	aFile, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer aFile.Close()
+	bFile, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer bFile.Close()
+	aInfo, err := aFile.Stat()
+	if err != nil {
+		return false, err
+	}
+	bInfo, err := bFile.Stat()
+	if err != nil {
+		return false, err
+	}
+	if aInfo.Size() != bInfo.Size() {
+		return false, nil
+	}
+	if os.SameFile(aInfo, bInfo) {
+		return true, nil
+	}
+	const blockSize = 64 * 1024
+	aBlock := make([]byte, blockSize)
+	bBlock := make([]byte, blockSize)
+	for {
+		aCount, aErr := io.ReadFull(aFile, aBlock)
+		bCount, bErr := io.ReadFull(bFile, bBlock)
+		if aErr != nil && aErr != io.EOF && aErr != io.ErrUnexpectedEOF {
+			return false, aErr
+		}
+		if bErr != nil && bErr != io.EOF && bErr != io.ErrUnexpectedEOF {
+			return false, bErr
+		}
+		if !bytes.Equal(aBlock[:aCount], bBlock[:bCount]) {
+			return false, nil
+		}
+		if aErr != nil || bErr != nil { // reached the end of one or both
+			return aErr != nil && bErr != nil, nil
+		}
+	}
+}
+
 // GetConfigFile given a domain name, say, "domain.com", and an application
 // name, say, "myapp", and an extention, say, ".json", returns where the
 // corresponding config file is located and true, or where the config file
@@ -73,15 +322,44 @@ func FileExists(path string) bool {
 //		_ = os.MkdirAll(dir, fs.ModePerm)
 //	}
 //	// now save to configFilename
+//
+// See also [GetConfigFileOpt] and [LoadConfig].
 func GetConfigFile(domain, appname, ext string) (string, bool) {
-	if !strings.HasPrefix(ext, ".") {
-		ext = "." + ext
+	return GetConfigFileOpt(domain, appname, ext, false)
+}
+
+// GetConfigFileOpt works like [GetConfigFile] and if portable is true
+// prefers a config file in the executable's folder (see [ExecutableDir]),
+// as is usual for portable applications: if that file exists it is
+// returned, otherwise if a config file exists in one of the usual places
+// that is returned, and otherwise the executable's folder is where the
+// config file should be saved.
+func GetConfigFileOpt(domain, appname, ext string, portable bool) (string,
+	bool,
+) {
+	var portableDir string
+	if portable {
+		portableDir = ExecutableDir()
 	}
-	rx := regexp.MustCompile(`\W+`)
-	filename := rx.ReplaceAllString(appname, "_") + ext
+	return getConfigFile(domain, appname, ext, portableDir)
+}
+
+// getConfigFile implements [GetConfigFileOpt] with the portable config
+// file in portableDir, or with none if portableDir is "".
+func getConfigFile(domain, appname, ext, portableDir string) (string,
+	bool,
+) {
+	filename := configFilename(appname, ext)
 	filenames := make([]string, 0, 8)
 	var preferred string
 	var fallback string
+	var portableName string
+	if portableDir != "" {
+		portableName = filepath.Join(portableDir, filename)
+		if FileExists(portableName) {
+			return portableName, true
+		}
+	}
 	configDir, err := os.UserConfigDir()
 	if err == nil {
 		if domain != "" {
@@ -121,6 +399,9 @@ func GetConfigFile(domain, appname, ext string) (string, bool) {
 			return filename, true // found
 		}
 	}
+	if portableName != "" {
+		return portableName, false
+	}
 	if preferred != "" {
 		return preferred, false
 	}
@@ -133,10 +414,20 @@ func GetConfigFile(domain, appname, ext string) (string, bool) {
 	return filename, false
 }
 
+// configFilename returns appname with runs of non-word characters
+// replaced by "_" plus ext (with a leading "." added if necessary).
+func configFilename(appname, ext string) string {
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	rx := regexp.MustCompile(`\W+`)
+	return rx.ReplaceAllString(appname, "_") + ext
+}
+
 // GetIniFile given a domain name, say, "domain.com", and an application
 // name, say, "myapp", returns where the corresponding .ini file is located
 // and true, or where the .ini should be saved (i.e., if it doesn't exist)
-// and false.
+// and false. Use [LoadIni] or [NewIni] to read or create it.
 //
 // When saving (at least for the first time) you may need to create the
 // domain folder:
@@ -168,12 +459,91 @@ func IsDir(name string) bool {
 	return info.IsDir()
 }
 
+// IsDevice returns true if path is a character or block device; otherwise
+// returns false. See also [IsCharDevice].
+func IsDevice(path string) bool {
+	mode, ok := modeOf(path)
+	return ok && mode&fs.ModeDevice != 0
+}
+
+// IsCharDevice returns true if path is a character device (e.g., a
+// terminal or /dev/null); otherwise returns false. See also [IsDevice].
+func IsCharDevice(path string) bool {
+	mode, ok := modeOf(path)
+	return ok && mode&fs.ModeCharDevice != 0
+}
+
+// IsFIFO returns true if path is a named pipe; otherwise returns false.
+// See also [IsRegularFile].
+func IsFIFO(path string) bool {
+	mode, ok := modeOf(path)
+	return ok && mode&fs.ModeNamedPipe != 0
+}
+
+// IsRegularFile returns true if path is a regular file (i.e., not a
+// folder, named pipe, socket, or device), so it is safe to read; otherwise
+// returns false. Symlinks are followed. See also [FileExists].
+func IsRegularFile(path string) bool {
+	mode, ok := modeOf(path)
+	return ok && mode.IsRegular()
+}
+
+// IsSocket returns true if path is a Unix domain socket; otherwise returns
+// false. See also [IsRegularFile].
+func IsSocket(path string) bool {
+	mode, ok := modeOf(path)
+	return ok && mode&fs.ModeSocket != 0
+}
+
+// modeOf returns path's mode (following symlinks) and true, or 0 and
+// false if path can't be stat'ed.
+func modeOf(path string) (fs.FileMode, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	return info.Mode(), true
+}
+
+// JoinAll returns the path made from the given components, the first of
+// which may be a root as returned by [SplitAll], using the root's
+// separator if it has one or the platform's separator otherwise. So for
+// any path, JoinAll(SplitAll(path)) is path with redundant separators and
+// "." components removed.
+func JoinAll(parts []string) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	root := ""
+	if isPathRoot(parts[0]) {
+		root = parts[0]
+		parts = parts[1:]
+	}
+	sep := string(os.PathSeparator)
+	if strings.HasSuffix(root, "/") || strings.HasSuffix(root, `\`) {
+		sep = root[len(root)-1:]
+	} else if root != "" { // a drive-relative volume, e.g., `C:`
+		sep = `\`
+	}
+	return root + strings.Join(parts, sep)
+}
+
 // LongestCommonPath returns the longest common path, i.e., component,
 // / or \ separated (which could be "" if there isn't one), and lowercased
-// on Windows.
+// on Windows and macOS. Paths on different drives (e.g., `C:\` vs `D:\`)
+// or UNC shares (e.g., `\\server\share`) have no common path, and a UNC
+// share root is never split. The paths slice is not modified.
+// See also [LongestCommonPathOpt].
 func LongestCommonPath(paths []string) string {
-	caseInsensitive := runtime.GOOS == "windows" ||
-		runtime.GOOS == "darwin"
+	return LongestCommonPathOpt(paths, runtime.GOOS == "windows" ||
+		runtime.GOOS == "darwin")
+}
+
+// LongestCommonPathOpt returns the longest common path, i.e., component,
+// / or \ separated (which could be "" if there isn't one), and if
+// caseInsensitive is true, compares and returns lowercased paths.
+// The paths slice is not modified. See also [LongestCommonPath].
+func LongestCommonPathOpt(paths []string, caseInsensitive bool) string {
 	if len(paths) == 0 {
 		return ""
 	} else if len(paths) == 1 {
@@ -183,15 +553,28 @@ func LongestCommonPath(paths []string) string {
 		return paths[0]
 	}
 	if caseInsensitive {
-		for i := range len(paths) {
-			paths[i] = strings.ToLower(paths[i])
+		lowered := make([]string, len(paths))
+		for i, path := range paths {
+			lowered[i] = strings.ToLower(path)
+		}
+		paths = lowered
+	}
+	volume := volumeName(paths[0])
+	for _, path := range paths[1:] {
+		if !strings.EqualFold(volumeName(path), volume) {
+			return "" // different drives or UNC shares
 		}
 	}
 	prefix := utext.LongestCommonPrefix(paths)
+	if len(prefix) < len(volume) { // never split a drive or UNC share
+		return ""
+	}
 	if len(prefix) > 1 {
 		i := strings.LastIndexByte(prefix, os.PathSeparator)
 		if i == -1 { // no path separator to slice to
 			prefix = ""
+		} else if volume != "" && i <= len(volume) {
+			prefix = prefix[:len(volume)] + string(os.PathSeparator)
 		} else {
 			if i == 0 { // preserve root of / or \
 				i = 1
@@ -202,6 +585,35 @@ func LongestCommonPath(paths []string) string {
 	return prefix
 }
 
+// volumeName returns the leading drive (e.g., `C:`) or UNC share (e.g.,
+// `\\server\share`) of the given path, or "" if it has neither. Unlike
+// [filepath.VolumeName] this recognizes Windows volumes on every platform.
+func volumeName(path string) string {
+	if len(path) >= 2 && path[1] == ':' && ('a' <= path[0] &&
+		path[0] <= 'z' || 'A' <= path[0] && path[0] <= 'Z') {
+		return path[:2]
+	}
+	isSep := func(c byte) bool {
+		return c == '\\' || (runtime.GOOS == "windows" && c == '/')
+	}
+	if len(path) < 5 || !isSep(path[0]) || !isSep(path[1]) ||
+		isSep(path[2]) {
+		return ""
+	}
+	i := 3
+	for i < len(path) && !isSep(path[i]) { // server
+		i++
+	}
+	if i+1 >= len(path) || isSep(path[i+1]) {
+		return "" // no share
+	}
+	i++
+	for i < len(path) && !isSep(path[i]) { // share
+		i++
+	}
+	return path[:i]
+}
+
 // PathExists returns true if the path/filename exists.
 // See also [FileExists].
 func PathExists(path string) bool {
@@ -209,14 +621,108 @@ func PathExists(path string) bool {
 	return err == nil
 }
 
+// ReadChunks reads the given file and returns an iterator of (chunk,
+// error) for every chunkSize block of the file (the last may be shorter).
+// The chunk's underlying buffer is reused so it must be copied if it is
+// to be kept beyond the current iteration.
+func ReadChunks(filename string, chunkSize int) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		if chunkSize <= 0 {
+			yield(nil, fmt.Errorf("invalid chunk size %d", chunkSize))
+			return
+		}
+		file, err := os.Open(filename)
+		if err != nil {
+			yield(nil, err) // failed to open file
+			return          // we cannot progress from here
+		}
+		defer file.Close()
+		chunk := make([]byte, chunkSize)
+		for {
+			n, err := io.ReadFull(file, chunk)
+			if n > 0 && !yield(chunk[:n], nil) {
+				return
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err) // read error
+				return
+			}
+		}
+	}
+}
+
 // ReadTextFile reads the given file and returns a slices of lines with
 // EOL stripped off. Will automatically uncompress .gz files.
-// See also [ReadUtf8Lines]
+// See also [ReadTextFileInfo], [ReadTextFileLimited], and [ReadUtf8Lines]
 func ReadTextFile(filename string) ([]string, error) {
+	raw, err := readRaw(filename)
+	if err != nil {
+		return nil, err
+	}
+	return rawLines(raw), nil
+}
+
+// ReadTextFileLimited is like [ReadTextFile] except that it returns an
+// error wrapping [ErrTooLarge] rather than reading the file if it is
+// larger than maxBytes (or, for .gz files, if it uncompresses to more than
+// maxBytes). Callers can then fall back to streaming, e.g., using
+// [ReadUtf8Lines].
+func ReadTextFileLimited(filename string, maxBytes int64) ([]string,
+	error,
+) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	tooLarge := &fs.PathError{Op: "read", Path: filename, Err: ErrTooLarge}
+	if info, err := file.Stat(); err != nil {
+		return nil, err
+	} else if info.Size() > maxBytes {
+		return nil, tooLarge
+	}
+	buffered := bufio.NewReader(file)
+	var reader io.Reader = buffered
+	if strings.HasSuffix(filename, ".gz") {
+		magic, _ := buffered.Peek(2)
+		if len(magic) == 2 && magic[0] == 0x1F && magic[1] == 0x8B {
+			if reader, err = gzip.NewReader(reader); err != nil {
+				return nil, err
+			}
+		}
+	}
+	raw, err := io.ReadAll(io.LimitReader(reader, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(raw)) > maxBytes {
+		return nil, tooLarge
+	}
+	return rawLines(raw), nil
+}
+
+// rawLines returns raw split into lines with EOL stripped off.
+func rawLines(raw []byte) []string {
+	raw = bytes.ReplaceAll(raw, []byte{'\r'}, []byte{})
+	raw = bytes.TrimRight(raw, "\n")
+	return strings.Split(string(raw), "\n")
+}
+
+// readRaw returns the given file's bytes, uncompressing .gz files.
+func readRaw(filename string) ([]byte, error) {
 	raw, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
+	return gunzipRaw(filename, raw)
+}
+
+// gunzipRaw returns raw uncompressed if filename is a .gz file and raw is
+// gzip-compressed; otherwise returns raw unchanged.
+func gunzipRaw(filename string, raw []byte) ([]byte, error) {
 	if strings.HasSuffix(filename, ".gz") && len(raw) > 2 &&
 		raw[0] == 0x1F && raw[1] == 0x8B {
 		reader := bytes.NewReader(raw)
@@ -229,13 +735,39 @@ func ReadTextFile(filename string) ([]string, error) {
 			}
 		}
 	}
-	raw = bytes.ReplaceAll(raw, []byte{'\r'}, []byte{})
-	raw = bytes.TrimRight(raw, "\n")
-	return strings.Split(string(raw), "\n"), nil
+	return raw, nil
+}
+
+// ReadLinesContext works like [ReadUtf8Lines] but checks ctx before
+// opening the file and between lines, and if ctx is cancelled yields the
+// context's error and stops.
+func ReadLinesContext(ctx context.Context,
+	filename string,
+) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		if err := ctx.Err(); err != nil {
+			yield("", err)
+			return
+		}
+		file, err := os.Open(filename)
+		if err != nil {
+			yield("", err) // failed to open file
+			return         // we cannot progress from here
+		}
+		defer file.Close()
+		yieldLines(bufio.NewReader(file), func(line string, err error) bool {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				yield("", ctxErr)
+				return false
+			}
+			return yield(line, err)
+		})
+	}
 }
 
 // ReadUtf8Lines reads the given file and returns an iterator of (line,
-// error) for every line with EOL stripped off. See also [ReadTextFile].
+// error) for every line with EOL stripped off. See also [ReadTextFile]
+// and [ReadLinesContext].
 func ReadUtf8Lines(filename string) iter.Seq2[string, error] {
 	return func(yield func(string, error) bool) {
 		file, err := os.Open(filename)
@@ -244,47 +776,378 @@ func ReadUtf8Lines(filename string) iter.Seq2[string, error] {
 			return         // we cannot progress from here
 		}
 		defer file.Close()
-		reader := bufio.NewReader(file)
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil && err != io.EOF {
-				yield("", err) // read error
-				return         // we cannot progress further
-			}
-			if line == "" && err == io.EOF {
-				break // last (i.e., prev.) line ended with \n
-			}
-			if !yield(strings.TrimRight(line, "\r\n"), nil) {
-				return // for loop break or return or panic
-			}
-			if err == io.EOF {
-				break // last line did not end with \n
-			}
+		yieldLines(bufio.NewReader(file), yield)
+	}
+}
+
+// yieldLines calls yield with every line read from reader with EOL
+// stripped off.
+func yieldLines(reader *bufio.Reader, yield func(string, error) bool) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			yield("", err) // read error
+			return         // we cannot progress further
 		}
+		if line == "" && err == io.EOF {
+			break // last (i.e., prev.) line ended with \n
+		}
+		if !yield(strings.TrimRight(line, "\r\n"), nil) {
+			return // for loop break or return or panic
+		}
+		if err == io.EOF {
+			break // last line did not end with \n
+		}
+	}
+}
+
+// ReadUtf8LinesGz reads the given gzip-compressed file and returns an
+// iterator of (line, error) for every uncompressed line with EOL stripped
+// off. See also [ReadUtf8Lines] and [WriteTextFileGz].
+func ReadUtf8LinesGz(filename string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		file, err := os.Open(filename)
+		if err != nil {
+			yield("", err) // failed to open file
+			return         // we cannot progress from here
+		}
+		defer file.Close()
+		gzreader, err := gzip.NewReader(bufio.NewReader(file))
+		if err != nil {
+			yield("", err) // not a gzip file
+			return
+		}
+		defer gzreader.Close()
+		yieldLines(bufio.NewReader(gzreader), yield)
+	}
+}
+
+// RelativizeAll returns the longest common path of the given paths (see
+// [LongestCommonPath]) in its original case, and each path relative to it.
+// If there is just one path the root is its folder; if there is no common
+// path the root is "" and the paths are returned unchanged.
+// See also [AbsPath].
+func RelativizeAll(paths []string) (root string, rels []string) {
+	if len(paths) == 0 {
+		return "", nil
 	}
+	if len(paths) == 1 {
+		root = filepath.Dir(paths[0])
+	} else {
+		root = LongestCommonPath(paths)
+		if len(root) <= len(paths[0]) &&
+			strings.EqualFold(paths[0][:len(root)], root) {
+			root = paths[0][:len(root)] // restore original case
+		}
+	}
+	rels = make([]string, 0, len(paths))
+	for _, path := range paths {
+		rels = append(rels, relativeTo(root, path))
+	}
+	return root, rels
+}
+
+func relativeTo(root, path string) string {
+	if root == "" {
+		return path
+	}
+	if len(root) <= len(path) && strings.EqualFold(path[:len(root)], root) {
+		rel := strings.TrimLeft(path[len(root):], `/\`)
+		if rel == "" {
+			return "."
+		}
+		return rel
+	}
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// SplitExt returns path split into its stem and its (final) extension,
+// such that stem + ext == path, e.g., "a/notes.tar.gz" gives "a/notes.tar"
+// and ".gz". Unlike [filepath.Ext], a dotfile's leading dot doesn't start
+// an extension (so ".bashrc" gives ".bashrc" and ""), and nor does a
+// trailing dot (so "notes." gives "notes." and ""). See also [Barename].
+func SplitExt(path string) (stem, ext string) {
+	start := strings.LastIndexAny(path, `/\`) + 1
+	name := trimTrailingDots(path[start:])
+	dots := len(name) - len(strings.TrimLeft(name, "."))
+	i := strings.LastIndexByte(name[dots:], '.')
+	if i < 0 || len(name) < len(path[start:]) {
+		return path, ""
+	}
+	i += start + dots
+	return path[:i], path[i:]
+}
+
+// ShortestPath returns whichever of path's relative (to folder base, or
+// to the current folder if base is "") or absolute forms is shorter,
+// preferring the relative form if they're the same length. This is useful
+// for showing filenames in diagnostics. If path can't be made relative
+// (e.g., it's on a different drive) the absolute form is returned. See
+// also [AbsPath] and [RelativizeAll].
+func ShortestPath(path, base string) string {
+	abs := AbsPath(path)
+	if base == "" {
+		base = "."
+	}
+	rel, err := filepath.Rel(AbsPath(base), abs)
+	if err != nil || len(rel) > len(abs) {
+		return abs
+	}
+	return rel
+}
+
+// SplitAll returns all of path's components, with its root, if it has
+// one, as the first component, e.g., "/a/b" gives ["/" "a" "b"], `C:\a`
+// gives [`C:\` "a"], `C:a` gives ["C:" "a"], `\\server\share\a` gives
+// [`\\server\share\` "a"], and "a//./b/" gives ["a" "b"]. Both "/" and
+// "\" are treated as separators on every platform (as by [Barename]).
+// Empty and "." components are dropped but ".." components are kept.
+// See also [Depth] and [JoinAll].
+func SplitAll(path string) []string {
+	root := pathRoot(path)
+	var parts []string
+	if root != "" {
+		parts = append(parts, root)
+	}
+	isSep := func(c rune) bool { return c == '/' || c == '\\' }
+	for _, part := range strings.FieldsFunc(path[len(root):], isSep) {
+		if part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// pathRoot returns path's leading volume (see [volumeName]) and separator
+// if it has either; otherwise returns "".
+func pathRoot(path string) string {
+	root := volumeName(path)
+	if len(path) > len(root) && (path[len(root)] == '/' ||
+		path[len(root)] == '\\') {
+		root = path[:len(root)+1]
+	}
+	return root
+}
+
+// isPathRoot returns true if part is a root as returned by [pathRoot];
+// otherwise returns false.
+func isPathRoot(part string) bool {
+	return part != "" && pathRoot(part) == part
+}
+
+// trimTrailingDots returns name without any trailing dots unless it
+// consists only of dots (e.g., ".." stays "..").
+func trimTrailingDots(name string) string {
+	if trimmed := strings.TrimRight(name, "."); trimmed != "" {
+		return trimmed
+	}
+	return name
+}
+
+// WithSuffix returns path with its extension (see [SplitExt]) replaced by
+// suffix, e.g., "a/notes.txt" with ".md" gives "a/notes.md", and
+// ".bashrc" with ".bak" gives ".bashrc.bak". An empty suffix removes the
+// extension.
+func WithSuffix(path, suffix string) string {
+	stem, _ := SplitExt(path)
+	return stem + suffix
 }
 
 // WriteTextFile writes the given lines to the given filename adding the
-// platform-appropriate EOL to each line written.
+// platform-appropriate EOL to each line written. See also
+// [WriteTextFileOpt], [WriteTextFileIfChanged], [WriteTextFileFS],
+// [WriteTextFileGz], and [WriteTextFileInfo].
 func WriteTextFile(filename string, lines []string) error {
+	return writeTextFile(filename, lines, WriteOptions{})
+}
+
+// WriteTextFileIfChanged works like [WriteTextFile] except that if the
+// file already has exactly the content that would be written it is left
+// untouched (so its modification time is preserved, which avoids
+// triggering needless rebuilds), and returns whether the file was
+// written. See also [WriteOptions] IfChanged.
+func WriteTextFileIfChanged(filename string, lines []string) (bool,
+	error,
+) {
+	if same, err := hasLines(filename, lines, platformEOL()); err != nil ||
+		same {
+		return false, err
+	}
+	return true, writeTextFile(filename, lines, WriteOptions{})
+}
+
+// hasLines returns true if filename exists and has exactly the content
+// that writing lines with the given EOL would produce; otherwise returns
+// false.
+func hasLines(filename string, lines []string, eol string) (bool, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	var content bytes.Buffer
+	if err = writeLinesEOL(&content, slices.Values(lines),
+		eol); err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() || info.Size() != int64(content.Len()) {
+		return false, nil
+	}
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(raw, content.Bytes()), nil
+}
+
+func writeTextFile(filename string, lines []string,
+	opts WriteOptions,
+) error {
+	eol := platformEOL()
+	if opts.Deterministic {
+		eol = "\n"
+	}
+	if opts.IfChanged {
+		if same, err := hasLines(filename, lines, eol); err != nil || same {
+			return err
+		}
+	}
+	var err error
+	if opts.Atomic {
+		err = writeAtomicOpt(filename, opts.Verify,
+			func(out *bufio.Writer) error {
+				return writeLinesEOL(out, slices.Values(lines), eol)
+			})
+	} else {
+		err = writeTextFilePlain(filename, lines, eol, opts)
+	}
+	if err != nil || !opts.Deterministic {
+		return err
+	}
+	when := reproducibleTime()
+	return os.Chtimes(filename, when, when)
+}
+
+// writeTextFilePlain writes lines to filename in place using the given
+// EOL, syncing and verifying as opts specify.
+func writeTextFilePlain(filename string, lines []string, eol string,
+	opts WriteOptions,
+) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	eol := "\n"
-	if runtime.GOOS == "windows" {
-		eol = "\r\n"
+	var writer io.Writer = file
+	hash := sha256.New()
+	if opts.Verify {
+		writer = io.MultiWriter(file, hash)
 	}
-	out := bufio.NewWriter(file)
-	for _, line := range lines {
-		if _, err = out.WriteString(line); err != nil {
+	if err = writeLinesEOL(writer, slices.Values(lines), eol); err != nil {
+		return err
+	}
+	if opts.Sync {
+		if err = file.Sync(); err != nil {
 			return err
 		}
-		if _, err = out.WriteString(eol); err != nil {
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if opts.Verify {
+		if err = verifyWritten(filename, hash.Sum(nil)); err != nil {
 			return err
 		}
 	}
-	out.Flush()
+	if opts.Sync {
+		return SyncDir(filepath.Dir(filename))
+	}
 	return nil
 }
+
+// WriteOptions are used by [WriteTextFileOpt].
+//
+// If CreateDirs is true the file's folder is created first if necessary
+// (see [EnsureParentDir]). If Sync is true the file and its folder are
+// synced to stable storage after writing (see [SyncFile] and [SyncDir]).
+// If Atomic is true the lines are written to a temporary file which is
+// then synced and renamed over filename, so readers see either the old or
+// the new file but never a partial one. If Verify is true the written
+// file is read back and its checksum compared with that of the data
+// written, and an error wrapping [ErrVerifyFailed] is returned if they
+// differ (for atomic writes the original file is then left untouched).
+// Note that the reread may be satisfied from the operating system's
+// cache, so it is most effective combined with Sync or Atomic. If
+// IfChanged is true nothing is written if the file already has exactly
+// the content that would be written (see [WriteTextFileIfChanged]). If
+// Deterministic is true the file is written with \n line endings on
+// every platform and its modification time is set to a fixed time
+// (1980-01-01, or the time given by the SOURCE_DATE_EPOCH environment
+// variable), so generated files are identical on every machine.
+type WriteOptions struct {
+	CreateDirs    bool
+	Sync          bool
+	Atomic        bool
+	Verify        bool
+	IfChanged     bool
+	Deterministic bool
+}
+
+// WriteTextFileOpt works like [WriteTextFile] but with the given options.
+func WriteTextFileOpt(filename string, lines []string,
+	opts WriteOptions,
+) error {
+	if opts.CreateDirs {
+		if err := EnsureParentDir(filename); err != nil {
+			return err
+		}
+	}
+	return writeTextFile(filename, lines, opts)
+}
+
+// WriteTextFileGz writes the given lines gzip-compressed at the given
+// level (see [gzip.NewWriterLevel]) to the given filename adding the
+// platform-appropriate EOL to each line written. Read such files with
+// [ReadTextFile] or [ReadUtf8LinesGz].
+func WriteTextFileGz(filename string, lines []string, level int) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gzwriter, err := gzip.NewWriterLevel(file, level)
+	if err != nil {
+		return err
+	}
+	if err = writeLines(gzwriter, slices.Values(lines)); err != nil {
+		return err
+	}
+	if err = gzwriter.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func writeLines(writer io.Writer, lines iter.Seq[string]) error {
+	return writeLinesEOL(writer, lines, platformEOL())
+}
+
+func writeLinesEOL(writer io.Writer, lines iter.Seq[string],
+	eol string,
+) error {
+	out := bufio.NewWriter(writer)
+	for line := range lines {
+		if _, err := out.WriteString(line); err != nil {
+			return err
+		}
+		if _, err := out.WriteString(eol); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
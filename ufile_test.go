@@ -1,19 +1,26 @@
 package ufile
 
 import (
+	"compress/gzip"
+	"context"
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test_Barename(t *testing.T) {
 	names := []string{"/home/mark/data.dat", `C:\Users\mark\config.ini`,
-		"archive.tar.gz", "master.zip", "README", "weird.named.file.xz"}
+		"archive.tar.gz", "master.zip", "README", "weird.named.file.xz",
+		"/home/mark/.bashrc", ".config.tar.gz", "notes.", `C:\x\a.txt.`,
+		".."}
 	bares := []string{"data", "config", "archive", "master", "README",
-		"weird"}
+		"weird", ".bashrc", ".config", "notes", "a", ".."}
 	for i, name := range names {
 		bare := Barename(name)
 		if bare != bares[i] {
@@ -22,6 +29,86 @@ func Test_Barename(t *testing.T) {
 	}
 }
 
+func Test_SplitExt(t *testing.T) {
+	for _, item := range [][3]string{
+		{"a/notes.tar.gz", "a/notes.tar", ".gz"},
+		{"README", "README", ""},
+		{"/home/mark/.bashrc", "/home/mark/.bashrc", ""},
+		{"..config.json", "..config", ".json"},
+		{"notes.", "notes.", ""},
+		{`C:\x.d\file`, `C:\x.d\file`, ""},
+		{"a.d/", "a.d/", ""},
+		{"..", "..", ""},
+	} {
+		stem, ext := SplitExt(item[0])
+		if stem != item[1] || ext != item[2] {
+			t.Errorf("expected %q %q got %q %q", item[1], item[2], stem,
+				ext)
+		}
+	}
+}
+
+func Test_SplitAll(t *testing.T) {
+	for _, test := range []struct {
+		path     string
+		parts    []string
+		joined   string
+		expected int
+	}{
+		{"", nil, "", 0},
+		{"/", []string{"/"}, "/", 0},
+		{"/a/b", []string{"/", "a", "b"}, "/a/b", 2},
+		{"a//./b/", []string{"a", "b"}, filepath.FromSlash("a/b"), 2},
+		{"../a", []string{"..", "a"}, filepath.FromSlash("../a"), 2},
+		{`C:\a\b.txt`, []string{`C:\`, "a", "b.txt"}, `C:\a\b.txt`, 2},
+		{`C:a`, []string{"C:", "a"}, `C:a`, 1},
+		{`\\server\share\a`, []string{`\\server\share\`, "a"},
+			`\\server\share\a`, 1},
+	} {
+		parts := SplitAll(test.path)
+		if !slices.Equal(parts, test.parts) {
+			t.Errorf("expected %q got %q", test.parts, parts)
+		}
+		if joined := JoinAll(parts); joined != test.joined {
+			t.Errorf("expected %q got %q", test.joined, joined)
+		}
+		if depth := Depth(test.path); depth != test.expected {
+			t.Errorf("%q: expected %d got %d", test.path, test.expected,
+				depth)
+		}
+	}
+}
+
+func Test_ShortestPath(t *testing.T) {
+	base := AbsPath(t.TempDir())
+	near := filepath.Join(base, "src", "main.go")
+	if path := ShortestPath(near, base); path != filepath.Join("src",
+		"main.go") {
+		t.Errorf("expected src/main.go got %q", path)
+	}
+	far := filepath.Join(filepath.VolumeName(base)+string(filepath.Separator),
+		"x")
+	if path := ShortestPath(far, base); path != far {
+		t.Errorf("expected %q got %q", far, path)
+	}
+	if path := ShortestPath(base, base); path != "." {
+		t.Errorf("expected . got %q", path)
+	}
+}
+
+func Test_WithSuffix(t *testing.T) {
+	for _, item := range [][3]string{
+		{"a/notes.txt", ".md", "a/notes.md"},
+		{"archive.tar.gz", ".xz", "archive.tar.xz"},
+		{".bashrc", ".bak", ".bashrc.bak"},
+		{"README.md", "", "README"},
+	} {
+		if path := WithSuffix(item[0], item[1]); path != item[2] {
+			t.Errorf("expected %q got %q", item[2], path)
+		}
+	}
+}
+
 func Test_LongestCommonPath1(t *testing.T) {
 	items := []string{"/home/mark/app/go/ufile",
 		"/home/mark/app/py/accelhints", "/home/mark/app/rs"}
@@ -200,6 +287,98 @@ func Test_LongestCommonPath6(t *testing.T) {
 	}
 }
 
+func Test_LongestCommonPathOpt(t *testing.T) {
+	items := []string{filepath.FromSlash("/Home/Mark/app/go"),
+		filepath.FromSlash("/home/mark/app/rs")}
+	orig := slices.Clone(items)
+	prefix := LongestCommonPathOpt(items, true)
+	if prefix != filepath.FromSlash("/home/mark/app") {
+		t.Errorf("expected /home/mark/app got %q", prefix)
+	}
+	if slices.Compare(items, orig) != 0 {
+		t.Errorf("expected unchanged %q got %q", orig, items)
+	}
+	prefix = LongestCommonPathOpt(items, false)
+	if prefix != filepath.FromSlash("/") {
+		t.Errorf("expected / got %q", prefix)
+	}
+	_ = LongestCommonPath(items)
+	if slices.Compare(items, orig) != 0 {
+		t.Errorf("expected unchanged %q got %q", orig, items)
+	}
+}
+
+func Test_LongestCommonPath7(t *testing.T) {
+	items := []string{`C:\users\mark\app`, `D:\users\mark\app`}
+	if prefix := LongestCommonPath(items); prefix != "" {
+		t.Errorf("expected \"\" got %q", prefix)
+	}
+	items = []string{`\\server\share1\app`, `\\server\share2\app`}
+	if prefix := LongestCommonPath(items); prefix != "" {
+		t.Errorf("expected \"\" got %q", prefix)
+	}
+	items = []string{`\\server\share\app`, `C:\share\app`}
+	if prefix := LongestCommonPath(items); prefix != "" {
+		t.Errorf("expected \"\" got %q", prefix)
+	}
+	if runtime.GOOS == "windows" {
+		items = []string{`\\server\share\app\go`,
+			`\\server\share\app\rs`}
+		prefix := LongestCommonPath(items)
+		if prefix != `\\server\share\app` {
+			t.Errorf(`expected \\server\share\app got %q`, prefix)
+		}
+		items = []string{`\\server\share\go`, `\\server\share\rs`}
+		if prefix := LongestCommonPath(items); prefix != `\\server\share\` {
+			t.Errorf(`expected \\server\share\ got %q`, prefix)
+		}
+		items = []string{`c:\go`, `c:\rs`}
+		if prefix := LongestCommonPath(items); prefix != `c:\` {
+			t.Errorf(`expected c:\ got %q`, prefix)
+		}
+	}
+}
+
+func Test_volumeName(t *testing.T) {
+	names := []string{`C:\users`, `d:`, `\\server\share\dir`,
+		`\\server\share`, `\\server`, `\\\server\share`, "/home/mark",
+		"rel/path"}
+	volumes := []string{"C:", "d:", `\\server\share`, `\\server\share`,
+		"", "", "", ""}
+	for i, name := range names {
+		if volume := volumeName(name); volume != volumes[i] {
+			t.Errorf("expected %q got %q", volumes[i], volume)
+		}
+	}
+}
+
+func Test_RelativizeAll(t *testing.T) {
+	items := []string{"/home/mark/app/go/ufile",
+		"/home/mark/app/py/accelhints", "/home/mark/app/rs"}
+	for i := range len(items) {
+		items[i] = filepath.FromSlash(items[i])
+	}
+	root, rels := RelativizeAll(items)
+	if root != filepath.FromSlash("/home/mark/app") {
+		t.Errorf("expected /home/mark/app got %q", root)
+	}
+	expected := []string{filepath.FromSlash("go/ufile"),
+		filepath.FromSlash("py/accelhints"), "rs"}
+	if slices.Compare(rels, expected) != 0 {
+		t.Errorf("expected %q got %q", expected, rels)
+	}
+	root, rels = RelativizeAll([]string{filepath.FromSlash("/tmp/x.txt")})
+	if root != filepath.FromSlash("/tmp") || len(rels) != 1 ||
+		rels[0] != "x.txt" {
+		t.Errorf("expected /tmp [x.txt] got %q %q", root, rels)
+	}
+	items = []string{"home", "homeric"}
+	root, rels = RelativizeAll(items)
+	if root != "" || slices.Compare(rels, items) != 0 {
+		t.Errorf("expected \"\" %q got %q %q", items, root, rels)
+	}
+}
+
 func Test_read_write_text(t *testing.T) {
 	Lines := []string{"This text file has non-ASCII characters:",
 		"é and ä, π and α²."}
@@ -227,3 +406,317 @@ func Test_read_write_text(t *testing.T) {
 		log.Fatalf("%q ReadUtf8Lines !=\n%q\n", Lines, lines)
 	}
 }
+
+func Test_AppendTextFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "append.txt")
+	if err := AppendTextFile(filename, []string{"one", "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filename); err != nil ||
+		(runtime.GOOS != "windows" && info.Mode().Perm() != ModeURW) {
+		t.Errorf("expected new file with mode %o got %v", ModeURW, err)
+	}
+	err := AppendLines(filename, slices.Values([]string{"three"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := ReadTextFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"one", "two", "three"}
+	if slices.Compare(lines, expected) != 0 {
+		t.Errorf("expected %q got %q", expected, lines)
+	}
+}
+
+func Test_WriteTextFileGz(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "export.txt.gz")
+	Lines := []string{"first", "", "third"}
+	if err := WriteTextFileGz(filename, Lines,
+		gzip.BestCompression); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := ReadTextFile(filename)
+	if err != nil || slices.Compare(lines, Lines) != 0 {
+		t.Errorf("expected %q got %q %v", Lines, lines, err)
+	}
+	lines = []string{}
+	for line, err := range ReadUtf8LinesGz(filename) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if slices.Compare(lines, Lines) != 0 {
+		t.Errorf("expected %q got %q", Lines, lines)
+	}
+}
+
+func Test_ReadTextFileLimited(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "small.txt")
+	expected := []string{"one", "two"}
+	if err := WriteTextFile(filename, expected); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := ReadTextFileLimited(filename, 100)
+	if err != nil || slices.Compare(lines, expected) != 0 {
+		t.Errorf("expected %q got %q %v", expected, lines, err)
+	}
+	if _, err = ReadTextFileLimited(filename, 4); !errors.Is(err,
+		ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge got %v", err)
+	}
+	gzname := filepath.Join(dir, "big.txt.gz")
+	big := slices.Repeat([]string{strings.Repeat("x", 100)}, 100)
+	if err = WriteTextFileGz(gzname, big, gzip.BestCompression); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadTextFileLimited(gzname, 5000); !errors.Is(err,
+		ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge for .gz got %v", err)
+	}
+	if lines, err = ReadTextFileLimited(gzname, 20_000); err != nil ||
+		slices.Compare(lines, big) != 0 {
+		t.Errorf("expected big lines got %d %v", len(lines), err)
+	}
+}
+
+func Test_ReadLinesContext(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "lines.txt")
+	expected := []string{"one", "two", "three"}
+	if err := WriteTextFile(filename, expected); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lines []string
+	var lastErr error
+	for line, err := range ReadLinesContext(ctx, filename) {
+		if err != nil {
+			lastErr = err
+			break
+		}
+		lines = append(lines, line)
+		if len(lines) == 2 {
+			cancel()
+		}
+	}
+	if !errors.Is(lastErr, context.Canceled) ||
+		slices.Compare(lines, expected[:2]) != 0 {
+		t.Errorf("expected %q and cancellation got %q %v", expected[:2],
+			lines, lastErr)
+	}
+	for _, err := range ReadLinesContext(ctx, filename) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected cancellation got %v", err)
+		}
+	}
+}
+
+func Test_FilesEqual(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 100_000)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	names := []string{"a.bin", "b.bin", "c.bin", "d.bin"}
+	for i, name := range names {
+		content := slices.Clone(data)
+		switch i {
+		case 2:
+			content[99_999]++
+		case 3:
+			content = content[:99_999]
+		}
+		err := os.WriteFile(filepath.Join(dir, name), content, ModeURW)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, expected := range []bool{true, false, false} {
+		equal, err := FilesEqual(filepath.Join(dir, names[0]),
+			filepath.Join(dir, names[i+1]))
+		if err != nil || equal != expected {
+			t.Errorf("%s: expected %t got %t %v", names[i+1], expected,
+				equal, err)
+		}
+	}
+	if _, err := FilesEqual(filepath.Join(dir, "a.bin"),
+		filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func Test_EnsureDir(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "a", "b")
+	if err := EnsureDir(sub, 0o755); err != nil || !IsDir(sub) {
+		t.Errorf("expected %q to be created: %v", sub, err)
+	}
+	if err := EnsureDir(sub, 0o755); err != nil {
+		t.Errorf("expected existing folder to be OK: %v", err)
+	}
+	filename := filepath.Join(dir, "file.txt")
+	if err := WriteTextFile(filename, []string{"x"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureDir(filename, 0o755); err == nil {
+		t.Error("expected error for existing file")
+	}
+	if err := EnsureParentDir(filepath.Join(filename, "x")); err == nil {
+		t.Error("expected error for file as parent")
+	}
+	deep := filepath.Join(dir, "c", "d", "e.txt")
+	if err := WriteTextFile(deep, []string{"x"}); err == nil {
+		t.Error("expected error for missing folder")
+	}
+	if err := WriteTextFileOpt(deep, []string{"x"},
+		WriteOptions{CreateDirs: true}); err != nil || !FileExists(deep) {
+		t.Errorf("expected %q to be created: %v", deep, err)
+	}
+	if err := WriteTextFileOpt(deep, []string{"y"},
+		WriteOptions{Sync: true}); err != nil {
+		t.Error(err)
+	}
+	if lines, err := ReadTextFile(deep); err != nil || lines[0] != "y" {
+		t.Errorf("expected %q got %q %v", "y", lines, err)
+	}
+	for _, opts := range []WriteOptions{{Verify: true},
+		{Atomic: true, Verify: true}, {Atomic: true}} {
+		if err := WriteTextFileOpt(deep, []string{"z"}, opts); err != nil {
+			t.Errorf("%+v: %v", opts, err)
+		}
+		if lines, err := ReadTextFile(deep); err != nil ||
+			lines[0] != "z" {
+			t.Errorf("%+v: expected %q got %q %v", opts, "z", lines, err)
+		}
+		os.Remove(deep)
+	}
+	if tree, _ := TreeString(filepath.Dir(deep)); tree != ".\n" {
+		t.Errorf("expected no temporary files got\n%s", tree)
+	}
+	if err := verifyWritten(filename, []byte("wrong")); !errors.Is(err,
+		ErrVerifyFailed) {
+		t.Errorf("expected ErrVerifyFailed got %v", err)
+	}
+}
+
+func Test_WriteTextFileIfChanged(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "gen.go")
+	lines := []string{"package gen", "", "const N = 1"}
+	if changed, err := WriteTextFileIfChanged(filename, lines); err != nil ||
+		!changed {
+		t.Errorf("expected new file to be written got %t %v", changed, err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filename, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := WriteTextFileIfChanged(filename, lines); err != nil ||
+		changed {
+		t.Errorf("expected no write got %t %v", changed, err)
+	}
+	if err := WriteTextFileOpt(filename, lines,
+		WriteOptions{IfChanged: true, Atomic: true}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filename); !info.ModTime().Equal(old) {
+		t.Errorf("expected mtime %v got %v", old, info.ModTime())
+	}
+	lines[2] = "const N = 2"
+	if changed, err := WriteTextFileIfChanged(filename, lines); err != nil ||
+		!changed {
+		t.Errorf("expected write got %t %v", changed, err)
+	}
+	if got, _ := ReadTextFile(filename); !slices.Equal(got, lines) {
+		t.Errorf("expected %q got %q", lines, got)
+	}
+}
+
+func Test_WriteTextFileDeterministic(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "gen.txt")
+	if err := WriteTextFileOpt(filename, []string{"a", "b"},
+		WriteOptions{Deterministic: true, Atomic: true}); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := os.ReadFile(filename); string(raw) != "a\nb\n" {
+		t.Errorf("expected LF line endings got %q", raw)
+	}
+	if info, _ := os.Stat(filename); !info.ModTime().Equal(
+		deterministicTime) {
+		t.Errorf("expected %v got %v", deterministicTime, info.ModTime())
+	}
+}
+
+func Test_ExecutableDir(t *testing.T) {
+	dir := ExecutableDir()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	if dir != filepath.Dir(exe) {
+		t.Errorf("expected %q got %q", filepath.Dir(exe), dir)
+	}
+	appname := "ufile test portable"
+	filename, found := GetConfigFileOpt("", appname, "ini", true)
+	expected := filepath.Join(dir, "ufile_test_portable.ini")
+	if found || filename != expected {
+		t.Errorf("expected %q got %q %t", expected, filename, found)
+	}
+	dir = t.TempDir()
+	expected = filepath.Join(dir, "ufile_test_portable.ini")
+	if err = WriteTextFile(expected, []string{"[x]"}); err != nil {
+		t.Fatal(err)
+	}
+	filename, found = getConfigFile("", appname, "ini", dir)
+	if !found || filename != expected {
+		t.Errorf("expected %q got %q %t", expected, filename, found)
+	}
+}
+
+func Test_ExistsFold(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"Photos/IMG_0001.JPG": "1",
+		"Photos/notes.txt": "2"})
+	expected := filepath.Join(dir, "Photos", "IMG_0001.JPG")
+	for _, path := range []string{expected,
+		filepath.Join(dir, "photos", "img_0001.jpg"),
+		filepath.Join(dir, "PHOTOS", ".", "Img_0001.Jpg")} {
+		if actual, ok := ExistsFold(path); !ok || actual != expected {
+			t.Errorf("expected %q got %q %t", expected, actual, ok)
+		}
+	}
+	if actual, ok := ExistsFold(filepath.Join(dir, "photos",
+		"missing.jpg")); ok {
+		t.Errorf("expected not found got %q", actual)
+	}
+}
+
+func Test_ReadChunks(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(filename, []byte("abcdefghij"),
+		ModeURW); err != nil {
+		t.Fatal(err)
+	}
+	var chunks []string
+	for chunk, err := range ReadChunks(filename, 4) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, string(chunk))
+	}
+	if expected := []string{"abcd", "efgh", "ij"}; !slices.Equal(chunks,
+		expected) {
+		t.Errorf("expected %q got %q", expected, chunks)
+	}
+	for _, err := range ReadChunks(filename, 0) {
+		if err == nil {
+			t.Error("expected error for invalid chunk size")
+		}
+	}
+}
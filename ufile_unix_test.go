@@ -0,0 +1,126 @@
+//go:build unix
+
+package ufile
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func Test_SpecialFiles(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"a.txt": "alpha"})
+	regular := filepath.Join(dir, "a.txt")
+	fifo := filepath.Join(dir, "pipe")
+	if err := syscall.Mkfifo(fifo, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if !IsRegularFile(regular) || IsFIFO(regular) || IsSocket(regular) ||
+		IsDevice(regular) {
+		t.Errorf("expected %q to be a regular file", regular)
+	}
+	if !FileExists(fifo) || IsRegularFile(fifo) || !IsFIFO(fifo) {
+		t.Errorf("expected %q to be a FIFO", fifo)
+	}
+	if IsRegularFile(socket) || !IsSocket(socket) {
+		t.Errorf("expected %q to be a socket", socket)
+	}
+	if IsRegularFile(dir) || IsFIFO(filepath.Join(dir, "missing")) {
+		t.Error("expected folder and missing path to be irregular")
+	}
+	if PathExists("/dev/null") && (!IsDevice("/dev/null") ||
+		!IsCharDevice("/dev/null") || IsRegularFile("/dev/null")) {
+		t.Error("expected /dev/null to be a character device")
+	}
+	expected := map[string]EntryType{".": TypeDir, "a.txt": TypeFile,
+		"pipe": TypeFIFO, "sock": TypeSocket}
+	for entry, err := range Find(dir, NewQuery()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel, _ := filepath.Rel(dir, entry.Path)
+		if entry.Type() != expected[rel] {
+			t.Errorf("expected %s type %d got %d", rel, expected[rel],
+				entry.Type())
+		}
+	}
+	var files []string
+	for entry := range Find(dir, NewQuery().Type(TypeFile)) {
+		files = append(files, filepath.Base(entry.Path))
+	}
+	if len(files) != 1 || files[0] != "a.txt" {
+		t.Errorf("expected [a.txt] got %v", files)
+	}
+}
+
+func Test_CanonicalPath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	makeTestTree(t, dir, map[string]string{"real/sub/": ""})
+	if err = os.Symlink("real", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "real", "sub")
+	for _, path := range []string{target,
+		filepath.Join(dir, "link", "sub"),
+		filepath.Join(dir, "link", "..", "real", ".", "sub")} {
+		canonical, err := CanonicalPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if canonical != target {
+			t.Errorf("expected %q got %q", target, canonical)
+		}
+	}
+	missing := filepath.Join(dir, "link", "sub", "new", "file.txt")
+	canonical, err := CanonicalPath(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(target, "new", "file.txt"); canonical !=
+		expected {
+		t.Errorf("expected %q got %q", expected, canonical)
+	}
+}
+
+func Test_CanonicalPath_dotDotAfterSymlink(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	makeTestTree(t, dir, map[string]string{"other/deep/real/": "",
+		"other/deep/f.txt": "deep", "f.txt": "top"})
+	err = os.Symlink(filepath.Join("other", "deep", "real"),
+		filepath.Join(dir, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sep := string(filepath.Separator)
+	expected := filepath.Join(dir, "other", "deep", "f.txt")
+	for _, rel := range []string{"f.txt", "missing" + sep + "x"} {
+		path := dir + sep + "link" + sep + ".." + sep + rel
+		canonical, err := CanonicalPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := filepath.Join(dir, "other", "deep", rel)
+		if canonical != want {
+			t.Errorf("expected %q got %q", want, canonical)
+		}
+	}
+	if resolved, err := filepath.EvalSymlinks(dir + sep + "link" + sep +
+		".." + sep + "f.txt"); err != nil || resolved != expected {
+		t.Errorf("expected %q got %q %v", expected, resolved, err)
+	}
+}
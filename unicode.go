@@ -0,0 +1,35 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import "golang.org/x/text/unicode/norm"
+
+// UnicodeForm identifies a Unicode normalization form for
+// [NormalizePathUnicode].
+type UnicodeForm uint8
+
+const (
+	NFC UnicodeForm = iota // composed, e.g., "é" as one code point
+	NFD                    // decomposed, e.g., "é" as "e" plus accent
+)
+
+// String returns the UnicodeForm's name, e.g., "NFC".
+func (me UnicodeForm) String() string {
+	if me == NFD {
+		return "NFD"
+	}
+	return "NFC"
+}
+
+// NormalizePathUnicode returns path with its Unicode text normalized to
+// the given form. The same visual name can be stored differently, e.g.,
+// macOS (HFS+, and many macOS tools) uses NFD while Linux tools usually
+// produce NFC, so a name copied from one to the other might not be found
+// by exact comparison. See also [DiffOptions] and [Query.NormalizeUnicode]
+// which can compare names normalization-insensitively.
+func NormalizePathUnicode(path string, form UnicodeForm) string {
+	if form == NFD {
+		return norm.NFD.String(path)
+	}
+	return norm.NFC.String(path)
+}
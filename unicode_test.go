@@ -0,0 +1,77 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const (
+	cafeNFC = "caf\u00e9.txt"
+	cafeNFD = "cafe\u0301.txt"
+)
+
+func Test_NormalizePathUnicode(t *testing.T) {
+	if got := NormalizePathUnicode("dir/"+cafeNFD, NFC); got !=
+		"dir/"+cafeNFC {
+		t.Errorf("expected %q got %q", "dir/"+cafeNFC, got)
+	}
+	if got := NormalizePathUnicode(cafeNFC, NFD); got != cafeNFD {
+		t.Errorf("expected %q got %q", cafeNFD, got)
+	}
+	if NFC.String() != "NFC" || NFD.String() != "NFD" {
+		t.Errorf("unexpected form names %s %s", NFC, NFD)
+	}
+}
+
+func Test_DiffDirsNormalizeUnicode(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	makeTestTree(t, a, map[string]string{cafeNFC: "new"})
+	makeTestTree(t, b, map[string]string{cafeNFD: "old"})
+	if IsDir(filepath.Join(b, cafeNFC)) || FileExists(filepath.Join(b,
+		cafeNFC)) {
+		t.Skip("filesystem normalizes names")
+	}
+	report, err := DiffDirs(a, b, DiffOptions{})
+	if err != nil || len(report.OnlyInA) != 1 || len(report.OnlyInB) != 1 {
+		t.Errorf("expected names to differ got %+v %v", report, err)
+	}
+	report, err = DiffDirs(a, b, DiffOptions{NormalizeUnicode: true})
+	if err != nil || !slices.Equal(report.Differ, []string{cafeNFC}) ||
+		len(report.OnlyInA)+len(report.OnlyInB) != 0 {
+		t.Errorf("expected only content to differ got %+v %v", report, err)
+	}
+	future := time.Now().Add(time.Hour)
+	if err = os.Chtimes(filepath.Join(a, cafeNFC), future,
+		future); err != nil {
+		t.Fatal(err)
+	}
+	_, err = SyncDirs(a, b, SyncOptions{Delete: true,
+		Diff: DiffOptions{NormalizeUnicode: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := ListDir(b, ListOptions{})
+	if len(entries) != 1 || filepath.Base(entries[0].Path) != cafeNFD {
+		t.Errorf("expected just %q got %v", cafeNFD, entries)
+	}
+	if lines, _ := ReadTextFile(filepath.Join(b, cafeNFD)); lines[0] !=
+		"new" {
+		t.Errorf("expected %q got %q", "new", lines)
+	}
+	q := NewQuery().Name("café*").NormalizeUnicode()
+	var found []string
+	for entry, err := range Find(a, q) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		found = append(found, filepath.Base(entry.Path))
+	}
+	if !slices.Equal(found, []string{cafeNFC}) {
+		t.Errorf("expected %q got %q", cafeNFC, found)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"cmp"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// LargestFiles returns the n largest regular files in the tree rooted at
+// folder root, largest first (and in path order for files of equal size).
+// Symlinks aren't followed. See also [SizeByDir].
+func LargestFiles(root string, n int) ([]Entry, error) {
+	largest := make([]Entry, 0, max(n, 0)+1)
+	compare := func(a, b Entry) int {
+		if c := cmp.Compare(b.Size(), a.Size()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Path, b.Path)
+	}
+	err := filepath.WalkDir(root, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil || n < 1 || !dirEntry.Type().IsRegular() {
+			return err
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		entry := Entry{Path: path, FileInfo: info}
+		if len(largest) == n && compare(entry, largest[n-1]) >= 0 {
+			return nil // too small
+		}
+		i, _ := slices.BinarySearchFunc(largest, entry, compare)
+		largest = slices.Insert(largest, i, entry)
+		if len(largest) > n {
+			largest = largest[:n]
+		}
+		return nil
+	})
+	return largest, err
+}
+
+// SizeByDir returns the total size of the regular files in each folder in
+// the tree rooted at folder root down to the given depth (0 for root
+// only, 1 for root and its subfolders, and so on, or -1 for every
+// folder), much like du(1). Each folder's size includes the sizes of all
+// the files below it, at any depth. The map's keys are root and the
+// folder paths below it as found by walking. Symlinks aren't followed.
+// See also [DirSize] and [LargestFiles].
+func SizeByDir(root string, depth int) (map[string]int64, error) {
+	root = filepath.Clean(root)
+	sizes := make(map[string]int64)
+	err := filepath.WalkDir(root, func(path string, dirEntry fs.DirEntry,
+		err error,
+	) error {
+		if err != nil {
+			return err
+		}
+		if dirEntry.IsDir() {
+			if depth < 0 ||
+				pathDepth(root, path, os.PathSeparator) <= depth {
+				sizes[path] += 0
+			}
+			return nil
+		}
+		if !dirEntry.Type().IsRegular() {
+			return nil
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(path)
+		if depth >= 0 { // attribute to the ancestor at depth
+			d := pathDepth(root, dir, os.PathSeparator)
+			for ; d > depth; d-- {
+				dir = filepath.Dir(dir)
+			}
+		}
+		for {
+			sizes[dir] += info.Size()
+			if dir == root {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+		return nil
+	})
+	return sizes, err
+}
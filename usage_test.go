@@ -0,0 +1,49 @@
+package ufile
+
+import (
+	"maps"
+	"path/filepath"
+	"testing"
+)
+
+func Test_LargestFiles(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"a": "12345", "sub/b": "123",
+		"sub/c": "1234567", "sub/deep/d": "12345", "e": ""})
+	entries, err := LargestFiles(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"sub/c", "a", "sub/deep/d"}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries got %d", len(expected), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Path != filepath.Join(dir, filepath.FromSlash(
+			expected[i])) {
+			t.Errorf("expected %q got %q", expected[i], entry.Path)
+		}
+	}
+	if entries, err = LargestFiles(dir, 0); err != nil || len(entries) != 0 {
+		t.Errorf("expected no entries got %v %v", entries, err)
+	}
+}
+
+func Test_SizeByDir(t *testing.T) {
+	dir := t.TempDir()
+	makeTestTree(t, dir, map[string]string{"a": "12345", "sub/b": "123",
+		"sub/deep/c": "1234567", "other/": ""})
+	sub := filepath.Join(dir, "sub")
+	for depth, expected := range map[int]map[string]int64{
+		0: {dir: 15},
+		1: {dir: 15, sub: 10, filepath.Join(dir, "other"): 0},
+		-1: {dir: 15, sub: 10, filepath.Join(dir, "other"): 0,
+			filepath.Join(sub, "deep"): 7},
+	} {
+		sizes, err := SizeByDir(dir, depth)
+		if err != nil || !maps.Equal(sizes, expected) {
+			t.Errorf("depth %d: expected %v got %v %v", depth, expected,
+				sizes, err)
+		}
+	}
+}
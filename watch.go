@@ -0,0 +1,335 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WatchOp is the kind of change a [WatchEvent] reports.
+type WatchOp uint8
+
+const (
+	WatchCreate  WatchOp = iota // path has appeared
+	WatchWrite                  // path's size or modification time changed
+	WatchRemove                 // path has disappeared
+	WatchReplace                // path is now a different file (inode)
+)
+
+// String returns the WatchOp's name, e.g., "write".
+func (me WatchOp) String() string {
+	switch me {
+	case WatchCreate:
+		return "create"
+	case WatchRemove:
+		return "remove"
+	case WatchReplace:
+		return "replace"
+	default:
+		return "write"
+	}
+}
+
+// WatchEvent is a change reported by a [Watcher].
+type WatchEvent struct {
+	Path string
+	Op   WatchOp
+}
+
+// WatchOptions are used by [NewWatcher].
+//
+// Interval is how often the watched paths are checked (default 250ms). If
+// Coalesce is positive [Watcher.Run] batches changes until none have
+// been found for that long (so, for example, a build that writes hundreds
+// of files produces a single callback), with at most one event per path
+// (e.g., a file created and then written is reported once as created, and
+// one created and then removed isn't reported at all).
+type WatchOptions struct {
+	Interval time.Duration
+	Coalesce time.Duration
+}
+
+// Watcher reports changes to watched paths by polling them, so it works
+// on every platform and filesystem (including network filesystems where
+// change notifications are unreliable). Because paths rather than open
+// files are watched, a file that is replaced by an atomic rename (as many
+// editors do when saving, and as logrotate does) is reported as
+// [WatchReplace] and then watched as the new file. Create a Watcher with
+// [NewWatcher], add paths with [Watcher.WatchFile] or [Watcher.WatchTree],
+// and then call [Watcher.Run].
+type Watcher struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	coalesce time.Duration
+	files    map[string]fs.FileInfo // nil if the file doesn't exist
+	trees    map[string]*watchedTree
+}
+
+// WatchTreeOptions are used by [Watcher.WatchTree].
+//
+// If Include is nonempty only files whose names or slash-separated paths
+// relative to the root match one of its globs (see [filepath.Match]) are
+// watched. Entries matching any of the Exclude globs are never watched
+// (and excluded folders aren't descended into).
+type WatchTreeOptions struct {
+	Include []string
+	Exclude []string
+}
+
+type watchedTree struct {
+	root  string
+	opts  WatchTreeOptions
+	files map[string]fs.FileInfo
+}
+
+// NewWatcher returns a new Watcher with the given options.
+func NewWatcher(opts WatchOptions) *Watcher {
+	if opts.Interval <= 0 {
+		opts.Interval = 250 * time.Millisecond
+	}
+	return &Watcher{interval: opts.Interval, coalesce: opts.Coalesce,
+		files: make(map[string]fs.FileInfo),
+		trees: make(map[string]*watchedTree)}
+}
+
+// WatchFile adds path to the watched paths. The file need not exist yet
+// (its creation will be reported). Watching a path that is already
+// watched has no effect. See also [Watcher.Unwatch].
+func (me *Watcher) WatchFile(path string) error {
+	info, err := watchStat(path)
+	if err != nil {
+		return err
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if _, ok := me.files[path]; !ok {
+		me.files[path] = info
+	}
+	return nil
+}
+
+// WatchTree adds every file (but not folder) in the tree rooted at root
+// that is selected by opts to the watched paths, including files in
+// subfolders created later. For example, to be told of changes to any
+// Markdown file:
+//
+//	err := watcher.WatchTree(root,
+//		ufile.WatchTreeOptions{Include: []string{"*.md"}})
+//
+// Watching a tree that is already watched replaces its options. Symlinks
+// are watched but not followed. See also [Watcher.Unwatch].
+func (me *Watcher) WatchTree(root string, opts WatchTreeOptions) error {
+	root = filepath.Clean(root)
+	tree := &watchedTree{root: root, opts: opts}
+	files, err := tree.scan()
+	if err != nil {
+		return err
+	}
+	tree.files = files
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.trees[root] = tree
+	return nil
+}
+
+// Unwatch removes path (a file or tree root) from the watched paths.
+func (me *Watcher) Unwatch(path string) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	delete(me.files, path)
+	delete(me.trees, filepath.Clean(path))
+}
+
+// Run checks the watched paths every interval and calls fn with the
+// changes found (sorted by path, and coalesced if the Coalesce option was
+// set) until ctx is cancelled, in which case it returns the context's
+// error, or fn returns an error, in which case it returns that error.
+func (me *Watcher) Run(ctx context.Context,
+	fn func([]WatchEvent) error,
+) error {
+	ticker := time.NewTicker(me.interval)
+	defer ticker.Stop()
+	var pending []WatchEvent
+	var lastChange time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			events := me.poll()
+			if me.coalesce > 0 {
+				if len(events) > 0 {
+					pending = mergeWatchEvents(pending, events)
+					lastChange = now
+				}
+				if now.Sub(lastChange) < me.coalesce {
+					continue
+				}
+				events, pending = pending, nil
+			}
+			if len(events) > 0 {
+				if err := fn(events); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// mergeWatchEvents returns pending (sorted by path) with events merged
+// in, keeping at most one event per path.
+func mergeWatchEvents(pending, events []WatchEvent) []WatchEvent {
+	ops := make(map[string]WatchOp, len(pending)+len(events))
+	for _, event := range pending {
+		ops[event.Path] = event.Op
+	}
+	for _, event := range events {
+		old, ok := ops[event.Path]
+		op := event.Op
+		switch {
+		case !ok:
+		case old == WatchCreate && op == WatchRemove:
+			delete(ops, event.Path)
+			continue
+		case old == WatchCreate:
+			op = WatchCreate
+		case old == WatchRemove && op == WatchCreate:
+			op = WatchReplace
+		case old == WatchReplace && op == WatchWrite:
+			op = WatchReplace
+		}
+		ops[event.Path] = op
+	}
+	merged := make([]WatchEvent, 0, len(ops))
+	for _, path := range slices.Sorted(maps.Keys(ops)) {
+		merged = append(merged, WatchEvent{path, ops[path]})
+	}
+	return merged
+}
+
+// poll returns the changes to the watched paths since the last poll.
+func (me *Watcher) poll() []WatchEvent {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	var events []WatchEvent
+	for path, old := range me.files {
+		info, err := watchStat(path)
+		if err != nil {
+			continue // try again next time
+		}
+		me.files[path] = info
+		if op, changed := watchChange(old, info); changed {
+			events = append(events, WatchEvent{path, op})
+		}
+	}
+	for _, tree := range me.trees {
+		files, err := tree.scan()
+		if err != nil {
+			continue // try again next time
+		}
+		for path, info := range files {
+			if op, changed := watchChange(tree.files[path],
+				info); changed {
+				events = append(events, WatchEvent{path, op})
+			}
+		}
+		for path := range tree.files {
+			if _, ok := files[path]; !ok {
+				events = append(events, WatchEvent{path, WatchRemove})
+			}
+		}
+		tree.files = files
+	}
+	slices.SortFunc(events, func(a, b WatchEvent) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return events
+}
+
+// watchChange returns the kind of change from old to info (either of
+// which is nil if the file didn't or doesn't exist) and true, or false if
+// there's no change.
+func watchChange(old, info fs.FileInfo) (WatchOp, bool) {
+	switch {
+	case old == nil && info == nil:
+		return 0, false
+	case old == nil:
+		return WatchCreate, true
+	case info == nil:
+		return WatchRemove, true
+	case !os.SameFile(old, info):
+		return WatchReplace, true
+	case old.Size() != info.Size() || !old.ModTime().Equal(info.ModTime()):
+		return WatchWrite, true
+	}
+	return 0, false
+}
+
+// scan returns the info of every selected file in the tree (or none if
+// the root doesn't exist).
+func (me *watchedTree) scan() (map[string]fs.FileInfo, error) {
+	files := make(map[string]fs.FileInfo)
+	err := filepath.WalkDir(me.root, func(path string,
+		dirEntry fs.DirEntry, err error,
+	) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // removed during the walk
+			}
+			return err
+		}
+		if path == me.root {
+			return nil
+		}
+		rel, err := filepath.Rel(me.root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchesAnyGlob(me.opts.Exclude, dirEntry.Name(), rel) {
+			if dirEntry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if dirEntry.IsDir() || (len(me.opts.Include) > 0 &&
+			!matchesAnyGlob(me.opts.Include, dirEntry.Name(), rel)) {
+			return nil
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // removed during the walk
+			}
+			return err
+		}
+		os.SameFile(info, info) // see watchStat
+		files[path] = info
+		return nil
+	})
+	return files, err
+}
+
+// watchStat returns path's info, or nil if it doesn't exist.
+func watchStat(path string) (fs.FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// On Windows the file's identity is only read when first compared, so
+	// compare now while path still refers to this file.
+	os.SameFile(info, info)
+	return info, nil
+}
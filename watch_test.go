@@ -0,0 +1,183 @@
+package ufile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_WatchFile(t *testing.T) {
+	dir := t.TempDir()
+	logname := filepath.Join(dir, "app.log")
+	newname := filepath.Join(dir, "new.txt")
+	makeTestTree(t, dir, map[string]string{"app.log": "one\n"})
+	watcher := NewWatcher(WatchOptions{})
+	for _, path := range []string{logname, newname} {
+		if err := watcher.WatchFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(expected ...string) {
+		t.Helper()
+		var got []string
+		for _, event := range watcher.poll() {
+			got = append(got, fmt.Sprintf("%s %s",
+				filepath.Base(event.Path), event.Op))
+		}
+		if !slices.Equal(got, expected) {
+			t.Errorf("expected %q got %q", expected, got)
+		}
+	}
+	check()
+	if err := AppendTextFile(logname, []string{"two"}); err != nil {
+		t.Fatal(err)
+	}
+	check("app.log write")
+	// rotate: move the log aside and write a new one in its place
+	if err := os.Rename(logname, logname+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteTextFile(logname, []string{"three"}); err != nil {
+		t.Fatal(err)
+	}
+	check("app.log replace")
+	if err := AppendTextFile(logname, []string{"four"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteTextFile(newname, []string{"new"}); err != nil {
+		t.Fatal(err)
+	}
+	check("app.log write", "new.txt create")
+	if err := os.Remove(newname); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Unwatch(logname)
+	if err := AppendTextFile(logname, []string{"five"}); err != nil {
+		t.Fatal(err)
+	}
+	check("new.txt remove")
+}
+
+func Test_WatcherRun(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.ini")
+	watcher := NewWatcher(WatchOptions{Interval: 5 * time.Millisecond})
+	if err := watcher.WatchFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteTextFile(filename, []string{"[main]"}); err != nil {
+		t.Fatal(err)
+	}
+	errDone := errors.New("done")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := watcher.Run(ctx, func(events []WatchEvent) error {
+		if len(events) != 1 || events[0].Op != WatchCreate {
+			t.Errorf("expected create got %v", events)
+		}
+		return errDone
+	})
+	if !errors.Is(err, errDone) {
+		t.Errorf("expected errDone got %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err = watcher.Run(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled got %v", err)
+	}
+}
+
+func Test_MergeWatchEvents(t *testing.T) {
+	pending := mergeWatchEvents(nil, []WatchEvent{{"b", WatchCreate},
+		{"c", WatchRemove}, {"d", WatchCreate}, {"e", WatchReplace}})
+	merged := mergeWatchEvents(pending, []WatchEvent{{"a", WatchWrite},
+		{"b", WatchWrite}, {"c", WatchCreate}, {"d", WatchRemove},
+		{"e", WatchWrite}})
+	expected := []WatchEvent{{"a", WatchWrite}, {"b", WatchCreate},
+		{"c", WatchReplace}, {"e", WatchReplace}}
+	if !slices.Equal(merged, expected) {
+		t.Errorf("expected %v got %v", expected, merged)
+	}
+}
+
+func Test_WatcherCoalesce(t *testing.T) {
+	dir := t.TempDir()
+	watcher := NewWatcher(WatchOptions{Interval: 5 * time.Millisecond,
+		Coalesce: 50 * time.Millisecond})
+	var names []string
+	for i := range 20 {
+		name := filepath.Join(dir, fmt.Sprintf("%02d.o", i))
+		names = append(names, name)
+		if err := watcher.WatchFile(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	go func() {
+		for _, name := range names {
+			os.WriteFile(name, []byte("obj"), ModeURW)
+			time.Sleep(2 * time.Millisecond)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var events []WatchEvent
+	errDone := errors.New("done")
+	err := watcher.Run(ctx, func(batch []WatchEvent) error {
+		events = append(events, batch...)
+		if len(events) < len(names) {
+			t.Logf("partial batch of %d", len(batch))
+			return nil
+		}
+		return errDone
+	})
+	if !errors.Is(err, errDone) || len(events) != len(names) {
+		t.Errorf("expected %d events got %d %v", len(names), len(events),
+			err)
+	}
+	for _, event := range events {
+		if event.Op != WatchCreate {
+			t.Errorf("expected create got %v", event)
+		}
+	}
+}
+
+func Test_WatchTree(t *testing.T) {
+	root := t.TempDir()
+	makeTestTree(t, root, map[string]string{"README.md": "# Read me",
+		"main.go": "package main", "docs/guide.md": "# Guide",
+		"build/out.md": "generated"})
+	watcher := NewWatcher(WatchOptions{})
+	if err := watcher.WatchTree(root, WatchTreeOptions{
+		Include: []string{"*.md"}, Exclude: []string{"build"}}); err != nil {
+		t.Fatal(err)
+	}
+	check := func(expected ...string) {
+		t.Helper()
+		var got []string
+		for _, event := range watcher.poll() {
+			rel, _ := filepath.Rel(root, event.Path)
+			got = append(got, filepath.ToSlash(rel)+" "+event.Op.String())
+		}
+		if !slices.Equal(got, expected) {
+			t.Errorf("expected %q got %q", expected, got)
+		}
+	}
+	check()
+	makeTestTree(t, root, map[string]string{"docs/api/index.md": "# API",
+		"main.go": "package main // changed", "build/new.md": "x",
+		"docs/guide.md": "# Guide to it"})
+	check("docs/api/index.md create", "docs/guide.md write")
+	if err := os.Remove(filepath.Join(root, "README.md")); err != nil {
+		t.Fatal(err)
+	}
+	check("README.md remove")
+	watcher.Unwatch(root)
+	if err := os.Remove(filepath.Join(root, "docs", "guide.md")); err != nil {
+		t.Fatal(err)
+	}
+	check()
+}
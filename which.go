@@ -0,0 +1,109 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Which returns the path of the executable called name that would be run
+// by a shell, and true, or "" and false if there isn't one. The folders
+// in the PATH environment variable are searched in order (but not the
+// current folder). On Unix a file must have an executable bit set; on
+// Windows name may omit the extension, in which case each of the
+// extensions in PATHEXT (e.g., .COM, .EXE, .BAT) is tried. If name
+// contains a path separator it is checked directly rather than searched
+// for. See also [WhichAll].
+func Which(name string) (string, bool) {
+	paths := which(name, true)
+	if len(paths) == 0 {
+		return "", false
+	}
+	return paths[0], true
+}
+
+// WhichAll returns the paths of every executable called name in PATH
+// order (without duplicates), or nil if there are none. See also [Which].
+func WhichAll(name string) []string {
+	return which(name, false)
+}
+
+func which(name string, first bool) []string {
+	windows := runtime.GOOS == "windows"
+	var exts []string
+	if windows {
+		exts = pathExts(os.Getenv("PATHEXT"), name)
+	}
+	if strings.Contains(name, "/") ||
+		(windows && strings.Contains(name, `\`)) {
+		if path, ok := findExecutable(name, exts); ok {
+			return []string{path}
+		}
+		return nil
+	}
+	var paths []string
+	seen := map[string]bool{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue // don't search the current folder
+		}
+		path, ok := findExecutable(filepath.Join(dir, name), exts)
+		if !ok || seen[path] {
+			continue
+		}
+		paths = append(paths, path)
+		if first {
+			break
+		}
+		seen[path] = true
+	}
+	return paths
+}
+
+// pathExts returns the extensions to try for name given the PATHEXT
+// value: "" (i.e., name as is) if name already has one of them, followed
+// by each of them.
+func pathExts(pathext, name string) []string {
+	if pathext == "" {
+		pathext = ".COM;.EXE;.BAT;.CMD"
+	}
+	var exts []string
+	nameExt := strings.ToLower(filepath.Ext(name))
+	for _, ext := range strings.Split(strings.ToLower(pathext), ";") {
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if ext == nameExt {
+			exts = append([]string{""}, exts...)
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// findExecutable returns path (with the first of exts that makes it an
+// executable, if exts isn't empty) and true if it is an executable;
+// otherwise returns "" and false.
+func findExecutable(path string, exts []string) (string, bool) {
+	if len(exts) == 0 {
+		info, err := os.Stat(path)
+		if err == nil && info.Mode().IsRegular() &&
+			info.Mode().Perm()&0o111 != 0 {
+			return path, true
+		}
+		return "", false
+	}
+	for _, ext := range exts {
+		if info, err := os.Stat(path + ext); err == nil &&
+			info.Mode().IsRegular() {
+			return path + ext, true
+		}
+	}
+	return "", false
+}
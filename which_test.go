@@ -0,0 +1,55 @@
+package ufile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func Test_Which(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix executable bits")
+	}
+	dir := t.TempDir()
+	one := filepath.Join(dir, "one")
+	two := filepath.Join(dir, "two")
+	makeTestTree(t, dir, map[string]string{"one/tool": "#!/bin/sh\n",
+		"two/tool": "#!/bin/sh\n", "two/data": "not executable"})
+	for _, name := range []string{"one/tool", "two/tool"} {
+		err := os.Chmod(filepath.Join(dir, name), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", one+string(os.PathListSeparator)+two+
+		string(os.PathListSeparator)+one)
+	path, ok := Which("tool")
+	if expected := filepath.Join(one, "tool"); !ok || path != expected {
+		t.Errorf("expected %q got %q", expected, path)
+	}
+	paths := WhichAll("tool")
+	expected := []string{filepath.Join(one, "tool"),
+		filepath.Join(two, "tool")}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("expected %q got %q", expected, paths)
+	}
+	if path, ok = Which("data"); ok {
+		t.Errorf("expected not found got %q", path)
+	}
+	if _, ok = Which(filepath.Join(two, "tool")); !ok {
+		t.Error("expected direct path to be found")
+	}
+}
+
+func Test_pathExts(t *testing.T) {
+	exts := pathExts(".COM;.EXE;.BAT", "go")
+	if !slices.Equal(exts, []string{".com", ".exe", ".bat"}) {
+		t.Errorf("unexpected %q", exts)
+	}
+	exts = pathExts(".COM;.EXE;.BAT", "go.exe")
+	if !slices.Equal(exts, []string{"", ".com", ".exe", ".bat"}) {
+		t.Errorf("unexpected %q", exts)
+	}
+}
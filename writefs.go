@@ -0,0 +1,366 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriteFS is a filesystem that can be written to as well as read. Names
+// are slash-separated and unrooted as for [fs.FS] (see [fs.ValidPath]).
+// Use [NewOSFS] for a real folder, or [NewMemFS] for an in-memory
+// filesystem, e.g., for unit tests that mustn't touch the disk.
+type WriteFS interface {
+	fs.FS
+	Create(name string) (io.WriteCloser, error)
+	Mkdir(name string, perm fs.FileMode) error
+	Remove(name string) error
+	Rename(oldname, newname string) error
+}
+
+// WriteTextFileFS works like [WriteTextFile] but writes to the named file
+// in wfs.
+func WriteTextFileFS(wfs WriteFS, name string, lines []string) error {
+	file, err := wfs.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = writeLines(file, slices.Values(lines)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// CopyFileFS copies the contents of the named file src to the named file
+// dst (replacing dst if it exists), both in wfs. Unlike [CopyFile] the
+// copy isn't atomic and permissions and modification times aren't
+// preserved.
+func CopyFileFS(wfs WriteFS, src, dst string) error {
+	in, err := wfs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := wfs.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// OSFS is a [WriteFS] for the tree rooted at a folder on disk.
+type OSFS struct {
+	fs.FS
+	root string
+}
+
+// NewOSFS returns an OSFS for the tree rooted at folder root.
+func NewOSFS(root string) *OSFS {
+	return &OSFS{FS: os.DirFS(root), root: root}
+}
+
+// path returns the OS path for name or an error if it isn't valid.
+func (me *OSFS) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(me.root, filepath.FromSlash(name)), nil
+}
+
+// Create creates or truncates the named file.
+func (me *OSFS) Create(name string) (io.WriteCloser, error) {
+	filename, err := me.path("create", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Create(filename)
+}
+
+// Mkdir creates the named folder.
+func (me *OSFS) Mkdir(name string, perm fs.FileMode) error {
+	dirname, err := me.path("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return os.Mkdir(dirname, perm)
+}
+
+// Remove removes the named file or empty folder.
+func (me *OSFS) Remove(name string) error {
+	filename, err := me.path("remove", name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(filename)
+}
+
+// Rename renames (moves) oldname to newname.
+func (me *OSFS) Rename(oldname, newname string) error {
+	oldFilename, err := me.path("rename", oldname)
+	if err != nil {
+		return err
+	}
+	newFilename, err := me.path("rename", newname)
+	if err != nil {
+		return err
+	}
+	return rename(oldFilename, newFilename)
+}
+
+// MemFS is an in-memory [WriteFS] that is safe for concurrent use.
+type MemFS struct {
+	mutex   sync.Mutex
+	entries map[string]memEntry
+}
+
+// memEntry is a file or folder in a [MemFS].
+type memEntry struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemFS returns an empty MemFS.
+func NewMemFS() *MemFS {
+	return &MemFS{entries: map[string]memEntry{
+		".": {mode: fs.ModeDir | 0o755, modTime: time.Now()}}}
+}
+
+// Open opens the named file or folder for reading.
+func (me *MemFS) Open(name string) (fs.File, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := me.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name,
+			Err: fs.ErrNotExist}
+	}
+	info := &memInfo{name: path.Base(name), entry: entry}
+	if !entry.mode.IsDir() {
+		return &memReader{Reader: bytes.NewReader(entry.data), info: info},
+			nil
+	}
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	var children []fs.DirEntry
+	for key, child := range me.entries {
+		if key != "." && strings.HasPrefix(key, prefix) &&
+			!strings.Contains(key[len(prefix):], "/") {
+			children = append(children, fs.FileInfoToDirEntry(
+				&memInfo{name: path.Base(key), entry: child}))
+		}
+	}
+	slices.SortFunc(children, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return &memDir{path: name, info: info, children: children}, nil
+}
+
+// Create creates or truncates the named file; its contents are stored
+// when it is closed.
+func (me *MemFS) Create(name string) (io.WriteCloser, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if err := me.checkParent("create", name); err != nil {
+		return nil, err
+	}
+	if entry, ok := me.entries[name]; ok && entry.mode.IsDir() {
+		return nil, &fs.PathError{Op: "create", Path: name,
+			Err: fs.ErrExist}
+	}
+	me.entries[name] = memEntry{mode: modeDefault, modTime: time.Now()}
+	return &memFile{fsys: me, name: name}, nil
+}
+
+// Mkdir creates the named folder.
+func (me *MemFS) Mkdir(name string, perm fs.FileMode) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if err := me.checkParent("mkdir", name); err != nil {
+		return err
+	}
+	if _, ok := me.entries[name]; ok {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	me.entries[name] = memEntry{mode: fs.ModeDir | perm.Perm(),
+		modTime: time.Now()}
+	return nil
+}
+
+// Remove removes the named file or empty folder.
+func (me *MemFS) Remove(name string) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	if _, ok := me.entries[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name,
+			Err: fs.ErrNotExist}
+	}
+	if len(me.children(name)) > 0 {
+		return &fs.PathError{Op: "remove", Path: name,
+			Err: fs.ErrExist} // folder not empty
+	}
+	delete(me.entries, name)
+	return nil
+}
+
+// Rename renames (moves) oldname (a file or folder) to newname, replacing
+// newname if it is an existing file.
+func (me *MemFS) Rename(oldname, newname string) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if !fs.ValidPath(oldname) || oldname == "." {
+		return &fs.PathError{Op: "rename", Path: oldname,
+			Err: fs.ErrInvalid}
+	}
+	entry, ok := me.entries[oldname]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldname,
+			Err: fs.ErrNotExist}
+	}
+	if err := me.checkParent("rename", newname); err != nil {
+		return err
+	}
+	if entry, ok := me.entries[newname]; ok && entry.mode.IsDir() {
+		return &fs.PathError{Op: "rename", Path: newname,
+			Err: fs.ErrExist}
+	}
+	if newname == oldname || strings.HasPrefix(newname, oldname+"/") {
+		return &fs.PathError{Op: "rename", Path: newname,
+			Err: fs.ErrInvalid}
+	}
+	for _, child := range me.children(oldname) {
+		me.entries[newname+child[len(oldname):]] = me.entries[child]
+		delete(me.entries, child)
+	}
+	me.entries[newname] = entry
+	delete(me.entries, oldname)
+	return nil
+}
+
+// checkParent returns an error if name is invalid or its parent isn't an
+// existing folder. The caller must hold the lock.
+func (me *MemFS) checkParent(op, name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	parent, ok := me.entries[path.Dir(name)]
+	if !ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if !parent.mode.IsDir() {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return nil
+}
+
+// children returns the names of every file and folder below name. The
+// caller must hold the lock.
+func (me *MemFS) children(name string) []string {
+	var names []string
+	for key := range me.entries {
+		if strings.HasPrefix(key, name+"/") {
+			names = append(names, key)
+		}
+	}
+	return names
+}
+
+// memInfo is the [fs.FileInfo] for a [MemFS] entry.
+type memInfo struct {
+	name  string
+	entry memEntry
+}
+
+func (me *memInfo) Name() string       { return me.name }
+func (me *memInfo) Size() int64        { return int64(len(me.entry.data)) }
+func (me *memInfo) Mode() fs.FileMode  { return me.entry.mode }
+func (me *memInfo) ModTime() time.Time { return me.entry.modTime }
+func (me *memInfo) IsDir() bool        { return me.entry.mode.IsDir() }
+func (me *memInfo) Sys() any           { return nil }
+
+// memReader is the file returned by [MemFS.Open] for a file.
+type memReader struct {
+	*bytes.Reader
+	info *memInfo
+}
+
+func (me *memReader) Stat() (fs.FileInfo, error) { return me.info, nil }
+func (me *memReader) Close() error               { return nil }
+
+// memDir is the file returned by [MemFS.Open] for a folder.
+type memDir struct {
+	path     string
+	info     *memInfo
+	children []fs.DirEntry
+	offset   int
+}
+
+func (me *memDir) Stat() (fs.FileInfo, error) { return me.info, nil }
+func (me *memDir) Close() error               { return nil }
+
+func (me *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: me.path, Err: fs.ErrInvalid}
+}
+
+// ReadDir returns up to count of the folder's remaining entries (or all of
+// them if count <= 0) as described by [fs.ReadDirFile].
+func (me *memDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	rest := me.children[me.offset:]
+	if count > 0 {
+		if len(rest) == 0 {
+			return nil, io.EOF
+		}
+		rest = rest[:min(count, len(rest))]
+	}
+	me.offset += len(rest)
+	return rest, nil
+}
+
+// memFile is the writer returned by [MemFS.Create].
+type memFile struct {
+	fsys   *MemFS
+	name   string
+	buffer bytes.Buffer
+	closed bool
+}
+
+func (me *memFile) Write(p []byte) (int, error) {
+	if me.closed {
+		return 0, fs.ErrClosed
+	}
+	return me.buffer.Write(p)
+}
+
+func (me *memFile) Close() error {
+	if me.closed {
+		return fs.ErrClosed
+	}
+	me.closed = true
+	me.fsys.mutex.Lock()
+	defer me.fsys.mutex.Unlock()
+	me.fsys.entries[me.name] = memEntry{data: me.buffer.Bytes(),
+		mode: modeDefault, modTime: time.Now()}
+	return nil
+}
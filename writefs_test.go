@@ -0,0 +1,79 @@
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func Test_WriteFS(t *testing.T) {
+	dir := t.TempDir()
+	for _, wfs := range []WriteFS{NewMemFS(), NewOSFS(dir)} {
+		if err := wfs.Mkdir("sub", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := wfs.Mkdir("sub", 0o755); !errors.Is(err, fs.ErrExist) {
+			t.Errorf("expected %v got %v", fs.ErrExist, err)
+		}
+		if _, err := wfs.Create("missing/a.txt"); !errors.Is(err,
+			fs.ErrNotExist) {
+			t.Errorf("expected %v got %v", fs.ErrNotExist, err)
+		}
+		lines := []string{"alpha", "beta"}
+		if err := WriteTextFileFS(wfs, "sub/a.txt", lines); err != nil {
+			t.Fatal(err)
+		}
+		if err := CopyFileFS(wfs, "sub/a.txt", "b.txt"); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadTextFileFS(wfs, "b.txt")
+		if err != nil || !slices.Equal(got, lines) {
+			t.Errorf("expected %q got %q %v", lines, got, err)
+		}
+		if err = wfs.Remove("sub"); err == nil {
+			t.Error("expected error removing nonempty folder")
+		}
+		if err = wfs.Rename("sub", "moved"); err != nil {
+			t.Fatal(err)
+		}
+		if !FileExistsFS(wfs, "moved/a.txt") || PathExistsFS(wfs, "sub") {
+			t.Error("expected sub to have been renamed to moved")
+		}
+		if err = wfs.Remove("moved/a.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if err = wfs.Remove("moved"); err != nil {
+			t.Fatal(err)
+		}
+		if err = wfs.Remove("moved"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected %v got %v", fs.ErrNotExist, err)
+		}
+		if _, err = wfs.Create("../escape.txt"); !errors.Is(err,
+			fs.ErrInvalid) {
+			t.Errorf("expected %v got %v", fs.ErrInvalid, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); err != nil {
+		t.Errorf("expected b.txt on disk: %v", err)
+	}
+}
+
+func Test_MemFS_conforms(t *testing.T) {
+	wfs := NewMemFS()
+	if err := wfs.Mkdir("sub", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "sub/b.txt", "sub/c.txt"} {
+		if err := WriteTextFileFS(wfs, name, []string{name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fstest.TestFS(wfs, "a.txt", "sub/b.txt",
+		"sub/c.txt"); err != nil {
+		t.Error(err)
+	}
+}
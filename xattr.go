@@ -0,0 +1,59 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// ErrNoXattr is returned (wrapped) by [GetXattr] and [RemoveXattr] when
+// the file doesn't have the named extended attribute.
+var ErrNoXattr = errors.New("no such extended attribute")
+
+// GetXattr returns the value of the named extended attribute of the given
+// file (following symlinks).
+//
+// Extended attributes are supported on Linux (where user-defined names
+// must start with "user.", e.g., "user.xdg.origin.url") and macOS (e.g.,
+// "com.apple.metadata:kMDItemWhereFroms"). On Windows NTFS alternate data
+// streams are used as a best-effort analog, i.e., the attribute is stored
+// as the stream path:name. On other platforms the error wraps
+// [errors.ErrUnsupported]. See also [SetXattr], [ListXattrs], and
+// [RemoveXattr].
+func GetXattr(path, name string) ([]byte, error) {
+	value, err := getXattr(path, name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "getxattr", Path: path, Err: err}
+	}
+	return value, nil
+}
+
+// SetXattr sets the named extended attribute of the given file to value,
+// creating or replacing it. See also [GetXattr].
+func SetXattr(path, name string, value []byte) error {
+	if err := setXattr(path, name, value); err != nil {
+		return &fs.PathError{Op: "setxattr", Path: path, Err: err}
+	}
+	return nil
+}
+
+// ListXattrs returns the names of the given file's extended attributes.
+// On Windows this isn't supported and the error wraps
+// [errors.ErrUnsupported]. See also [GetXattr].
+func ListXattrs(path string) ([]string, error) {
+	names, err := listXattrs(path)
+	if err != nil {
+		return nil, &fs.PathError{Op: "listxattr", Path: path, Err: err}
+	}
+	return names, nil
+}
+
+// RemoveXattr removes the named extended attribute from the given file.
+// See also [GetXattr].
+func RemoveXattr(path, name string) error {
+	if err := removeXattr(path, name); err != nil {
+		return &fs.PathError{Op: "removexattr", Path: path, Err: err}
+	}
+	return nil
+}
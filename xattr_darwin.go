@@ -0,0 +1,8 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import "golang.org/x/sys/unix"
+
+// errNoAttr is the error for a missing extended attribute.
+const errNoAttr = unix.ENOATTR
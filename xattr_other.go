@@ -0,0 +1,23 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build !linux && !darwin && !windows
+
+package ufile
+
+import "errors"
+
+func getXattr(string, string) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func setXattr(string, string, []byte) error {
+	return errors.ErrUnsupported
+}
+
+func listXattrs(string) ([]string, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func removeXattr(string, string) error {
+	return errors.ErrUnsupported
+}
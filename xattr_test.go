@@ -0,0 +1,37 @@
+package ufile
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func Test_Xattr(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.txt")
+	if err := WriteTextFile(filename, []string{"alpha"}); err != nil {
+		t.Fatal(err)
+	}
+	const name = "user.xdg.origin.url"
+	const url = "https://example.com/a.txt"
+	if err := SetXattr(filename, name, []byte(url)); err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+	value, err := GetXattr(filename, name)
+	if err != nil || string(value) != url {
+		t.Errorf("expected %q got %q %v", url, value, err)
+	}
+	if runtime.GOOS != "windows" {
+		names, err := ListXattrs(filename)
+		if err != nil || !slices.Contains(names, name) {
+			t.Errorf("expected %q in %q %v", name, names, err)
+		}
+	}
+	if err = RemoveXattr(filename, name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GetXattr(filename, name); !errors.Is(err, ErrNoXattr) {
+		t.Errorf("expected %v got %v", ErrNoXattr, err)
+	}
+}
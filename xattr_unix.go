@@ -0,0 +1,71 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+//go:build linux || darwin
+
+package ufile
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+func getXattr(path, name string) ([]byte, error) {
+	for {
+		size, err := unix.Getxattr(path, name, nil)
+		if err != nil {
+			return nil, xattrError(err)
+		}
+		value := make([]byte, size)
+		size, err = unix.Getxattr(path, name, value)
+		if err == nil {
+			return value[:size], nil
+		}
+		if !errors.Is(err, unix.ERANGE) { // ERANGE: grew in between
+			return nil, xattrError(err)
+		}
+	}
+}
+
+func setXattr(path, name string, value []byte) error {
+	return unix.Setxattr(path, name, value, 0)
+}
+
+func listXattrs(path string) ([]string, error) {
+	for {
+		size, err := unix.Listxattr(path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		buffer := make([]byte, size)
+		size, err = unix.Listxattr(path, buffer)
+		if err == nil {
+			var names []string
+			for _, name := range bytes.Split(buffer[:size], []byte{0}) {
+				if len(name) > 0 {
+					names = append(names, string(name))
+				}
+			}
+			return names, nil
+		}
+		if !errors.Is(err, unix.ERANGE) {
+			return nil, err
+		}
+	}
+}
+
+func removeXattr(path, name string) error {
+	return xattrError(unix.Removexattr(path, name))
+}
+
+// xattrError returns ErrNoXattr for a missing attribute's error.
+func xattrError(err error) error {
+	if errors.Is(err, errNoAttr) {
+		return ErrNoXattr
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+// Copyright © 2024 Mark Summerfield. All rights reserved.
+
+package ufile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// Extended attributes are stored as NTFS alternate data streams.
+
+func getXattr(path, name string) ([]byte, error) {
+	value, err := os.ReadFile(path + ":" + name)
+	return value, xattrError(path, err)
+}
+
+func setXattr(path, name string, value []byte) error {
+	return os.WriteFile(path+":"+name, value, ModeURW)
+}
+
+func listXattrs(string) ([]string, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func removeXattr(path, name string) error {
+	return xattrError(path, os.Remove(path+":"+name))
+}
+
+// xattrError returns ErrNoXattr if the stream is missing but the file
+// exists.
+func xattrError(path string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) && PathExists(path) {
+		return ErrNoXattr
+	}
+	return err
+}